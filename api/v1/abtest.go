@@ -0,0 +1,65 @@
+package v1
+
+import "time"
+
+// ABTest is a Parameter payload for two programs measured in the same window
+// with interleaved sampling, e.g. the old and new version of a program
+// attached to different interfaces. Both see the same host noise, so the
+// per-interval paired difference isolates the change between them.
+type ABTest struct {
+	BaselineID  uint32 `json:"baseline_id"`
+	CandidateID uint32 `json:"candidate_id"`
+
+	// Measurement window
+	Duration time.Duration  `json:"duration"`
+	Warmup   *time.Duration `json:"warmup,omitempty"`
+	Started  *time.Time     `json:"started,omitempty"`
+	Ended    *time.Time     `json:"ended,omitempty"`
+
+	// Volume / integrity
+	Pairs    uint64 `json:"pairs"`    // intervals in which both programs ran
+	Unpaired uint64 `json:"unpaired"` // intervals in which only one program ran (discarded)
+
+	// Per-program latency over the paired intervals
+	Baseline  ABSide `json:"baseline"`
+	Candidate ABSide `json:"candidate"`
+
+	// Candidate minus baseline, interval by interval
+	Difference PairedDifference `json:"difference"`
+}
+
+// ABSide is the latency of one program of an ABTest. Timelines of both sides
+// are aligned: the i-th samples were taken in the same interval.
+type ABSide struct {
+	Runs uint64 `json:"runs"`
+
+	Mean   uint64   `json:"mean_ns"`
+	StdDev uint64   `json:"stddev_ns"`
+	CV     *float64 `json:"cv,omitempty"`
+	Min    *uint64  `json:"min_ns,omitempty"`
+	Max    *uint64  `json:"max_ns,omitempty"`
+
+	Percentiles *map[string]uint64 `json:"percentiles_ns,omitempty"`
+
+	Timeline []uint64 `json:"timeline_ns,omitempty"`
+}
+
+// PairedDifference summarizes paired differences (candidate - baseline) in
+// nanoseconds, with a bootstrap confidence interval for their mean and a
+// paired significance test.
+type PairedDifference struct {
+	Test       string  `json:"test"`       // "wilcoxon" or "paired-t"
+	Alpha      float64 `json:"alpha"`      // significance level, e.g. 0.05
+	Confidence float64 `json:"confidence"` // level of the interval, e.g. 0.95
+
+	Mean     float64  `json:"mean_ns"`
+	StdDev   float64  `json:"stddev_ns"`
+	MeanPct  *float64 `json:"mean_pct,omitempty"` // relative to the baseline mean
+	CILow    float64  `json:"ci_low_ns"`
+	CIHigh   float64  `json:"ci_high_ns"`
+	Median   float64  `json:"median_ns"`
+	Positive float64  `json:"positive_share"` // fraction of intervals where the candidate was slower
+
+	PValue      float64 `json:"p_value"`
+	Significant bool    `json:"significant"` // p_value < alpha
+}
@@ -0,0 +1,51 @@
+package v1
+
+// Comparison is a Parameter payload comparing the sample distributions of a
+// candidate result against a baseline, e.g. a program before and after an
+// optimisation. Parameters are matched by kind and order of appearance.
+type Comparison struct {
+	Baseline  string `json:"baseline"`  // source of the baseline results, e.g. a file name
+	Candidate string `json:"candidate"` // source of the candidate results
+
+	// Methodology
+	Test       string  `json:"test"`       // "mann-whitney", "welch" or "bootstrap"
+	Alpha      float64 `json:"alpha"`      // significance level, e.g. 0.05
+	Confidence float64 `json:"confidence"` // level of the delta confidence intervals, e.g. 0.95
+	Resamples  int     `json:"resamples"`  // bootstrap resamples behind the intervals
+
+	Metrics []MetricComparison `json:"metrics"`
+}
+
+// MetricComparison compares one metric's distribution, e.g. latency.
+type MetricComparison struct {
+	Parameter string `json:"parameter"`      // kind of the compared parameters, e.g. "latency"
+	Metric    string `json:"metric"`         // e.g. "latency", "cycles_per_run"
+	Unit      string `json:"unit,omitempty"` // e.g. "ns", "per_sec"
+
+	BaselineSamples  int `json:"baseline_samples"`
+	CandidateSamples int `json:"candidate_samples"`
+
+	// Result of the significance test on the whole distribution
+	PValue      float64 `json:"p_value"`
+	Significant bool    `json:"significant"` // p_value < alpha
+
+	// Set when either side has no timeline, e.g. results written without
+	// --timeline: the statistics are deltas of the recorded summaries,
+	// without confidence intervals or a significance test
+	SummaryOnly bool `json:"summary_only,omitempty"`
+
+	// Mean and percentiles, e.g. "mean", "p50", "p99"
+	Statistics []StatisticDelta `json:"statistics"`
+}
+
+// StatisticDelta is the change of one summary statistic, candidate minus
+// baseline, with a bootstrap confidence interval for the difference.
+type StatisticDelta struct {
+	Name      string   `json:"name"`
+	Baseline  float64  `json:"baseline"`
+	Candidate float64  `json:"candidate"`
+	Delta     float64  `json:"delta"`
+	DeltaPct  *float64 `json:"delta_pct,omitempty"` // undefined for a zero baseline
+	CILow     float64  `json:"ci_low"`
+	CIHigh    float64  `json:"ci_high"`
+}
@@ -0,0 +1,38 @@
+package v1
+
+import "time"
+
+// Integrity accounts for the polls of a measurement that yielded no usable
+// reading, so results built from fewer intervals than expected are not
+// mistaken for complete ones.
+type Integrity struct {
+	Polls           uint64 `json:"polls"`                     // polls of the program's counters, failed ones included
+	FailedPolls     uint64 `json:"failed_polls"`              // polls that returned an error
+	MissingProgram  uint64 `json:"missing_program_intervals"` // failed polls that found no program with the ID
+	ZeroActivity    uint64 `json:"zero_activity_intervals"`   // intervals after warmup without invocations
+	CollectorErrors uint64 `json:"collector_errors"`          // readings a collector failed to process, e.g. probe reads
+
+	ErrorRate float64 `json:"error_rate"` // FailedPolls / Polls
+
+	// Metrics left out of the results for lack of samples, e.g. the latency
+	// of a program that was never invoked
+	NoSamples []string `json:"no_samples,omitempty"`
+
+	// Distinct errors in order of first occurrence, at most MaxIntegrityErrors
+	Errors []ErrorCount `json:"errors,omitempty"`
+
+	// Conditions that make the results incomplete or unrepresentative
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// MaxIntegrityErrors bounds Integrity.Errors; further distinct errors are
+// only counted.
+const MaxIntegrityErrors = 10
+
+// ErrorCount is an error that occurred Count times during a measurement.
+type ErrorCount struct {
+	Message string     `json:"message"`
+	Count   uint64     `json:"count"`
+	First   *time.Time `json:"first,omitempty"`
+	Last    *time.Time `json:"last,omitempty"`
+}
@@ -1,13 +1,23 @@
 package v1
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Parameter interface {
 	Kind() string
 }
 
-func (Latency) Kind() string { return "latency" }
-func (Cpu) Kind() string     { return "cpu" }
+func (Latency) Kind() string    { return "latency" }
+func (Cpu) Kind() string        { return "cpu" }
+func (Health) Kind() string     { return "health" }
+func (Throughput) Kind() string { return "throughput" }
+func (Profile) Kind() string    { return "profile" }
+func (Bench) Kind() string      { return "bench" }
+func (Comparison) Kind() string { return "comparison" }
+func (ABTest) Kind() string     { return "ab" }
+func (Trials) Kind() string     { return "trials" }
 
 // Latency is a Parameter payload containing distribution-aware latency statistics.
 // Units: all duration-like fields are nanoseconds unless otherwise stated.
@@ -23,7 +33,7 @@ type Latency struct {
 
 	// Volume / integrity
 	Samples uint64   `json:"samples"`                // n
-	Dropped *uint64  `json:"dropped,omitempty"`      // lost events / ringbuf drops etc.
+	Dropped *uint64  `json:"dropped,omitempty"`      // invocations skipped by the kernel (recursion misses)
 	Rate    *float64 `json:"rate_per_sec,omitempty"` // samples/sec, if computed
 
 	// Summary stats (nanoseconds)
@@ -36,12 +46,43 @@ type Latency struct {
 	// Percentiles in nanoseconds: keys like "p50", "p90", "p99", "p99_9"
 	Percentiles *map[string]uint64 `json:"percentiles_ns,omitempty"`
 
+	// Per-interval mean latency in nanoseconds, in collection order.
+	// This is the raw distribution the summary stats are computed from.
+	Timeline []uint64 `json:"timeline_ns,omitempty"`
+
+	// Latency per return code of the program, keyed by the decimal return
+	// value (e.g. "1" for XDP_DROP); measured by fexit probes
+	ByReturnCode map[string]ReturnCodeLatency `json:"by_retval,omitempty"`
+
+	// Statistics of recent windows, keyed e.g. "last_10s" or "tumbling_1m"
+	Windows map[string]WindowStats `json:"windows,omitempty"`
+
 	// Measurement semantics / reproducibility
 	Clock     *string `json:"clock,omitempty"`     // e.g. "ktime_ns", "cycles"
 	Histogram *string `json:"histogram,omitempty"` // e.g. "log2", "ddsketch", etc.
 
 }
 
+// ReturnCodeLatency is the latency of the invocations that returned one
+// value, e.g. the packets an XDP program passed. Summary stats are over the
+// per-interval mean latency of those invocations, in nanoseconds.
+type ReturnCodeLatency struct {
+	Retval  uint32 `json:"retval"`
+	Verdict string `json:"verdict,omitempty"` // e.g. "XDP_PASS", "TC_ACT_SHOT"
+
+	Runs    uint64  `json:"runs"`
+	Share   float64 `json:"share"`   // fraction of all runs, 0..1
+	Samples uint64  `json:"samples"` // intervals with at least one such run
+
+	Mean   uint64   `json:"mean_ns"`
+	StdDev uint64   `json:"stddev_ns"`
+	CV     *float64 `json:"cv,omitempty"`
+	Min    *uint64  `json:"min_ns,omitempty"`
+	Max    *uint64  `json:"max_ns,omitempty"`
+
+	Percentiles *map[string]uint64 `json:"percentiles_ns,omitempty"`
+}
+
 type Cpu struct {
 	// Identity / target
 	ID uint32 `json:"id"` // e.g. kernel bpf program id (or bpfstat target id)
@@ -57,16 +98,250 @@ type Cpu struct {
 	Dropped *uint64  `json:"dropped,omitempty"`      // lost events / ringbuf drops etc.
 	Rate    *float64 `json:"rate_per_sec,omitempty"` // samples/sec, if computed
 
-	// Summary stats (nanoseconds)
-	// Ratio fields are 0..1 (e.g. 0.2375 == 23.75%)
-	Mean   float64  `json:"mean"`          // ratio
-	StdDev float64  `json:"stddev"`        // ratio
-	Min    *float64 `json:"min,omitempty"` // ratio
-	Max    *float64 `json:"max,omitempty"` // ratio
-	CV     *float64 `json:"cv,omitempty"`  // still stddev/mean (dimensionless)
+	// Summary stats in cores consumed: ΔRuntime/ΔWall per interval, where
+	// 1.0 is one fully busy CPU. Values above 1 mean the program ran on
+	// several CPUs concurrently.
+	Mean   float64  `json:"cores_mean"`
+	StdDev float64  `json:"cores_stddev"`
+	Min    *float64 `json:"cores_min,omitempty"`
+	Max    *float64 `json:"cores_max,omitempty"`
+	CV     *float64 `json:"cv,omitempty"` // stddev/mean (dimensionless)
+
+	// Machine capacity the fractions below are relative to: online CPUs
+	// usable by bpfstats (i.e. restricted by its cgroup cpuset/affinity).
+	CPUs int `json:"cpus"`
+
+	// Fraction of total machine capacity, cores / CPUs (0..1, e.g. 0.2375 == 23.75%)
+	MachineMean   float64  `json:"machine_fraction_mean"`
+	MachineStdDev float64  `json:"machine_fraction_stddev"`
+	MachineMin    *float64 `json:"machine_fraction_min,omitempty"`
+	MachineMax    *float64 `json:"machine_fraction_max,omitempty"`
+
+	// Optional per-CPU attribution of invocations and runtime
+	PerCPU *PerCPUBreakdown `json:"per_cpu,omitempty"`
+
+	// Cores consumed in recent windows, keyed e.g. "last_10s" or "tumbling_1m"
+	Windows map[string]WindowStats `json:"windows,omitempty"`
 
 	// Measurement semantics / reproducibility
 	Clock     *string `json:"clock,omitempty"`     // e.g. "ktime_ns", "cycles"
 	Histogram *string `json:"histogram,omitempty"` // e.g. "log2", "ddsketch", etc.
 
 }
+
+// UnmarshalJSON also reads result files written before CPU cost was reported
+// in cores, whose "mean", "stddev", "min" and "max" held the same
+// ΔRuntime/ΔWall ratio under other keys.
+func (c *Cpu) UnmarshalJSON(data []byte) error {
+	type cpu Cpu // without this method
+	var v struct {
+		cpu
+		LegacyMean   *float64 `json:"mean"`
+		LegacyStdDev *float64 `json:"stddev"`
+		LegacyMin    *float64 `json:"min"`
+		LegacyMax    *float64 `json:"max"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = Cpu(v.cpu)
+	if v.LegacyMean != nil {
+		c.Mean = *v.LegacyMean
+	}
+	if v.LegacyStdDev != nil {
+		c.StdDev = *v.LegacyStdDev
+	}
+	if v.LegacyMin != nil {
+		c.Min = v.LegacyMin
+	}
+	if v.LegacyMax != nil {
+		c.Max = v.LegacyMax
+	}
+	return nil
+}
+
+// Health is a Parameter payload describing whether the program actually ran
+// every time it was triggered. The kernel skips a program instead of running
+// it when another BPF program is already executing on the same CPU (e.g.
+// fentry/kprobe programs firing from within BPF); such skips are counted as
+// recursion misses and are invisible in runtime statistics.
+type Health struct {
+	// Identity / target
+	ID uint32 `json:"id"`
+
+	// Measurement window
+	Duration time.Duration  `json:"duration"`
+	Warmup   *time.Duration `json:"warmup,omitempty"`
+	Started  *time.Time     `json:"started,omitempty"`
+	Ended    *time.Time     `json:"ended,omitempty"`
+
+	// Volume
+	Samples uint64 `json:"samples"` // observations of the kernel counters
+
+	// Counter deltas over the window
+	Runs            uint64 `json:"runs"`             // run_cnt
+	RecursionMisses uint64 `json:"recursion_misses"` // recursion_misses
+
+	// Rates (per second of window) and ratios
+	RunRate        float64 `json:"run_rate_per_sec"`
+	MissRate       float64 `json:"recursion_miss_rate_per_sec"`
+	MissedFraction float64 `json:"missed_fraction"` // misses / (runs + misses), 0..1
+}
+
+// Throughput is a Parameter payload containing the distribution of the
+// program's invocation rate, computed from run_cnt deltas per interval.
+// Intervals without any invocation are included as zero-rate samples.
+type Throughput struct {
+	// Identity / target
+	ID uint32 `json:"id"`
+
+	// Measurement window
+	Duration time.Duration  `json:"duration"`
+	Warmup   *time.Duration `json:"warmup,omitempty"`
+	Started  *time.Time     `json:"started,omitempty"`
+	Ended    *time.Time     `json:"ended,omitempty"`
+
+	// Volume / integrity
+	Samples uint64   `json:"samples"`                // n intervals
+	Rate    *float64 `json:"rate_per_sec,omitempty"` // samples/sec, if computed
+
+	// Summary stats (invocations per second)
+	Mean   float64  `json:"mean_per_sec"`
+	StdDev float64  `json:"stddev_per_sec"`
+	CV     *float64 `json:"cv,omitempty"`
+	Min    *float64 `json:"min_per_sec,omitempty"`
+	Max    *float64 `json:"max_per_sec,omitempty"`
+
+	// Percentiles in invocations per second: keys like "p50", "p99"
+	Percentiles *map[string]float64 `json:"percentiles_per_sec,omitempty"`
+
+	// Per-interval invocation rate, in collection order
+	Timeline []float64 `json:"timeline_per_sec,omitempty"`
+}
+
+// Profile is a Parameter payload containing hardware counter statistics per
+// invocation, read around the program by fentry/fexit probes. Each metric is
+// computed per interval from counter deltas, e.g. cycles/runs, and summarized
+// over intervals like latency.
+type Profile struct {
+	// Identity / target
+	ID uint32 `json:"id"`
+
+	// Measurement window
+	Duration time.Duration  `json:"duration"`
+	Warmup   *time.Duration `json:"warmup,omitempty"`
+	Started  *time.Time     `json:"started,omitempty"`
+	Ended    *time.Time     `json:"ended,omitempty"`
+
+	// Volume / integrity
+	Samples uint64 `json:"samples"` // n intervals with at least one run
+	Runs    uint64 `json:"runs"`    // invocations profiled
+	Source  string `json:"source"`  // e.g. "fentry/fexit+perf"
+
+	// Per-invocation metrics: keys like "cycles_per_run", "instructions_per_run",
+	// "ipc", "llc_misses_per_run", "branch_misses_per_run"
+	Metrics map[string]CounterStats `json:"metrics"`
+
+	// Requested counters the PMU does not provide
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+// CounterStats summarizes one profiled metric over the collection intervals.
+type CounterStats struct {
+	Mean   float64  `json:"mean"`
+	StdDev float64  `json:"stddev"`
+	CV     *float64 `json:"cv,omitempty"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+
+	// Percentiles: keys like "p50", "p99"
+	Percentiles *map[string]float64 `json:"percentiles,omitempty"`
+
+	// Per-interval value, in collection order
+	Timeline []float64 `json:"timeline,omitempty"`
+}
+
+// Bench is a Parameter payload containing the distribution of per-run
+// durations measured with BPF_PROG_TEST_RUN over a fixed input. Each trial
+// runs the program Repeat times and contributes one sample: the kernel's
+// average duration per run.
+type Bench struct {
+	// Identity / target
+	ID uint32 `json:"id"`
+
+	// Measurement window
+	Started *time.Time `json:"started,omitempty"`
+	Ended   *time.Time `json:"ended,omitempty"`
+
+	// Input and repetition
+	Input        string `json:"input,omitempty"`         // e.g. the input file name
+	InputSize    int    `json:"input_bytes"`             // total size of all inputs
+	Packets      int    `json:"packets,omitempty"`       // inputs read from a packet capture
+	Repeat       int    `json:"repeat"`                  // runs per input and trial
+	WarmupTrials int    `json:"warmup_trials,omitempty"` // discarded trials
+	Retval       uint32 `json:"retval"`                  // return value of the last run
+
+	// Volume
+	Samples uint64 `json:"samples"` // n measurements: inputs x trials
+	Runs    uint64 `json:"runs"`    // program executions across measured trials
+
+	// Summary stats (nanoseconds per run)
+	Mean   uint64   `json:"mean_ns"`
+	StdDev uint64   `json:"stddev_ns"`
+	CV     *float64 `json:"cv,omitempty"`
+	Min    *uint64  `json:"min_ns,omitempty"`
+	Max    *uint64  `json:"max_ns,omitempty"`
+
+	// Percentiles in nanoseconds: keys like "p50", "p90", "p99", "p99_9"
+	Percentiles *map[string]uint64 `json:"percentiles_ns,omitempty"`
+
+	// Per-measurement duration per run in nanoseconds, in execution order
+	Timeline []uint64 `json:"timeline_ns,omitempty"`
+
+	// Breakdown by return code, for packet captures
+	Classes []BenchClass `json:"classes,omitempty"`
+}
+
+// BenchClass summarizes the measurements of the inputs a program returned
+// the same code for, e.g. the packets an XDP program dropped.
+type BenchClass struct {
+	Retval  uint32 `json:"retval"`
+	Verdict string `json:"verdict,omitempty"` // e.g. "XDP_DROP", "TC_ACT_OK"
+
+	Samples uint64  `json:"samples"`
+	Share   float64 `json:"share"` // fraction of all measurements, 0..1
+
+	// Summary stats (nanoseconds per run)
+	Mean   uint64   `json:"mean_ns"`
+	StdDev uint64   `json:"stddev_ns"`
+	CV     *float64 `json:"cv,omitempty"`
+	Min    *uint64  `json:"min_ns,omitempty"`
+	Max    *uint64  `json:"max_ns,omitempty"`
+
+	Percentiles *map[string]uint64 `json:"percentiles_ns,omitempty"`
+}
+
+// PerCPUBreakdown attributes a program's invocations and runtime to the CPUs
+// it ran on, e.g. to check whether cost concentrates on the RSS queue CPUs.
+type PerCPUBreakdown struct {
+	Source string `json:"source"` // e.g. "fentry/fexit"
+
+	// CPUs that ran the program at least once during the window
+	CPUs []CPUCost `json:"cpus"`
+
+	ActiveCPUs int `json:"active_cpus"`
+	// Imbalance is max/mean runtime across the active CPUs, those that ran
+	// the program during the window: 1 when they shared the work evenly,
+	// approaching ActiveCPUs when one of them did almost all of it. A single
+	// active CPU is therefore balanced, see ActiveCPUs for concentration.
+	Imbalance float64 `json:"imbalance"`
+}
+
+// CPUCost is the share of a program's cost incurred on one CPU.
+type CPUCost struct {
+	CPU          int     `json:"cpu"`
+	Runs         uint64  `json:"runs"`
+	Runtime      uint64  `json:"runtime_ns"`
+	Mean         uint64  `json:"mean_ns"`       // runtime per run
+	RuntimeShare float64 `json:"runtime_share"` // fraction of total runtime, 0..1
+}
@@ -0,0 +1,30 @@
+package v1
+
+import "time"
+
+// Program identifies a measured eBPF program as it was loaded in the kernel,
+// so a result remains meaningful after the program ID has been recycled.
+type Program struct {
+	ID   uint32 `json:"id"`             // kernel bpf_prog id
+	Name string `json:"name,omitempty"` // name supplied at load time (truncated to 15 chars by the kernel)
+	Type string `json:"type,omitempty"` // e.g. "XDP", "SchedCLS", "Tracing"
+	Tag  string `json:"tag,omitempty"`  // truncated hash of the bytecode
+
+	BTFID    *uint32    `json:"btf_id,omitempty"`
+	LoadedAt *time.Time `json:"loaded_at,omitempty"`
+
+	// Sizes
+	XlatedSize           *int    `json:"xlated_size_bytes,omitempty"` // translated (post-verifier) bytecode
+	JitedSize            *uint32 `json:"jited_size_bytes,omitempty"`  // native code
+	VerifiedInstructions *uint32 `json:"verified_insns,omitempty"`    // instructions processed by the verifier
+
+	// Relationships
+	MapIDs []uint32      `json:"map_ids,omitempty"`
+	Links  []ProgramLink `json:"links,omitempty"` // links the program is attached through
+}
+
+// ProgramLink is a BPF link attaching a Program.
+type ProgramLink struct {
+	ID   uint32 `json:"id"`
+	Type string `json:"type"` // e.g. "xdp", "tcx", "tracing"
+}
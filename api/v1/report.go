@@ -0,0 +1,94 @@
+package v1
+
+import "time"
+
+const (
+	// APIVersion identifies the schema of documents produced by this package.
+	APIVersion = "bpfstats/v1"
+	// ReportKind is the kind of the Report envelope.
+	ReportKind = "Report"
+)
+
+// Report is the single document produced by a run: the run metadata and every
+// parameter measured during it.
+type Report struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Metadata   RunMetadata      `json:"metadata"`
+	Parameters []TypedParameter `json:"parameters"`
+}
+
+// RunMetadata describes how and where a report was produced.
+type RunMetadata struct {
+	CommandLine []string   `json:"command_line,omitempty"` // os.Args of the run
+	Host        string     `json:"host,omitempty"`         // hostname
+	Kernel      string     `json:"kernel,omitempty"`       // uname release, e.g. "6.8.0-45-generic"
+	ToolVersion string     `json:"tool_version,omitempty"` // bpfstats version
+	Started     *time.Time `json:"started,omitempty"`
+	Ended       *time.Time `json:"ended,omitempty"`
+
+	// Host properties that influence measurement variance
+	Environment *Environment `json:"environment,omitempty"`
+
+	// Programs measured during the run, referenced by Parameter IDs
+	Programs []Program `json:"programs,omitempty"`
+
+	// Failed polls and idle intervals of the run
+	Integrity *Integrity `json:"integrity,omitempty"`
+}
+
+// Environment is a fingerprint of the host a measurement ran on. Fields are
+// nil or empty when the corresponding kernel interface is unavailable.
+type Environment struct {
+	// Kernel
+	KernelVersion string `json:"kernel_version,omitempty"` // uname version, e.g. "#45-Ubuntu SMP ..."
+
+	// BPF sysctls
+	BPFJITEnable    *int `json:"bpf_jit_enable,omitempty"`    // net.core.bpf_jit_enable
+	BPFJITHarden    *int `json:"bpf_jit_harden,omitempty"`    // net.core.bpf_jit_harden
+	BPFJITKallsyms  *int `json:"bpf_jit_kallsyms,omitempty"`  // net.core.bpf_jit_kallsyms
+	BPFStatsEnabled *int `json:"bpf_stats_enabled,omitempty"` // kernel.bpf_stats_enabled
+
+	// CPU
+	CPUModel      string     `json:"cpu_model,omitempty"`
+	OnlineCPUs    int        `json:"online_cpus,omitempty"`
+	AvailableCPUs int        `json:"available_cpus,omitempty"` // online CPUs in the cpuset/affinity of bpfstats
+	SMT           string     `json:"smt,omitempty"`            // smt/control: on, off, forceoff, notsupported
+	SMTActive     *bool      `json:"smt_active,omitempty"`     // smt/active
+	Governors     []string   `json:"governors,omitempty"`      // distinct scaling_governor values
+	Turbo         *bool      `json:"turbo,omitempty"`          // turbo/boost enabled
+	IsolatedCPUs  string     `json:"isolated_cpus,omitempty"`  // isolcpus list, e.g. "2-3"
+	NUMANodes     []NUMANode `json:"numa_nodes,omitempty"`
+
+	// Conditions known to inflate variance or bias results
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// NUMANode lists the CPUs of a NUMA node.
+type NUMANode struct {
+	ID   int    `json:"id"`
+	CPUs string `json:"cpus"` // cpulist, e.g. "0-15,32-47"
+}
+
+// NewReport returns a Report envelope holding params.
+func NewReport(meta RunMetadata, params ...Parameter) *Report {
+	r := &Report{
+		APIVersion: APIVersion,
+		Kind:       ReportKind,
+		Metadata:   meta,
+		Parameters: make([]TypedParameter, 0, len(params)),
+	}
+	for _, p := range params {
+		r.Parameters = append(r.Parameters, TypedParameter{Parameter: p})
+	}
+	return r
+}
+
+// Params returns the report parameters without their typed wrapper.
+func (r *Report) Params() []Parameter {
+	params := make([]Parameter, len(r.Parameters))
+	for i, t := range r.Parameters {
+		params[i] = t.Parameter
+	}
+	return params
+}
@@ -0,0 +1,43 @@
+package v1
+
+import "time"
+
+// Trials is a Parameter payload summarizing a measurement repeated several
+// times. Single runs hide between-run variance (ASLR, JIT layout, cache
+// state); this reports each metric per trial, pooled over all trials, and
+// the spread between trials with a confidence interval for its mean. The
+// per-trial parameters are reported alongside, in trial order.
+type Trials struct {
+	ID uint32 `json:"id"`
+
+	// Repetition
+	Trials   int            `json:"trials"`
+	Duration time.Duration  `json:"duration"` // per trial, excluding warmup
+	Warmup   *time.Duration `json:"warmup,omitempty"`
+	Cooldown time.Duration  `json:"cooldown"` // pause between trials
+
+	Confidence float64 `json:"confidence"` // level of the intervals, e.g. 0.95
+
+	// Metrics keyed by parameter, statistic and unit, e.g. "latency_mean_ns",
+	// "latency_p99_ns", "cpu_cores_mean", "throughput_mean_per_sec"
+	Metrics map[string]TrialMetric `json:"metrics"`
+}
+
+// TrialMetric is one metric across trials.
+type TrialMetric struct {
+	PerTrial []float64 `json:"per_trial"` // value in each trial, in trial order
+
+	// Computed over the samples of all trials together
+	Pooled float64 `json:"pooled"`
+
+	// Between-trial statistics of the per-trial values
+	Mean   float64  `json:"mean"`
+	StdDev float64  `json:"stddev"`
+	CV     *float64 `json:"cv,omitempty"`
+	CILow  float64  `json:"ci_low"` // Student's t interval for the mean
+	CIHigh float64  `json:"ci_high"`
+
+	// Fraction of the total sample variance due to differences between
+	// trials (intraclass correlation), for per-interval means
+	BetweenShare *float64 `json:"between_share,omitempty"`
+}
@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// TypedParameter wraps a Parameter so that its kind is encoded alongside its
+// fields, e.g. {"kind":"latency","id":42,...}. Decoding dispatches on that
+// kind, which makes a Parameter round-trip through JSON (and YAML via JSON).
+type TypedParameter struct {
+	Parameter
+}
+
+// parameterKinds maps Parameter.Kind() to a decoder for the concrete type.
+var parameterKinds = map[string]func([]byte) (Parameter, error){
+	Latency{}.Kind():    decodeParameter[Latency],
+	Cpu{}.Kind():        decodeParameter[Cpu],
+	Health{}.Kind():     decodeParameter[Health],
+	Throughput{}.Kind(): decodeParameter[Throughput],
+	Profile{}.Kind():    decodeParameter[Profile],
+	Bench{}.Kind():      decodeParameter[Bench],
+	Comparison{}.Kind(): decodeParameter[Comparison],
+	ABTest{}.Kind():     decodeParameter[ABTest],
+	Trials{}.Kind():     decodeParameter[Trials],
+}
+
+func decodeParameter[T Parameter](data []byte) (Parameter, error) {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Kinds returns the sorted list of parameter kinds that can be decoded.
+func Kinds() []string {
+	kinds := make([]string, 0, len(parameterKinds))
+	for k := range parameterKinds {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func (t TypedParameter) MarshalJSON() ([]byte, error) {
+	if t.Parameter == nil {
+		return []byte("null"), nil
+	}
+	data, err := json.Marshal(t.Parameter)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 2 || data[0] != '{' {
+		return nil, fmt.Errorf("parameter %s does not encode as a JSON object", t.Kind())
+	}
+	kind, err := json.Marshal(t.Kind())
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"kind":`)
+	buf.Write(kind)
+	if rest := bytes.TrimSpace(data[1:]); len(rest) > 0 && rest[0] != '}' {
+		buf.WriteByte(',')
+	}
+	buf.Write(data[1:])
+	return buf.Bytes(), nil
+}
+
+func (t *TypedParameter) UnmarshalJSON(data []byte) error {
+	var head struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return err
+	}
+	if head.Kind == "" {
+		return fmt.Errorf("parameter has no kind")
+	}
+	decode, ok := parameterKinds[head.Kind]
+	if !ok {
+		return fmt.Errorf("unknown parameter kind %q", head.Kind)
+	}
+	par, err := decode(data)
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", head.Kind, err)
+	}
+	t.Parameter = par
+	return nil
+}
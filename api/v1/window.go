@@ -0,0 +1,23 @@
+package v1
+
+import "time"
+
+// WindowStats summarizes the per-interval samples of a recent window of a
+// measurement, e.g. the last 10 seconds, so long-running measurements can
+// report current behavior besides the whole run. Values are in the unit of
+// the parameter holding them: nanoseconds for latency, cores for cpu.
+type WindowStats struct {
+	Mode  string        `json:"mode"`            // "sliding" (trailing span) or "tumbling" (last complete span)
+	Span  time.Duration `json:"span"`            // window length
+	Start *time.Time    `json:"start,omitempty"` // window bounds
+	End   *time.Time    `json:"end,omitempty"`
+
+	Samples uint64 `json:"samples"`
+
+	Mean   float64  `json:"mean"`
+	StdDev float64  `json:"stddev"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+
+	Percentiles map[string]float64 `json:"percentiles,omitempty"`
+}
@@ -0,0 +1,335 @@
+/*
+Copyright © 2026 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/Tjaarda1/bpfstats/internal/compare"
+	"github.com/Tjaarda1/bpfstats/internal/environment"
+	"github.com/Tjaarda1/bpfstats/internal/output"
+	"github.com/Tjaarda1/bpfstats/internal/program"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdAB returns the ab command
+func NewCmdAB(parent string) *cobra.Command {
+	flags := NewABFlags()
+	cmd := &cobra.Command{
+		Use:                   "ab",
+		DisableFlagsInUseLine: true,
+		Short:                 abShort,
+		Long:                  abLong,
+		Example:               abExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o, err := flags.ToOptions(parent, args)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true // flags are valid, errors are runtime failures
+			return o.Run()
+		},
+	}
+
+	flags.AddFlags(cmd)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(NewCmdAB(rootCmd.Name()))
+}
+
+var (
+	abLong = `
+		Compare two eBPF programs measured at the same time.
+
+		This command measures a baseline and a candidate program (e.g. the old and new
+		version of a program attached to different interfaces) in the same window. Every
+		sampling interval it reads the kernel statistics of both back to back, alternating
+		which one is read first, and pairs their mean latency in that interval.
+
+		Because both programs experience the same host noise (frequency scaling, interrupts,
+		co-located load), the paired difference cancels most of it, which sequential runs
+		cannot. The report holds the latency of each program, the mean paired difference
+		with a bootstrap confidence interval, and a paired significance test: the Wilcoxon
+		signed-rank test (default) or a paired t-test. Intervals in which only one program
+		ran are counted as unpaired and discarded.`
+
+	abExample = `
+		# Compare program 43 against program 42 for 60 seconds
+		bpfstat ab --baseline 42 --candidate 43 --duration 60s
+
+		# Use a paired t-test and a 99% interval, output as JSON
+		bpfstat ab --baseline 42 --candidate 43 --duration 60s --test paired-t --confidence 0.99 -o json
+
+		# Extract the mean difference in nanoseconds for scripting
+		bpfstat ab --baseline 42 --candidate 43 --duration 60s \
+			-o jsonpath='{.parameters[?(@.kind=="ab")].difference.mean_ns}'`
+	abShort = "Compare two eBPF programs measured at the same time."
+)
+
+// ABFlags are converted to ABOptions
+type ABFlags struct {
+
+	// Target selection
+	Baseline  uint32
+	Candidate uint32
+
+	// Measurement window
+	Duration time.Duration
+	Warmup   time.Duration
+
+	// Methodology
+	Test       string
+	Alpha      float64
+	Confidence float64
+	Resamples  int
+
+	// Output selection
+	PrintFlags *PrintFlags
+
+	// Stats config
+	Percentiles []string // e.g. ["50","90","99","99.9"] or ["p50","p99"]
+}
+
+// NewABFlags returns a default ABFlags
+func NewABFlags() *ABFlags {
+	return &ABFlags{
+		Test:       compare.TestWilcoxon,
+		Alpha:      0.05,
+		Confidence: 0.95,
+		Resamples:  2000,
+		PrintFlags: NewPrintFlags(),
+	}
+}
+
+// AddFlags registers flags for a cli
+func (flags *ABFlags) AddFlags(cmd *cobra.Command) {
+	// Target selection
+	cmd.Flags().Uint32Var(&flags.Baseline, "baseline", flags.Baseline,
+		"eBPF program identifier of the baseline (e.g. the current version).")
+	cmd.Flags().Uint32Var(&flags.Candidate, "candidate", flags.Candidate,
+		"eBPF program identifier of the candidate (e.g. the new version).")
+
+	// Measurement window
+	cmd.Flags().DurationVar(&flags.Duration, "duration", flags.Duration,
+		"How long to collect samples for (e.g. 10s, 1m).")
+	cmd.Flags().DurationVar(&flags.Warmup, "warmup", flags.Warmup,
+		"Optional warmup period to discard before measurement (e.g. 5s).")
+
+	// Methodology
+	cmd.Flags().StringVar(&flags.Test, "test", flags.Test,
+		"Paired significance test: "+strings.Join(compare.PairedTests(), ", ")+".")
+	cmd.Flags().Float64Var(&flags.Alpha, "alpha", flags.Alpha,
+		"Significance level below which the difference is reported as significant.")
+	cmd.Flags().Float64Var(&flags.Confidence, "confidence", flags.Confidence,
+		"Confidence level of the interval for the mean difference (e.g. 0.95).")
+	cmd.Flags().IntVar(&flags.Resamples, "resamples", flags.Resamples,
+		"Number of bootstrap resamples behind the confidence interval.")
+
+	// Stats config
+	cmd.Flags().StringSliceVar(&flags.Percentiles, "percentiles", flags.Percentiles,
+		"Percentile set to compute: default, wide, or tail. Example: --percentiles tail")
+
+	// Output selection
+	flags.PrintFlags.AddFlags(cmd)
+}
+
+func (flags *ABFlags) ToOptions(parent string, args []string) (*ABOptions, error) {
+	// Validation
+	if flags.Baseline == 0 || flags.Candidate == 0 {
+		return nil, fmt.Errorf("--baseline and --candidate are required")
+	}
+	if flags.Baseline == flags.Candidate {
+		return nil, fmt.Errorf("--baseline and --candidate must be different programs")
+	}
+	if flags.Duration == 0 {
+		return nil, fmt.Errorf("--duration is required")
+	}
+	if !slices.Contains(compare.PairedTests(), flags.Test) {
+		return nil, fmt.Errorf("unknown --test %q, use one of %s", flags.Test, strings.Join(compare.PairedTests(), ", "))
+	}
+	if flags.Alpha <= 0 || flags.Alpha >= 1 {
+		return nil, fmt.Errorf("--alpha must be between 0 and 1")
+	}
+	if flags.Confidence <= 0 || flags.Confidence >= 1 {
+		return nil, fmt.Errorf("--confidence must be between 0 and 1")
+	}
+	if flags.Resamples <= 0 {
+		return nil, fmt.Errorf("--resamples must be positive")
+	}
+
+	o := &ABOptions{
+		Baseline:  flags.Baseline,
+		Candidate: flags.Candidate,
+		Duration:  flags.Duration,
+		Compare: compare.Options{
+			Test:       flags.Test,
+			Alpha:      flags.Alpha,
+			Confidence: flags.Confidence,
+			Resamples:  flags.Resamples,
+		},
+		ErrOut: os.Stderr,
+	}
+
+	// Handle optional warmup
+	if flags.Warmup > 0 {
+		o.Warmup = &flags.Warmup
+	}
+
+	// Determine output format
+	if err := flags.PrintFlags.Validate(); err != nil {
+		return nil, err
+	}
+	o.Format = flags.PrintFlags.Format()
+	o.ToPrinter = flags.PrintFlags.ToPrinter
+	o.Timelines = flags.PrintFlags.Timelines()
+	o.OutputPath = flags.PrintFlags.OutputFile
+
+	o.PercentileKeys = normalizePercentiles(flags.Percentiles)
+
+	return o, nil
+}
+
+type ABOptions struct {
+
+	// Target selection
+	Baseline  uint32
+	Candidate uint32
+
+	// Measurement window
+	Duration time.Duration
+	Warmup   *time.Duration // nil => no warmup/discard
+
+	// Methodology of the paired difference
+	Compare compare.Options
+
+	// Output selection
+	Format     string // printer name, e.g. "text", "json", "csv"
+	Out        io.Writer
+	OutputPath string // file path (if specified)
+	ToPrinter  func(io.Writer) (output.Printer, error)
+	Timelines  bool      // keep per-interval timelines in the results
+	ErrOut     io.Writer // warnings and diagnostics
+
+	PercentileKeys []string // normalized: ["p50","p90","p99","p99_9"]
+}
+
+func (o *ABOptions) Run() error {
+	out, err := openOutput(o.OutputPath)
+	if err != nil {
+		return fmt.Errorf("setup output: %w", err)
+	}
+	defer closeOutput(out)
+	o.Out = out
+
+	// Fingerprint the host before measuring
+	env := environment.Capture()
+	printEnvironmentWarnings(o.ErrOut, env)
+
+	var programs []bpfsv1.Program
+	for _, id := range []uint32{o.Baseline, o.Candidate} {
+		prog, err := program.Describe(id)
+		if err != nil {
+			return fmt.Errorf("describe program %d: %w", id, err)
+		}
+		programs = append(programs, *prog)
+	}
+
+	analyze := func(baseline, candidate []float64) (bpfsv1.PairedDifference, error) {
+		return compare.Paired(baseline, candidate, o.Compare)
+	}
+	interval := 100 * time.Millisecond // sampling interval
+	abC := collector.NewABCollector(o.Baseline, o.Candidate, interval, o.Warmup, o.PercentileKeys, analyze)
+
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), o.Duration)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- abC.Start(ctx) }()
+
+	if o.Format == "text" {
+		fmt.Fprintf(o.Out, "Comparing eBPF program %d (candidate) against %d (baseline) for %v...\n\n",
+			o.Candidate, o.Baseline, o.Duration)
+		o.runWithLiveUpdates(ctx, abC)
+	} else {
+		<-ctx.Done()
+	}
+	if err := <-errCh; err != nil && err != context.DeadlineExceeded {
+		return err
+	}
+
+	integrity := abC.Integrity()
+	snap, err := abC.Snapshot()
+	if err != nil {
+		// Without pairs, the failed polls usually tell why
+		if len(integrity.Errors) > 0 {
+			last := integrity.Errors[len(integrity.Errors)-1]
+			return fmt.Errorf("get final snapshot: %w (%d of %d polls failed, e.g. %s)",
+				err, integrity.FailedPolls, integrity.Polls, last.Message)
+		}
+		return fmt.Errorf("get final snapshot: %w", err)
+	}
+
+	if o.Format == "text" {
+		fmt.Fprintln(o.Out, "\n\n=== Final Statistics ===")
+	}
+
+	outputter, err := o.ToPrinter(o.Out)
+	if err != nil {
+		return err
+	}
+
+	meta := environment.RunMetadata(started, time.Now(), env)
+	meta.Programs = programs
+	meta.Integrity = integrity
+	printIntegrityWarnings(o.ErrOut, integrity)
+	params := []bpfsv1.Parameter{snap}
+	if !o.Timelines {
+		params = withoutTimelines(params...)
+	}
+	if err := outputter.OutputReport(bpfsv1.NewReport(meta, params...), o.Out); err != nil {
+		return fmt.Errorf("output statistics: %w", err)
+	}
+	return nil
+}
+
+func (o *ABOptions) runWithLiveUpdates(ctx context.Context, abC *collector.ABCollector) {
+	ticker := time.NewTicker(1 * time.Second) // update every second
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snapshot, err := abC.Snapshot()
+			if err != nil {
+				// No pairs yet, skip
+				continue
+			}
+
+			// Clear previous line (ANSI escape)
+			fmt.Fprintf(o.Out, "\r\033[K")
+
+			ab := snapshot.(bpfsv1.ABTest)
+			fmt.Fprintf(o.Out, "Pairs: %d | Baseline: %v | Candidate: %v | Difference: %+.1fns",
+				ab.Pairs,
+				time.Duration(ab.Baseline.Mean),
+				time.Duration(ab.Candidate.Mean),
+				ab.Difference.Mean,
+			)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright © 2026 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/Tjaarda1/bpfstats/internal/output"
+	"github.com/Tjaarda1/bpfstats/internal/record"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdAnalyze returns the analyze command
+func NewCmdAnalyze(parent string) *cobra.Command {
+	flags := NewAnalyzeFlags()
+	cmd := &cobra.Command{
+		Use:                   "analyze FILE",
+		DisableFlagsInUseLine: true,
+		Short:                 analyzeShort,
+		Long:                  analyzeLong,
+		Example:               analyzeExample,
+		Args:                  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o, err := flags.ToOptions(parent, args)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true // flags are valid, errors are runtime failures
+			return o.Run()
+		},
+	}
+
+	flags.AddFlags(cmd)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(NewCmdAnalyze(rootCmd.Name()))
+}
+
+var (
+	analyzeLong = `
+		Analyze a recording made with "record" offline.
+
+		This command replays the observations of a recording through the latency, cpu,
+		health and throughput collectors, the statistics engine and the printers, as if they
+		had been polled live. The report carries the program, host fingerprint and times of
+		the recording, and CPU usage is normalized by the CPUs of the recording host.
+
+		The warmup, percentiles, output format and pass/fail conditions can differ from run
+		to run, so a single recording can be analyzed several ways.`
+
+	analyzeExample = `
+		# Analyze a recording as text
+		bpfstat analyze run.bpfrec
+
+		# Discard the first 5 seconds and report tail percentiles as JSON
+		bpfstat analyze run.bpfrec --warmup 5s --percentiles tail -o json
+
+		# Check a recording against a latency budget
+		bpfstat analyze run.bpfrec --assert 'p99 < 800ns'`
+	analyzeShort = "Analyze a recording made with record offline."
+)
+
+// AnalyzeFlags are converted to AnalyzeOptions
+type AnalyzeFlags struct {
+
+	// Measurement window
+	Warmup time.Duration
+
+	// Output selection
+	PrintFlags *PrintFlags
+	GateFlags  *GateFlags
+
+	// Stats config
+	Metrics     []string // collectors to replay, e.g. ["latency","cpu"]
+	Percentiles []string // e.g. ["50","90","99","99.9"] or ["p50","p99"]
+	Windows     []string // recent windows, e.g. ["10s","1m"]
+	WindowMode  string   // "sliding" or "tumbling"
+}
+
+// NewAnalyzeFlags returns a default AnalyzeFlags
+func NewAnalyzeFlags() *AnalyzeFlags {
+	return &AnalyzeFlags{
+		Metrics:    defaultMetrics,
+		WindowMode: collector.WindowSliding,
+		PrintFlags: NewPrintFlags(),
+		GateFlags:  NewGateFlags(),
+	}
+}
+
+// AddFlags registers flags for a cli
+func (flags *AnalyzeFlags) AddFlags(cmd *cobra.Command) {
+	// Measurement window
+	cmd.Flags().DurationVar(&flags.Warmup, "warmup", flags.Warmup,
+		"Optional warmup period to discard from the start of the recording (e.g. 5s).")
+
+	// Stats config
+	cmd.Flags().StringSliceVar(&flags.Metrics, "metrics", flags.Metrics,
+		"Metrics to compute from the recording: "+strings.Join(collector.Names(), ", ")+".")
+	cmd.Flags().StringSliceVar(&flags.Percentiles, "percentiles", flags.Percentiles,
+		"Percentile set to compute: default, wide, or tail. Example: --percentiles tail")
+	cmd.Flags().StringSliceVar(&flags.Windows, "windows", flags.Windows,
+		"Windows at the end of the recording to report besides the whole run, e.g. --windows 10s,1m")
+	cmd.Flags().StringVar(&flags.WindowMode, "window-mode", flags.WindowMode,
+		"How --windows are computed: sliding (the trailing span) or tumbling (the last complete span).")
+
+	// Output selection
+	flags.PrintFlags.AddFlags(cmd)
+	flags.GateFlags.AddFlags(cmd)
+	flags.GateFlags.AddIntegrityFlags(cmd)
+}
+
+func (flags *AnalyzeFlags) ToOptions(parent string, args []string) (*AnalyzeOptions, error) {
+	metrics, err := validateMetrics(flags.Metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &AnalyzeOptions{
+		Path:    args[0],
+		Metrics: metrics,
+		ErrOut:  os.Stderr,
+	}
+
+	// Handle optional warmup
+	if flags.Warmup > 0 {
+		o.Warmup = &flags.Warmup
+	}
+
+	// Determine output format
+	if err := flags.PrintFlags.Validate(); err != nil {
+		return nil, err
+	}
+	o.ToPrinter = flags.PrintFlags.ToPrinter
+	o.Timelines = flags.PrintFlags.Timelines()
+	o.OutputPath = flags.PrintFlags.OutputFile
+
+	o.PercentileKeys = normalizePercentiles(flags.Percentiles)
+
+	windows, err := collector.ParseWindows(flags.Windows, flags.WindowMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --windows: %w", err)
+	}
+	o.Windows = windows
+
+	// Pass/fail conditions
+	g, err := flags.GateFlags.ToGate()
+	if err != nil {
+		return nil, err
+	}
+	o.Gate = g
+
+	return o, nil
+}
+
+type AnalyzeOptions struct {
+
+	// Input
+	Path string // recording written by record
+
+	// Measurement window
+	Warmup *time.Duration // nil => no warmup/discard
+
+	// Output selection
+	Out        io.Writer
+	OutputPath string // file path (if specified)
+	ToPrinter  func(io.Writer) (output.Printer, error)
+	Timelines  bool      // keep per-interval timelines in the results
+	ErrOut     io.Writer // warnings and diagnostics
+
+	Gate *Gate // pass/fail conditions on the results
+
+	Metrics        []string               // registered collectors to replay, e.g. ["latency","cpu"]
+	PercentileKeys []string               // normalized: ["p50","p90","p99","p99_9"]
+	Windows        []collector.WindowSpec // recent windows reported besides the whole run
+}
+
+func (o *AnalyzeOptions) Run() error {
+	rec, err := record.ReadFile(o.Path)
+	if err != nil {
+		return fmt.Errorf("read recording: %w", err)
+	}
+	if rec.Truncated {
+		fmt.Fprintf(o.ErrOut, "Warning: recording %s is truncated, analyzing up to %v\n",
+			o.Path, rec.Ended.Sub(rec.Header.Started).Round(time.Millisecond))
+	}
+	// Same collectors as the latency command, driven by the recording
+	h := rec.Header
+	collectors, err := collector.New(o.Metrics, collector.Options{
+		ID:          h.ID,
+		Interval:    h.Interval,
+		Warmup:      o.Warmup,
+		Percentiles: o.PercentileKeys,
+		Windows:     o.Windows,
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range collectors {
+		if cpuC, ok := c.(*collector.CpuCollector); ok && h.Metadata.Environment != nil {
+			cpuC.SetCapacity(h.Metadata.Environment.AvailableCPUs)
+		}
+	}
+
+	if err := collector.Replay(h.Started, rec.Ended, o.Warmup, rec.Observations, collectors...); err != nil {
+		return err
+	}
+
+	integrity := rec.Integrity(o.Warmup)
+	params, err := collector.Snapshots(integrity, collectors...)
+	if err != nil {
+		return fmt.Errorf("get final snapshot: %w", err)
+	}
+	summary := integrity.Summary()
+	printIntegrityWarnings(o.ErrOut, summary)
+
+	out, err := openOutput(o.OutputPath)
+	if err != nil {
+		return fmt.Errorf("setup output: %w", err)
+	}
+	defer closeOutput(out)
+	o.Out = out
+
+	outputter, err := o.ToPrinter(o.Out)
+	if err != nil {
+		return err
+	}
+
+	// The report describes the recorded run, not the analysis
+	meta := h.Metadata
+	meta.Started = &h.Started
+	meta.Ended = &rec.Ended
+	meta.Integrity = summary
+	if !o.Timelines {
+		params = withoutTimelines(params...)
+	}
+	report := bpfsv1.NewReport(meta, params...)
+	if err := outputter.OutputReport(report, o.Out); err != nil {
+		return fmt.Errorf("output statistics: %w", err)
+	}
+
+	return o.Gate.Evaluate(report, o.ErrOut)
+}
@@ -0,0 +1,298 @@
+/*
+Copyright © 2026 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/bench"
+	"github.com/Tjaarda1/bpfstats/internal/environment"
+	"github.com/Tjaarda1/bpfstats/internal/output"
+	"github.com/Tjaarda1/bpfstats/internal/pcap"
+	"github.com/Tjaarda1/bpfstats/internal/program"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdBench returns the bench command
+func NewCmdBench(parent string) *cobra.Command {
+	flags := NewBenchFlags()
+	cmd := &cobra.Command{
+		Use:                   "bench",
+		DisableFlagsInUseLine: true,
+		Short:                 benchShort,
+		Long:                  benchLong,
+		Example:               benchExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o, err := flags.ToOptions(parent, args)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true // flags are valid, errors are runtime failures
+			return o.Run()
+		},
+	}
+
+	flags.AddFlags(cmd)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(NewCmdBench(rootCmd.Name()))
+}
+
+var (
+	benchLong = `
+		Microbenchmark an eBPF program with BPF_PROG_TEST_RUN.
+
+		This command runs the selected program in the kernel over a fixed input instead of
+		observing live traffic, so results are deterministic and suitable for CI. Each trial
+		executes the program --repeat times and yields the kernel's average duration per
+		run; the distribution over trials is reported like latency (mean, standard
+		deviation, percentiles).
+
+		The input is passed as the program's packet data. XDP and TC programs expect at
+		least an Ethernet header (14 bytes). Program types without BPF_PROG_TEST_RUN
+		support, such as kprobes, are rejected.
+
+		When --input is a pcap or pcapng capture of Ethernet traffic, every packet is fed to
+		the program in turn, --repeat times per trial. Results are then reported overall and
+		per return code (e.g. XDP_PASS vs XDP_DROP), to measure cost on a realistic traffic
+		mix without a live NIC.`
+
+	benchExample = `
+		# Benchmark XDP program id 42 over a captured packet, 10 trials of 1M runs
+		bpfstat bench --id 42 --input packet.bin --repeat 1000000
+
+		# Replay a traffic capture and break down cost per verdict
+		bpfstat bench --id 42 --input traffic.pcapng --repeat 10000
+
+		# More trials for tighter percentiles, as JSON for CI
+		bpfstat bench --id 42 --input packet.bin --trials 50 -o json
+
+		# Extract the mean duration per run in nanoseconds
+		bpfstat bench --id 42 --input packet.bin \
+			-o jsonpath='{.parameters[?(@.kind=="bench")].mean_ns}'
+
+		# Gate CI: exit with status 2 if p99 exceeds 800ns or costs regress by more than 5%
+		bpfstat bench --id 42 --input packet.bin --assert 'p99 < 800ns' \
+			--baseline main.json --max-regression 5%`
+	benchShort = "Microbenchmark an eBPF program with BPF_PROG_TEST_RUN."
+)
+
+// BenchFlags are converted to BenchOptions
+type BenchFlags struct {
+
+	// Target selection
+	ID uint32
+
+	// Input and repetition
+	Input        string
+	Repeat       int
+	Trials       int
+	WarmupTrials int
+
+	// Output selection
+	PrintFlags *PrintFlags
+	GateFlags  *GateFlags
+
+	// Stats config
+	Percentiles []string // e.g. ["50","90","99","99.9"] or ["p50","p99"]
+}
+
+// NewBenchFlags returns a default BenchFlags
+func NewBenchFlags() *BenchFlags {
+	return &BenchFlags{
+		Repeat:       1000000,
+		Trials:       10,
+		WarmupTrials: 1,
+		PrintFlags:   NewPrintFlags(),
+		GateFlags:    NewGateFlags(),
+	}
+}
+
+// AddFlags registers flags for a cli
+func (flags *BenchFlags) AddFlags(cmd *cobra.Command) {
+	// Target selection
+	cmd.Flags().Uint32Var(&flags.ID, "id", flags.ID,
+		"eBPF program identifier to benchmark (typically the kernel bpf_prog id).")
+
+	// Input and repetition
+	cmd.Flags().StringVar(&flags.Input, "input", flags.Input,
+		"File holding the raw input passed to the program, e.g. a packet, or a pcap/pcapng capture whose packets are replayed.")
+	cmd.Flags().IntVar(&flags.Repeat, "repeat", flags.Repeat,
+		"Number of program runs per input and trial.")
+	cmd.Flags().IntVar(&flags.Trials, "trials", flags.Trials,
+		"Number of measured trials; each contributes one sample.")
+	cmd.Flags().IntVar(&flags.WarmupTrials, "warmup-trials", flags.WarmupTrials,
+		"Number of trials to run and discard before measuring.")
+
+	// Stats config
+	cmd.Flags().StringSliceVar(&flags.Percentiles, "percentiles", flags.Percentiles,
+		"Percentile set to compute: default, wide, or tail. Example: --percentiles tail")
+
+	// Output selection
+	flags.PrintFlags.AddFlags(cmd)
+	flags.GateFlags.AddFlags(cmd)
+}
+
+func (flags *BenchFlags) ToOptions(parent string, args []string) (*BenchOptions, error) {
+	// Validation
+	if flags.ID == 0 {
+		return nil, fmt.Errorf("--id is required")
+	}
+	if flags.Input == "" {
+		return nil, fmt.Errorf("--input is required")
+	}
+	if flags.Repeat <= 0 {
+		return nil, fmt.Errorf("--repeat must be positive")
+	}
+	if flags.Trials <= 0 {
+		return nil, fmt.Errorf("--trials must be positive")
+	}
+	if flags.WarmupTrials < 0 {
+		return nil, fmt.Errorf("--warmup-trials must not be negative")
+	}
+
+	o := &BenchOptions{
+		ID:           flags.ID,
+		InputPath:    flags.Input,
+		Repeat:       flags.Repeat,
+		Trials:       flags.Trials,
+		WarmupTrials: flags.WarmupTrials,
+		ErrOut:       os.Stderr,
+	}
+
+	// Determine output format
+	if err := flags.PrintFlags.Validate(); err != nil {
+		return nil, err
+	}
+	o.Format = flags.PrintFlags.Format()
+	o.ToPrinter = flags.PrintFlags.ToPrinter
+	o.Timelines = flags.PrintFlags.Timelines()
+	o.OutputPath = flags.PrintFlags.OutputFile
+
+	o.PercentileKeys = normalizePercentiles(flags.Percentiles)
+
+	// Pass/fail conditions
+	g, err := flags.GateFlags.ToGate()
+	if err != nil {
+		return nil, err
+	}
+	o.Gate = g
+
+	return o, nil
+}
+
+type BenchOptions struct {
+
+	// Target selection
+	ID uint32
+
+	// Input and repetition
+	InputPath    string
+	Repeat       int
+	Trials       int
+	WarmupTrials int
+
+	// Output selection
+	Format     string // printer name, e.g. "text", "json", "csv"
+	Out        io.Writer
+	OutputPath string // file path (if specified)
+	ToPrinter  func(io.Writer) (output.Printer, error)
+	Timelines  bool      // keep per-interval timelines in the results
+	ErrOut     io.Writer // warnings and diagnostics
+
+	Gate *Gate // pass/fail conditions on the results
+
+	PercentileKeys []string // normalized: ["p50","p90","p99","p99_9"]
+}
+
+func (o *BenchOptions) Run() error {
+	data, err := os.ReadFile(o.InputPath)
+	if err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+
+	// A capture file replays each of its packets; anything else is one input
+	inputs, capture := [][]byte{data}, pcap.IsCapture(data)
+	if capture {
+		c, err := pcap.Parse(data)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", o.InputPath, err)
+		}
+		if c.LinkType != pcap.LinkTypeEthernet {
+			return fmt.Errorf("%s: link type %d is not supported, only Ethernet captures can be replayed", o.InputPath, c.LinkType)
+		}
+		if len(c.Packets) == 0 {
+			return fmt.Errorf("%s: capture holds no packets", o.InputPath)
+		}
+		inputs = c.Packets
+	}
+
+	out, err := openOutput(o.OutputPath)
+	if err != nil {
+		return fmt.Errorf("setup output: %w", err)
+	}
+	defer closeOutput(out)
+	o.Out = out
+
+	// Fingerprint the host before measuring
+	env := environment.Capture()
+	printEnvironmentWarnings(o.ErrOut, env)
+
+	prog, err := program.Describe(o.ID)
+	if err != nil {
+		return fmt.Errorf("describe program %d: %w", o.ID, err)
+	}
+
+	if o.Format == "text" {
+		if capture {
+			fmt.Fprintf(o.Out, "Benchmarking eBPF program %d: %d packets x %d trials x %d runs...\n", o.ID, len(inputs), o.Trials, o.Repeat)
+		} else {
+			fmt.Fprintf(o.Out, "Benchmarking eBPF program %d: %d trials x %d runs...\n", o.ID, o.Trials, o.Repeat)
+		}
+	}
+
+	started := time.Now()
+	result, err := bench.Run(context.Background(), o.ID, bench.Options{
+		Inputs:       inputs,
+		InputName:    filepath.Base(o.InputPath),
+		Capture:      capture,
+		Repeat:       o.Repeat,
+		Trials:       o.Trials,
+		WarmupTrials: o.WarmupTrials,
+		Percentiles:  o.PercentileKeys,
+	})
+	if err != nil {
+		return fmt.Errorf("benchmark program %d: %w", o.ID, err)
+	}
+
+	if o.Format == "text" {
+		fmt.Fprintln(o.Out, "\n=== Final Statistics ===")
+	}
+
+	outputter, err := o.ToPrinter(o.Out)
+	if err != nil {
+		return err
+	}
+
+	meta := environment.RunMetadata(started, time.Now(), env)
+	meta.Programs = []bpfsv1.Program{*prog}
+	params := []bpfsv1.Parameter{result}
+	if !o.Timelines {
+		params = withoutTimelines(params...)
+	}
+	report := bpfsv1.NewReport(meta, params...)
+	if err := outputter.OutputReport(report, o.Out); err != nil {
+		return fmt.Errorf("output statistics: %w", err)
+	}
+	return o.Gate.Evaluate(report, o.ErrOut)
+}
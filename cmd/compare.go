@@ -0,0 +1,221 @@
+/*
+Copyright © 2026 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/compare"
+	"github.com/Tjaarda1/bpfstats/internal/environment"
+	"github.com/Tjaarda1/bpfstats/internal/output"
+	"github.com/Tjaarda1/bpfstats/internal/results"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdCompare returns the compare command
+func NewCmdCompare(parent string) *cobra.Command {
+	flags := NewCompareFlags()
+	cmd := &cobra.Command{
+		Use:                   "compare BASELINE CANDIDATE [CANDIDATE...]",
+		DisableFlagsInUseLine: true,
+		Short:                 compareShort,
+		Long:                  compareLong,
+		Example:               compareExample,
+		Args:                  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o, err := flags.ToOptions(parent, args)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true // flags are valid, errors are runtime failures
+			return o.Run()
+		},
+	}
+
+	flags.AddFlags(cmd)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(NewCmdCompare(rootCmd.Name()))
+}
+
+var (
+	compareLong = `
+		Compare result files against a baseline.
+
+		This command loads results written with -o json, ndjson or yaml and compares
+		every candidate file against the first (baseline) file, in the spirit of
+		benchstat. Parameters are matched by kind and order of appearance; latency,
+		throughput, bench and profile results are compared using their timelines.
+		Results written without --timeline are compared by their recorded mean and
+		percentiles only, without intervals or a significance test.
+
+		For the mean and each percentile it reports the delta with a bootstrap confidence
+		interval. A significance test on the whole distribution decides whether the change
+		is reported or shown as "~": Mann-Whitney U (default, no normality assumption),
+		Welch's t-test on the means, or a bootstrap test on the difference in means.`
+
+	compareExample = `
+		# Compare a candidate run against a baseline
+		bpfstat compare base.json new.json
+
+		# Use Welch's t-test, p99 and p99.9, and emit a Markdown table for a PR
+		bpfstat compare base.json new.json --test welch --percentiles tail -o markdown
+
+		# Compare several candidates against the same baseline
+		bpfstat compare base.json v2.json v3.json -o json`
+	compareShort = "Compare result files with significance tests and confidence intervals."
+)
+
+// CompareFlags are converted to CompareOptions
+type CompareFlags struct {
+
+	// Methodology
+	Test       string
+	Alpha      float64
+	Confidence float64
+	Resamples  int
+
+	// Output selection
+	PrintFlags *PrintFlags
+
+	// Stats config
+	Percentiles []string // e.g. ["50","90","99","99.9"] or ["p50","p99"]
+}
+
+// NewCompareFlags returns a default CompareFlags
+func NewCompareFlags() *CompareFlags {
+	return &CompareFlags{
+		Test:       compare.TestMannWhitney,
+		Alpha:      0.05,
+		Confidence: 0.95,
+		Resamples:  2000,
+		PrintFlags: NewPrintFlags(),
+	}
+}
+
+// AddFlags registers flags for a cli
+func (flags *CompareFlags) AddFlags(cmd *cobra.Command) {
+	// Methodology
+	cmd.Flags().StringVar(&flags.Test, "test", flags.Test,
+		"Significance test: "+strings.Join(compare.Tests(), ", ")+".")
+	cmd.Flags().Float64Var(&flags.Alpha, "alpha", flags.Alpha,
+		"Significance level below which a change is reported.")
+	cmd.Flags().Float64Var(&flags.Confidence, "confidence", flags.Confidence,
+		"Confidence level of the delta intervals (e.g. 0.95).")
+	cmd.Flags().IntVar(&flags.Resamples, "resamples", flags.Resamples,
+		"Number of bootstrap resamples behind the confidence intervals.")
+
+	// Stats config
+	cmd.Flags().StringSliceVar(&flags.Percentiles, "percentiles", flags.Percentiles,
+		"Percentile set to compare besides the mean: default, wide, or tail. Example: --percentiles tail")
+
+	// Output selection
+	flags.PrintFlags.AddFlags(cmd)
+}
+
+func (flags *CompareFlags) ToOptions(parent string, args []string) (*CompareOptions, error) {
+	// Validation
+	if !slices.Contains(compare.Tests(), flags.Test) {
+		return nil, fmt.Errorf("unknown --test %q, use one of %s", flags.Test, strings.Join(compare.Tests(), ", "))
+	}
+	if flags.Alpha <= 0 || flags.Alpha >= 1 {
+		return nil, fmt.Errorf("--alpha must be between 0 and 1")
+	}
+	if flags.Confidence <= 0 || flags.Confidence >= 1 {
+		return nil, fmt.Errorf("--confidence must be between 0 and 1")
+	}
+	if flags.Resamples <= 0 {
+		return nil, fmt.Errorf("--resamples must be positive")
+	}
+
+	o := &CompareOptions{
+		Baseline:   args[0],
+		Candidates: args[1:],
+		Compare: compare.Options{
+			Test:        flags.Test,
+			Alpha:       flags.Alpha,
+			Confidence:  flags.Confidence,
+			Resamples:   flags.Resamples,
+			Percentiles: normalizePercentiles(flags.Percentiles),
+		},
+	}
+
+	// Determine output format
+	if err := flags.PrintFlags.Validate(); err != nil {
+		return nil, err
+	}
+	o.ToPrinter = flags.PrintFlags.ToPrinter
+	o.OutputPath = flags.PrintFlags.OutputFile
+
+	return o, nil
+}
+
+type CompareOptions struct {
+
+	// Inputs
+	Baseline   string   // result file every candidate is compared against
+	Candidates []string // result files to compare
+
+	Compare compare.Options
+
+	// Output selection
+	Out        io.Writer
+	OutputPath string // file path (if specified)
+	ToPrinter  func(io.Writer) (output.Printer, error)
+}
+
+func (o *CompareOptions) Run() error {
+	started := time.Now()
+
+	load := func(path string) (compare.Source, error) {
+		params, err := results.LoadFile(path)
+		if err != nil {
+			return compare.Source{}, fmt.Errorf("load %s: %w", path, err)
+		}
+		return compare.Source{Name: path, Params: params}, nil
+	}
+
+	baseline, err := load(o.Baseline)
+	if err != nil {
+		return err
+	}
+	var params []bpfsv1.Parameter
+	for _, path := range o.Candidates {
+		candidate, err := load(path)
+		if err != nil {
+			return err
+		}
+		cmp, err := compare.Compare(baseline, candidate, o.Compare)
+		if err != nil {
+			return err
+		}
+		params = append(params, cmp)
+	}
+
+	out, err := openOutput(o.OutputPath)
+	if err != nil {
+		return fmt.Errorf("setup output: %w", err)
+	}
+	defer closeOutput(out)
+	o.Out = out
+
+	outputter, err := o.ToPrinter(o.Out)
+	if err != nil {
+		return err
+	}
+
+	meta := environment.RunMetadata(started, time.Now(), nil)
+	if err := outputter.OutputReport(bpfsv1.NewReport(meta, params...), o.Out); err != nil {
+		return fmt.Errorf("output comparison: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/gate"
+	"github.com/Tjaarda1/bpfstats/internal/results"
+	"github.com/spf13/cobra"
+)
+
+// GateFlags are the pass/fail flags shared by measuring subcommands. When any
+// condition is violated the command exits with status 2 after printing its
+// results, so CI pipelines can gate on it.
+type GateFlags struct {
+	Assertions    []string // --assert, e.g. "p99 < 800ns"
+	Baseline      string   // --baseline result file
+	MaxRegression string   // --max-regression, e.g. "5%" or "0.05"
+	MaxErrorRate  string   // --max-error-rate, e.g. "10%"; empty, "0" or "none" => unchecked
+}
+
+// NewGateFlags returns a default GateFlags
+func NewGateFlags() *GateFlags {
+	return &GateFlags{
+		MaxRegression: "5%",
+	}
+}
+
+// AddFlags registers the gate flags for a cli
+func (f *GateFlags) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&f.Assertions, "assert", f.Assertions,
+		"Condition the results must meet, e.g. 'p99 < 800ns' or 'cpu.mean < 0.05'. Repeatable; exits with status 2 on violation.")
+	cmd.Flags().StringVar(&f.Baseline, "baseline", f.Baseline,
+		"Result file (json or yaml) to check for regressions in latency, bench and profile costs.")
+	cmd.Flags().StringVar(&f.MaxRegression, "max-regression", f.MaxRegression,
+		"Largest tolerated increase over --baseline, e.g. 5% or 0.05.")
+}
+
+// defaultMaxErrorRate is the --max-error-rate of commands registering it.
+const defaultMaxErrorRate = "10%"
+
+// AddIntegrityFlags registers the gate flags on failed polls, for commands
+// whose reports account for them, and enables their default.
+func (f *GateFlags) AddIntegrityFlags(cmd *cobra.Command) {
+	if f.MaxErrorRate == "" {
+		f.MaxErrorRate = defaultMaxErrorRate
+	}
+	cmd.Flags().StringVar(&f.MaxErrorRate, "max-error-rate", f.MaxErrorRate,
+		"Largest tolerated fraction of failed polls, e.g. 10% or 0.1; exits with status 2 when exceeded. 0 or none disables the check.")
+}
+
+// ToGate parses the conditions and loads the baseline, so that mistakes fail
+// before a measurement rather than after it.
+func (f *GateFlags) ToGate() (*Gate, error) {
+	g := &Gate{}
+	for _, s := range f.Assertions {
+		a, err := gate.ParseAssertion(s)
+		if err != nil {
+			return nil, err
+		}
+		g.assertions = append(g.assertions, a)
+	}
+
+	if f.Baseline != "" {
+		params, err := results.LoadFile(f.Baseline)
+		if err != nil {
+			return nil, fmt.Errorf("load --baseline: %w", err)
+		}
+		max, err := parseFraction(f.MaxRegression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --max-regression: %w", err)
+		}
+		g.baseline = params
+		g.maxRegression = max
+	}
+
+	if f.MaxErrorRate != "" && f.MaxErrorRate != "none" {
+		rate, err := parseFraction(f.MaxErrorRate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --max-error-rate: %w", err)
+		}
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid --max-error-rate %q: must be within 0%% and 100%%", f.MaxErrorRate)
+		}
+		if rate > 0 {
+			g.maxErrorRate = &rate
+		}
+	}
+	return g, nil
+}
+
+// parseFraction accepts "5%" or "0.05".
+func parseFraction(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		v, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+		return v / 100, err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Gate holds the parsed conditions of GateFlags.
+type Gate struct {
+	assertions    []gate.Assertion
+	baseline      []bpfsv1.Parameter
+	maxRegression float64
+	maxErrorRate  *float64
+}
+
+// Evaluate checks every condition against the final report, prints one line
+// per result to w and returns an error wrapping gate.ErrFailed if any was
+// violated. Without conditions it does nothing.
+func (g *Gate) Evaluate(report *bpfsv1.Report, w io.Writer) error {
+	if g == nil || (len(g.assertions) == 0 && g.baseline == nil && g.maxErrorRate == nil) {
+		return nil
+	}
+
+	params := report.Params()
+	var res []gate.Result
+	for _, a := range g.assertions {
+		res = append(res, a.Evaluate(params))
+	}
+	if g.baseline != nil {
+		res = append(res, gate.Regressions(g.baseline, params, g.maxRegression)...)
+	}
+	if g.maxErrorRate != nil {
+		res = append(res, gate.ErrorRate(report.Metadata.Integrity, *g.maxErrorRate))
+	}
+
+	for _, r := range res {
+		fmt.Fprintln(w, r)
+	}
+	return gate.Check(res)
+}
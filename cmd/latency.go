@@ -8,12 +8,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
 	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/Tjaarda1/bpfstats/internal/environment"
 	"github.com/Tjaarda1/bpfstats/internal/output"
+	"github.com/Tjaarda1/bpfstats/internal/probe"
+	"github.com/Tjaarda1/bpfstats/internal/program"
+	"github.com/Tjaarda1/bpfstats/internal/trials"
 	"github.com/spf13/cobra"
 )
 
@@ -31,6 +36,7 @@ func NewCmdLatency(parent string) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			cmd.SilenceUsage = true // flags are valid, errors are runtime failures
 			return o.Run()
 		},
 	}
@@ -60,8 +66,9 @@ var (
 
 		This command runs a time-bounded measurement for the selected program ID and prints
 		distribution-aware latency statistics, such as mean, standard deviation, and tail
-		percentiles (p50/p90/p99/p99.9). The default output is human-readable text; use --json
-		for machine-readable output.
+		percentiles (p50/p90/p99/p99.9). The default output is human-readable text; use -o to
+		select a machine-readable format (json, csv, ndjson, ...) or -o template=... /
+		-o jsonpath=... to extract fields.
 
 		The reported "latency" is the per-invocation execution duration of the eBPF program
 		(i.e., time spent executing BPF instructions and helper calls for each trigger), not
@@ -73,14 +80,46 @@ var (
 		bpfstat latency --id 42 --duration 60s
 
 		# Same measurement, output as JSON
-		bpfstat latency --id 42 --duration 60s --json
+		bpfstat latency --id 42 --duration 60s -o json
 
 		# Write JSON output to a file
-		bpfstat latency --id 42 --duration 60s --json -o latency_42.json
+		bpfstat latency --id 42 --duration 60s -o json --output-file latency_42.json
+
+		# Write CSV output for spreadsheets and data frames
+		bpfstat latency --id 42 --duration 60s -o csv --output-file latency_42.csv
+
+		# Extract the p99 latency in nanoseconds for scripting
+		bpfstat latency --id 42 --duration 60s \
+			-o jsonpath='{.parameters[?(@.kind=="latency")].percentiles_ns.p99}'
+
+		# Format selected fields with a Go template
+		bpfstat latency --id 42 --duration 60s \
+			-o template='{{range .parameters}}{{.kind}} {{.mean_ns}}{{"\n"}}{{end}}'
 
 		# Use a shorter interval for quick iteration
 		bpfstat latency --id 42 --duration 10s
 
+		# Render the latency histogram, CDF and timeline in the terminal
+		bpfstat latency --id 42 --duration 60s --plot
+
+		# Break down invocations and runtime per CPU (attaches fentry/fexit probes)
+		bpfstat latency --id 42 --duration 60s --per-cpu
+
+		# Break down latency per return code, e.g. XDP_PASS vs XDP_DROP
+		bpfstat latency --id 42 --duration 60s --by-retval
+
+		# Report the last 10 seconds and the last minute besides the whole run
+		bpfstat latency --id 42 --duration 10m --windows 10s,1m
+
+		# Repeat the measurement 5 times with pauses and report between-trial variance
+		bpfstat latency --id 42 --duration 30s --trials 5 --cooldown 5s
+
+		# Fail (exit status 2) if p99 latency or CPU usage exceed a budget
+		bpfstat latency --id 42 --duration 60s --assert 'p99 < 800ns' --assert 'cpu.mean < 0.05'
+
+		# Fail (exit status 2) if more than 1% of the polls of the program failed
+		bpfstat latency --id 42 --duration 60s --max-error-rate 1%
+
 		# Measure with custom percentiles (if supported by your flags)
 		bpfstat latency --id 42 --duration 60s --percentiles 50,90,99,99.9`
 	latencyShort = "Measure and report latency statistics for a specific eBPF program."
@@ -96,19 +135,33 @@ type LatencyFlags struct {
 	Duration time.Duration
 	Warmup   time.Duration
 
+	// Repetition
+	Trials   int
+	Cooldown time.Duration
+
 	// Output selection
-	JSON   bool
-	Pretty bool
-	Output string // -o / --output file path (empty => stdout)
+	PrintFlags *PrintFlags
+	GateFlags  *GateFlags
 
 	// Stats config
+	Metrics     []string // collectors to run, e.g. ["latency","cpu"]
 	Percentiles []string // e.g. ["50","90","99","99.9"] or ["p50","p99"]
+	Windows     []string // recent windows, e.g. ["10s","1m"]
+	WindowMode  string   // "sliding" or "tumbling"
+	PerCPU      bool     // attribute cost per CPU via fentry/fexit
+	ByRetval    bool     // break down latency per return code via fexit
 
 }
 
 // NewLatencyFlags returns a default LatencyFlags
 func NewLatencyFlags() *LatencyFlags {
-	return &LatencyFlags{}
+	return &LatencyFlags{
+		Metrics:    defaultMetrics,
+		WindowMode: collector.WindowSliding,
+		Trials:     1,
+		PrintFlags: NewPrintFlags(),
+		GateFlags:  NewGateFlags(),
+	}
 }
 
 // AddFlags registers flags for a cli
@@ -123,17 +176,30 @@ func (flags *LatencyFlags) AddFlags(cmd *cobra.Command) {
 	cmd.Flags().DurationVar(&flags.Warmup, "warmup", flags.Warmup,
 		"Optional warmup period to discard before measurement (e.g. 5s).")
 
+	// Repetition
+	cmd.Flags().IntVar(&flags.Trials, "trials", flags.Trials,
+		"Number of times to repeat the measurement; with more than one, between-trial statistics are reported.")
+	cmd.Flags().DurationVar(&flags.Cooldown, "cooldown", flags.Cooldown,
+		"Pause between trials (e.g. 5s).")
+
 	// Stats config
+	cmd.Flags().StringSliceVar(&flags.Metrics, "metrics", flags.Metrics,
+		"Metrics to collect, sampled together on one ticker: "+strings.Join(collector.Names(), ", ")+".")
 	cmd.Flags().StringSliceVar(&flags.Percentiles, "percentiles", flags.Percentiles,
 		"Percentile set to compute: default, wide, or tail. Example: --percentiles tail")
+	cmd.Flags().StringSliceVar(&flags.Windows, "windows", flags.Windows,
+		"Recent windows to report besides the whole run for latency and cpu, e.g. --windows 10s,1m")
+	cmd.Flags().StringVar(&flags.WindowMode, "window-mode", flags.WindowMode,
+		"How --windows are computed: sliding (the trailing span) or tumbling (the last complete span).")
+	cmd.Flags().BoolVar(&flags.PerCPU, "per-cpu", flags.PerCPU,
+		"If true, attach fentry/fexit probes to the program and break down invocations and runtime per CPU. Requires BTF for the program.")
+	cmd.Flags().BoolVar(&flags.ByRetval, "by-retval", flags.ByRetval,
+		"If true, attach fentry/fexit probes to the program and break down latency per return code (e.g. XDP_PASS vs XDP_DROP). Requires BTF for the program.")
 
 	// Output selection
-	cmd.Flags().BoolVar(&flags.JSON, "json", flags.JSON,
-		"If true, output results as JSON")
-	cmd.Flags().BoolVar(&flags.Pretty, "pretty", flags.Pretty,
-		"If true, pretty-print JSON output (only applies with --json).")
-	cmd.Flags().StringVarP(&flags.Output, "output", "o", flags.Output,
-		"Write output to a file instead of stdout.")
+	flags.PrintFlags.AddFlags(cmd)
+	flags.GateFlags.AddFlags(cmd)
+	flags.GateFlags.AddIntegrityFlags(cmd)
 
 }
 func (flags *LatencyFlags) ToOptions(parent string, args []string) (*MonitorOptions, error) {
@@ -144,10 +210,28 @@ func (flags *LatencyFlags) ToOptions(parent string, args []string) (*MonitorOpti
 	if flags.Duration == 0 {
 		return nil, fmt.Errorf("--duration is required")
 	}
+	if flags.Trials <= 0 {
+		return nil, fmt.Errorf("--trials must be positive")
+	}
+	if flags.Cooldown < 0 {
+		return nil, fmt.Errorf("--cooldown must not be negative")
+	}
+	metrics, err := validateMetrics(flags.Metrics)
+	if err != nil {
+		return nil, err
+	}
+	if flags.PerCPU && !slices.Contains(metrics, "cpu") {
+		return nil, fmt.Errorf("--per-cpu requires the cpu metric")
+	}
+	if flags.ByRetval && !slices.Contains(metrics, "latency") {
+		return nil, fmt.Errorf("--by-retval requires the latency metric")
+	}
 
 	o := &MonitorOptions{
 		ID:       flags.ID,
 		Duration: flags.Duration,
+		Metrics:  metrics,
+		ErrOut:   os.Stderr,
 	}
 
 	// Handle optional warmup
@@ -156,26 +240,71 @@ func (flags *LatencyFlags) ToOptions(parent string, args []string) (*MonitorOpti
 	}
 
 	// Determine output format
-	if flags.JSON {
-		o.Format = OutputJSON
-	} else {
-		o.Format = OutputText
+	if err := flags.PrintFlags.Validate(); err != nil {
+		return nil, err
 	}
-	o.Pretty = flags.Pretty
+	o.Format = flags.PrintFlags.Format()
+	o.ToPrinter = flags.PrintFlags.ToPrinter
+	o.Timelines = flags.PrintFlags.Timelines()
 
 	// Handle output destination
-	if flags.Output != "" {
+	if flags.PrintFlags.OutputFile != "" {
 		// Will be opened in Run()
-		o.OutputPath = flags.Output
+		o.OutputPath = flags.PrintFlags.OutputFile
 	}
 	// Otherwise defaults to stdout in Run()
 
 	// Parse percentiles (if specified)
 	o.PercentileKeys = normalizePercentiles(flags.Percentiles)
 
+	windows, err := collector.ParseWindows(flags.Windows, flags.WindowMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --windows: %w", err)
+	}
+	o.Windows = windows
+
+	o.PerCPU = flags.PerCPU
+	o.ByRetval = flags.ByRetval
+
+	// Repetition
+	o.Trials = flags.Trials
+	o.Cooldown = flags.Cooldown
+
+	// Pass/fail conditions
+	g, err := flags.GateFlags.ToGate()
+	if err != nil {
+		return nil, err
+	}
+	o.Gate = g
+
 	return o, nil
 }
 
+// trialConfidence is the level of the between-trial confidence intervals.
+const trialConfidence = 0.95
+
+// defaultMetrics are the collectors run when --metrics is not given.
+var defaultMetrics = []string{"latency", "cpu", "health", "throughput"}
+
+// validateMetrics checks --metrics against the collector registry.
+func validateMetrics(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("--metrics must name at least one metric")
+	}
+	metrics := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if _, err := collector.Lookup(name); err != nil {
+			return nil, err
+		}
+		if slices.Contains(metrics, name) {
+			return nil, fmt.Errorf("metric %q given twice", name)
+		}
+		metrics = append(metrics, name)
+	}
+	return metrics, nil
+}
+
 // normalizePercentiles converts user input to normalized keys
 // e.g., ["50", "99.9"] -> ["p50", "p99_9"]
 // e.g., ["default"] -> ["p50", "p90", "p99"]
@@ -215,40 +344,138 @@ func normalizePercentiles(input []string) []string {
 }
 
 func (o *MonitorOptions) Run() error {
-	ctx := context.Background()
-
 	// Setup output writer
 	if err := o.setupOutput(); err != nil {
 		return fmt.Errorf("setup output: %w", err)
 	}
 	defer o.closeOutput()
 
-	// Create collector
+	// Fingerprint the host before measuring
+	o.env = environment.Capture()
+	printEnvironmentWarnings(o.ErrOut, o.env)
+
+	// Identify the program up front: its ID alone is meaningless after the run
+	prog, err := program.Describe(o.ID)
+	if err != nil {
+		return fmt.Errorf("describe program %d: %w", o.ID, err)
+	}
+	o.program = prog
+
+	// Optional per-CPU and per-return-code attribution share one probe pair,
+	// kept attached across trials
+	if o.PerCPU || o.ByRetval {
+		p, err := probe.Attach(o.ID, probe.Options{ReturnCodes: o.ByRetval})
+		if err != nil {
+			return fmt.Errorf("attach fentry/fexit probes: %w", err)
+		}
+		defer p.Close()
+		o.probe = p
+	}
+
+	o.started = time.Now()
+	o.integrity = collector.NewIntegrity()
+	perTrial := make([][]bpfsv1.Parameter, 0, o.Trials)
+	for trial := 1; trial <= o.Trials; trial++ {
+		if trial > 1 && o.Cooldown > 0 {
+			if o.Format == "text" {
+				fmt.Fprintf(o.Out, "\n\nCooling down for %v...", o.Cooldown)
+			}
+			time.Sleep(o.Cooldown)
+		}
+		if o.Format == "text" && o.Trials > 1 {
+			fmt.Fprintf(o.Out, "\n\n--- Trial %d/%d ---\n", trial, o.Trials)
+		}
+
+		params, err := o.runTrial()
+		if err != nil {
+			if o.Trials > 1 {
+				return fmt.Errorf("trial %d: %w", trial, err)
+			}
+			return err
+		}
+		perTrial = append(perTrial, params)
+	}
+
+	// Output final statistics
+	return o.outputFinalStats(perTrial)
+}
+
+// runTrial measures once for Duration and returns the final snapshots of
+// the collectors.
+func (o *MonitorOptions) runTrial() ([]bpfsv1.Parameter, error) {
+	// Create the selected collectors, fed by one poll of the program per interval
 	interval := 100 * time.Millisecond // sampling interval
-	o.latCollector = collector.NewLatencyCollector(o.ID, interval, o.Warmup)
-	o.cpuCollector = collector.NewCPUCollector(o.ID, interval, o.Warmup)
+	opts := collector.Options{
+		ID:          o.ID,
+		Interval:    interval,
+		Warmup:      o.Warmup,
+		Percentiles: o.PercentileKeys,
+		Windows:     o.Windows,
+	}
+	if o.PerCPU {
+		opts.PerCPUProbe = o.probe
+	}
+	if o.ByRetval {
+		opts.ReturnCodeProbe = o.probe
+	}
+	collectors, err := o.trialCollectors(opts)
+	if err != nil {
+		return nil, err
+	}
+	o.runner, err = collector.NewRunner(collector.NewKernelSource(o.ID), interval, o.Warmup, collectors...)
+	if err != nil {
+		return nil, err
+	}
+	o.runner.SetIntegrity(o.integrity)
 
-	// Start collector in background
-	ctx, cancel := context.WithTimeout(ctx, o.Duration)
+	// Start collectors in background
+	ctx, cancel := context.WithTimeout(context.Background(), o.Duration)
 	defer cancel()
 
-	errCh := make(chan error, 2)
-	go func() { errCh <- o.latCollector.Start(ctx) }()
-	go func() { errCh <- o.cpuCollector.Start(ctx) }()
+	errCh := make(chan error, 1)
+	go func() { errCh <- o.runner.Start(ctx) }()
 	// Live updates during measurement
-	if o.Format == OutputText {
-		if err := o.runWithLiveUpdates(ctx); err != nil {
-			return err
+	if o.Format == "text" {
+		if err := o.runWithLiveUpdates(ctx, errCh); err != nil {
+			return nil, err
 		}
 	} else {
-		// JSON mode: just wait for completion
+		// Machine-readable formats: just wait for completion
 		if err := o.waitForCompletion(ctx, errCh); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	// Output final statistics
-	return o.outputFinalStats()
+	// The trial ends at its deadline, not once polling has stopped
+	for _, c := range collectors {
+		if w, ok := c.(collector.Windowed); ok {
+			w.Finalize()
+		}
+	}
+	return o.finalSnapshots()
+}
+
+// trialCollectors returns the collectors of the next trial. Collectors
+// implementing collector.Windowed (latency, cpu) are reset and reused, so
+// they keep their probes and windows; the others are created anew.
+func (o *MonitorOptions) trialCollectors(opts collector.Options) ([]collector.Collector, error) {
+	if o.collectors == nil {
+		var err error
+		o.collectors, err = collector.New(o.Metrics, opts)
+		return o.collectors, err
+	}
+	for i, c := range o.collectors {
+		if w, ok := c.(collector.Windowed); ok {
+			w.Reset(0, o.Warmup)
+			continue
+		}
+		fresh, err := collector.New(o.Metrics[i:i+1], opts)
+		if err != nil {
+			return nil, err
+		}
+		o.collectors[i] = fresh[0]
+	}
+	return o.collectors, nil
 }
 
 type MonitorOptions struct {
@@ -260,46 +487,50 @@ type MonitorOptions struct {
 	Duration time.Duration
 	Warmup   *time.Duration // nil => no warmup/discard
 
+	// Repetition
+	Trials   int           // measurements to run, each of Duration
+	Cooldown time.Duration // pause between trials
+
 	// Output selection
-	Format     OutputFormat
-	Pretty     bool
+	Format     string // printer name, e.g. "text", "json", "csv"
 	Out        io.Writer
 	OutputPath string // file path (if specified)
+	ToPrinter  func(io.Writer) (output.Printer, error)
+	Timelines  bool      // keep per-interval timelines in the results
+	ErrOut     io.Writer // warnings and diagnostics
+
+	Gate *Gate // pass/fail conditions on the results
 
-	PercentileKeys []string // normalized: ["p50","p90","p99","p99_9"]
+	Metrics        []string               // registered collectors to run, e.g. ["latency","cpu"]
+	PercentileKeys []string               // normalized: ["p50","p90","p99","p99_9"]
+	Windows        []collector.WindowSpec // recent windows reported besides the whole run
+	PerCPU         bool                   // attach fentry/fexit probes for a per-CPU breakdown
+	ByRetval       bool                   // attach fentry/fexit probes for a per-return-code breakdown
 
 	// Internal (set during Run)
-	latCollector *collector.LatencyCollector
-	cpuCollector *collector.CpuCollector
+	started    time.Time
+	env        *bpfsv1.Environment
+	program    *bpfsv1.Program
+	runner     *collector.Runner
+	collectors []collector.Collector // of the latest trial
+	probe      *probe.Probe
+
+	integrity *collector.Integrity // failed polls and idle intervals of every trial
 }
 
-type OutputFormat string
-
-const (
-	OutputText OutputFormat = "text"
-	OutputJSON OutputFormat = "json"
-)
-
 func (o *MonitorOptions) setupOutput() error {
-	if o.OutputPath != "" {
-		f, err := os.Create(o.OutputPath)
-		if err != nil {
-			return fmt.Errorf("create output file: %w", err)
-		}
-		o.Out = f
-	} else {
-		o.Out = os.Stdout
+	out, err := openOutput(o.OutputPath)
+	if err != nil {
+		return err
 	}
-
+	o.Out = out
 	return nil
 }
 func (o *MonitorOptions) closeOutput() {
-	if f, ok := o.Out.(*os.File); ok && f != os.Stdout {
-		f.Close()
-	}
+	closeOutput(o.Out)
 }
 
-func (o *MonitorOptions) runWithLiveUpdates(ctx context.Context) error {
+func (o *MonitorOptions) runWithLiveUpdates(ctx context.Context, errCh chan error) error {
 	ticker := time.NewTicker(1 * time.Second) // update every second
 	defer ticker.Stop()
 
@@ -309,13 +540,30 @@ func (o *MonitorOptions) runWithLiveUpdates(ctx context.Context) error {
 	}
 	fmt.Fprintf(o.Out, "Duration: %v\n\n", o.Duration)
 
+	// The live line shows latency, when it is collected
+	var latC *collector.LatencyCollector
+	for _, c := range o.runner.Collectors() {
+		if l, ok := c.(*collector.LatencyCollector); ok {
+			latC = l
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
+		case err := <-errCh:
+			if err != nil && err != context.DeadlineExceeded {
+				return err
+			}
+			return nil
 		case <-ticker.C:
+			if latC == nil {
+				continue
+			}
+
 			// Get current snapshot
-			snapshot, err := o.latCollector.Snapshot()
+			snapshot, err := latC.Snapshot()
 
 			if err != nil {
 				// No samples yet, skip
@@ -334,6 +582,19 @@ func (o *MonitorOptions) runWithLiveUpdates(ctx context.Context) error {
 				time.Duration(*latency.Min),
 				time.Duration(*latency.Max),
 			)
+
+			// Most recent behavior, when windows are reported
+			if len(o.Windows) > 0 {
+				name := o.Windows[0].Name()
+				if ws, ok := latency.Windows[name]; ok && ws.Samples > 0 {
+					fmt.Fprintf(o.Out, " | %s: %v", strings.ReplaceAll(name, "_", " "), time.Duration(ws.Mean))
+				}
+			}
+
+			// Failed polls shrink the sample, say so while it happens
+			if in := o.runner.Integrity(); in.FailedPolls > 0 {
+				fmt.Fprintf(o.Out, " | Failed polls: %d/%d", in.FailedPolls, in.Polls)
+			}
 		}
 	}
 }
@@ -350,46 +611,67 @@ func (o *MonitorOptions) waitForCompletion(ctx context.Context, errCh chan error
 	}
 }
 
-func (o *MonitorOptions) outputFinalStats() error {
-	// Stop collector and get final snapshot
-	if err := o.latCollector.Stop(); err != nil {
-		return fmt.Errorf("stop collector: %w", err)
-	}
-	if err := o.cpuCollector.Stop(); err != nil {
-		return fmt.Errorf("stop collector: %w", err)
+// finalSnapshots stops the collectors and returns their final statistics.
+func (o *MonitorOptions) finalSnapshots() ([]bpfsv1.Parameter, error) {
+	if err := o.runner.Stop(); err != nil {
+		return nil, fmt.Errorf("stop collector: %w", err)
 	}
 
-	latencySnap, err := o.latCollector.Snapshot()
+	params, err := o.runner.Snapshots()
 	if err != nil {
-		return fmt.Errorf("get final snapshot: %w", err)
+		// Without samples, the failed polls usually tell why
+		if in := o.runner.Integrity(); len(in.Errors) > 0 {
+			last := in.Errors[len(in.Errors)-1]
+			return nil, fmt.Errorf("get final snapshot: %w (%d of %d polls failed, e.g. %s)",
+				err, in.FailedPolls, in.Polls, last.Message)
+		}
+		return nil, fmt.Errorf("get final snapshot: %w", err)
 	}
-	cpuSnap, err := o.cpuCollector.Snapshot()
-	if err != nil {
-		return fmt.Errorf("get final snapshot: %w", err)
+	return params, nil
+}
+
+func (o *MonitorOptions) outputFinalStats(perTrial [][]bpfsv1.Parameter) error {
+	// With repeated trials, every trial's parameters are followed by the
+	// between-trial summary
+	params := perTrial[0]
+	if len(perTrial) > 1 {
+		params = nil
+		for _, p := range perTrial {
+			params = append(params, p...)
+		}
+		summary, err := trials.Summarize(perTrial, trialConfidence, o.PercentileKeys)
+		if err != nil {
+			return fmt.Errorf("summarize trials: %w", err)
+		}
+		summary.ID = o.ID
+		summary.Duration = o.Duration
+		summary.Warmup = o.Warmup
+		summary.Cooldown = o.Cooldown
+		params = append(params, summary)
 	}
 
 	// For text mode, add newline after live updates
-	if o.Format == OutputText {
+	if o.Format == "text" {
 		fmt.Fprintln(o.Out, "\n\n=== Final Statistics ===")
 	}
 
-	var outputter output.ParameterOutput
-	switch o.Format {
-	case OutputText:
-		outputter = &output.TextOutput{}
-	case OutputJSON:
-		outputter = &output.JsonOutput{}
-	default:
-		return fmt.Errorf("unknown output format: %v", o.Format)
+	outputter, err := o.ToPrinter(o.Out)
+	if err != nil {
+		return err
 	}
 
-	// Use the output interface to format and write
-	if err := outputter.OutputParam(latencySnap, o.Out); err != nil {
-		return fmt.Errorf("output statistics: %w", err)
+	// Emit a single report holding every parameter of the run
+	meta := environment.RunMetadata(o.started, time.Now(), o.env)
+	meta.Programs = []bpfsv1.Program{*o.program}
+	meta.Integrity = o.integrity.Summary()
+	printIntegrityWarnings(o.ErrOut, meta.Integrity)
+	if !o.Timelines {
+		params = withoutTimelines(params...)
 	}
-	if err := outputter.OutputParam(cpuSnap, o.Out); err != nil {
+	report := bpfsv1.NewReport(meta, params...)
+	if err := outputter.OutputReport(report, o.Out); err != nil {
 		return fmt.Errorf("output statistics: %w", err)
 	}
 
-	return nil
+	return o.Gate.Evaluate(report, o.ErrOut)
 }
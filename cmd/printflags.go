@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Tjaarda1/bpfstats/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// PrintFlags are the output selection flags shared by every subcommand.
+// Formats are resolved through the output printer registry, so adding a new
+// format does not require touching command code.
+type PrintFlags struct {
+	OutputFormat     string // -o / --output, e.g. "json" or "jsonpath={.id}"
+	OutputFile       string // --output-file path (empty => stdout)
+	AllowMissingKeys bool   // ignore missing fields in template/jsonpath output
+	NoHeaders        bool   // omit headers in tabular output
+	Plot             bool   // render histogram/CDF/timeline in text output
+	Timeline         bool   // keep per-interval timelines in the results
+}
+
+// NewPrintFlags returns a default PrintFlags
+func NewPrintFlags() *PrintFlags {
+	return &PrintFlags{
+		OutputFormat:     "text",
+		AllowMissingKeys: true,
+	}
+}
+
+// AddFlags registers the output flags for a cli
+func (f *PrintFlags) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.OutputFormat, "output", "o", f.OutputFormat,
+		fmt.Sprintf("Output format. One of: %s. Formats taking an argument are given as name=arg, e.g. jsonpath={.id}.",
+			strings.Join(output.Formats(), "|")))
+	cmd.Flags().StringVar(&f.OutputFile, "output-file", f.OutputFile,
+		"Write output to a file instead of stdout.")
+	cmd.Flags().BoolVar(&f.AllowMissingKeys, "allow-missing-template-keys", f.AllowMissingKeys,
+		"If true, ignore errors in templates when a field or map key is missing (template and jsonpath output only).")
+	cmd.Flags().BoolVar(&f.NoHeaders, "no-headers", f.NoHeaders,
+		"If true, omit headers in tabular output (csv only).")
+	cmd.Flags().BoolVar(&f.Plot, "plot", f.Plot,
+		"If true, render a latency histogram, CDF and timeline sparkline (text output only).")
+	cmd.Flags().BoolVar(&f.Timeline, "timeline", f.Timeline,
+		"If true, include the per-interval samples in the results, e.g. for compare. Implied by --plot.")
+}
+
+// Timelines reports whether results keep their per-interval timelines.
+func (f *PrintFlags) Timelines() bool {
+	return f.Timeline || f.Plot
+}
+
+// Format returns the printer name without its argument, e.g. "jsonpath".
+func (f *PrintFlags) Format() string {
+	name, _, _ := strings.Cut(f.OutputFormat, "=")
+	if name == "" {
+		return "text"
+	}
+	return name
+}
+
+// IsText reports whether the human-readable text printer is selected.
+func (f *PrintFlags) IsText() bool {
+	return f.Format() == "text"
+}
+
+// Validate checks that the selected format exists and its argument parses,
+// so that bad templates fail before a measurement rather than after it.
+func (f *PrintFlags) Validate() error {
+	_, err := f.ToPrinter(nil)
+	return err
+}
+
+// ToPrinter builds the selected printer. out is used to size terminal plots
+// and may be nil.
+func (f *PrintFlags) ToPrinter(out io.Writer) (output.Printer, error) {
+	opts := output.OutputOptions{
+		NoHeaders:        f.NoHeaders,
+		AllowMissingKeys: f.AllowMissingKeys,
+		Plot:             f.Plot,
+	}
+	if out != nil && f.IsText() {
+		opts.Width, opts.Unicode = output.TerminalWidth(out)
+	}
+	return output.NewPrinter(f.OutputFormat, opts)
+}
+
+// OpenOutput opens the --output-file destination, or returns stdout when
+// none was given. close releases the file and does nothing for stdout.
+func (f *PrintFlags) OpenOutput() (w io.Writer, close func(), err error) {
+	w, err = openOutput(f.OutputFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return w, func() { closeOutput(w) }, nil
+}
+
+// openOutput opens the --output-file destination, or stdout when path is empty.
+func openOutput(path string) (io.Writer, error) {
+	if path == "" {
+		return os.Stdout, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create output file: %w", err)
+	}
+	return f, nil
+}
+
+// closeOutput closes a writer returned by openOutput.
+func closeOutput(w io.Writer) {
+	if f, ok := w.(*os.File); ok && f != os.Stdout {
+		f.Close()
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright © 2026 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/Tjaarda1/bpfstats/internal/environment"
+	"github.com/Tjaarda1/bpfstats/internal/output"
+	"github.com/Tjaarda1/bpfstats/internal/probe"
+	"github.com/Tjaarda1/bpfstats/internal/program"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdProfile returns the profile command
+func NewCmdProfile(parent string) *cobra.Command {
+	flags := NewProfileFlags()
+	cmd := &cobra.Command{
+		Use:                   "profile",
+		DisableFlagsInUseLine: true,
+		Short:                 profileShort,
+		Long:                  profileLong,
+		Example:               profileExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o, err := flags.ToOptions(parent, args)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true // flags are valid, errors are runtime failures
+			return o.Run()
+		},
+	}
+
+	flags.AddFlags(cmd)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(NewCmdProfile(rootCmd.Name()))
+}
+
+var (
+	profileLong = `
+		Profile an eBPF program with hardware performance counters.
+
+		This command opens CPU performance counters on every online CPU and attaches
+		fentry/fexit probes to the selected program, which read the counters around each
+		invocation (like bpftool prog profile). It reports cycles, instructions, LLC misses
+		and branch misses per run, and instructions per cycle (IPC), with the same
+		statistics as latency: each metric is computed per sampling interval and summarized
+		by mean, standard deviation and percentiles.
+
+		The program must have been loaded with BTF. Hardware counters are often not exposed
+		to virtual machines; when the PMU is unavailable the command fails with a clear
+		error, and counters the PMU does not provide are listed as unavailable.`
+
+	profileExample = `
+		# Profile eBPF program id 42 for 30 seconds
+		bpfstat profile --id 42 --duration 30s
+
+		# Only count cycles and instructions (IPC)
+		bpfstat profile --id 42 --duration 30s --events cycles,instructions
+
+		# Extract the mean IPC for scripting
+		bpfstat profile --id 42 --duration 30s \
+			-o jsonpath='{.parameters[?(@.kind=="profile")].metrics.ipc.mean}'`
+	profileShort = "Profile an eBPF program with hardware performance counters."
+)
+
+// ProfileFlags are converted to ProfileOptions
+type ProfileFlags struct {
+
+	// Target selection
+	ID uint32
+
+	// Measurement window
+	Duration time.Duration
+	Warmup   time.Duration
+
+	// Output selection
+	PrintFlags *PrintFlags
+	GateFlags  *GateFlags
+
+	// Stats config
+	Percentiles []string // e.g. ["50","90","99","99.9"] or ["p50","p99"]
+	Events      []string // e.g. ["cycles","instructions"]
+}
+
+// NewProfileFlags returns a default ProfileFlags
+func NewProfileFlags() *ProfileFlags {
+	events := make([]string, 0, len(probe.Events()))
+	for _, ev := range probe.Events() {
+		events = append(events, ev.Name)
+	}
+	return &ProfileFlags{
+		PrintFlags: NewPrintFlags(),
+		GateFlags:  NewGateFlags(),
+		Events:     events,
+	}
+}
+
+// AddFlags registers flags for a cli
+func (flags *ProfileFlags) AddFlags(cmd *cobra.Command) {
+	// Target selection
+	cmd.Flags().Uint32Var(&flags.ID, "id", flags.ID,
+		"eBPF program identifier to profile (typically the kernel bpf_prog id).")
+
+	// Measurement window
+	cmd.Flags().DurationVar(&flags.Duration, "duration", flags.Duration,
+		"How long to collect samples for (e.g. 10s, 1m).")
+	cmd.Flags().DurationVar(&flags.Warmup, "warmup", flags.Warmup,
+		"Optional warmup period to discard before measurement (e.g. 5s).")
+
+	// Stats config
+	cmd.Flags().StringSliceVar(&flags.Percentiles, "percentiles", flags.Percentiles,
+		"Percentile set to compute: default, wide, or tail. Example: --percentiles tail")
+	cmd.Flags().StringSliceVar(&flags.Events, "events", flags.Events,
+		"Hardware counters to read around each run: "+strings.Join(flags.Events, ", ")+".")
+
+	// Output selection
+	flags.PrintFlags.AddFlags(cmd)
+	flags.GateFlags.AddFlags(cmd)
+}
+
+func (flags *ProfileFlags) ToOptions(parent string, args []string) (*ProfileOptions, error) {
+	// Validation
+	if flags.ID == 0 {
+		return nil, fmt.Errorf("--id is required")
+	}
+	if flags.Duration == 0 {
+		return nil, fmt.Errorf("--duration is required")
+	}
+
+	o := &ProfileOptions{
+		ID:       flags.ID,
+		Duration: flags.Duration,
+		ErrOut:   os.Stderr,
+	}
+
+	// Handle optional warmup
+	if flags.Warmup > 0 {
+		o.Warmup = &flags.Warmup
+	}
+
+	for _, name := range flags.Events {
+		ev, ok := probe.EventByName(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown event %q", name)
+		}
+		o.Events = append(o.Events, ev)
+	}
+	if len(o.Events) == 0 {
+		return nil, fmt.Errorf("--events must name at least one counter")
+	}
+
+	// Determine output format
+	if err := flags.PrintFlags.Validate(); err != nil {
+		return nil, err
+	}
+	o.Format = flags.PrintFlags.Format()
+	o.ToPrinter = flags.PrintFlags.ToPrinter
+	o.Timelines = flags.PrintFlags.Timelines()
+	o.OpenOutput = flags.PrintFlags.OpenOutput
+
+	o.PercentileKeys = normalizePercentiles(flags.Percentiles)
+
+	// Pass/fail conditions
+	g, err := flags.GateFlags.ToGate()
+	if err != nil {
+		return nil, err
+	}
+	o.Gate = g
+
+	return o, nil
+}
+
+type ProfileOptions struct {
+
+	// Target selection
+	ID uint32
+
+	// Measurement window
+	Duration time.Duration
+	Warmup   *time.Duration // nil => no warmup/discard
+
+	// Output selection
+	Format     string // printer name, e.g. "text", "json", "csv"
+	Out        io.Writer
+	OpenOutput func() (io.Writer, func(), error) // --output-file or stdout
+	ToPrinter  func(io.Writer) (output.Printer, error)
+	Timelines  bool      // keep per-interval timelines in the results
+	ErrOut     io.Writer // warnings and diagnostics
+
+	Gate *Gate // pass/fail conditions on the results
+
+	PercentileKeys []string      // normalized: ["p50","p90","p99","p99_9"]
+	Events         []probe.Event // counters to read
+}
+
+func (o *ProfileOptions) Run() error {
+	out, closeOut, err := o.OpenOutput()
+	if err != nil {
+		return fmt.Errorf("setup output: %w", err)
+	}
+	defer closeOut()
+	o.Out = out
+
+	// Fingerprint the host before measuring
+	env := environment.Capture()
+	printEnvironmentWarnings(o.ErrOut, env)
+
+	prog, err := program.Describe(o.ID)
+	if err != nil {
+		return fmt.Errorf("describe program %d: %w", o.ID, err)
+	}
+
+	profiler, err := probe.AttachProfile(o.ID, o.Events)
+	if errors.Is(err, probe.ErrNoPMU) {
+		return fmt.Errorf("cannot profile program %d: %w", o.ID, err)
+	}
+	if err != nil {
+		return fmt.Errorf("attach profiling probes: %w", err)
+	}
+	defer profiler.Close()
+	if missing := profiler.Unavailable(); len(missing) > 0 {
+		fmt.Fprintf(o.ErrOut, "warning: counters not provided by the PMU, skipping: %s\n", strings.Join(missing, ", "))
+	}
+
+	interval := 100 * time.Millisecond // sampling interval
+	pc := collector.NewProfileCollector(o.ID, profiler, interval, o.Warmup, o.PercentileKeys)
+
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), o.Duration)
+	defer cancel()
+
+	if o.Format == "text" {
+		fmt.Fprintf(o.Out, "Profiling eBPF program %d for %v...\n", o.ID, o.Duration)
+	}
+	if err := pc.Start(ctx); err != nil && err != context.DeadlineExceeded {
+		return err
+	}
+	if err := pc.Err(); err != nil {
+		fmt.Fprintf(o.ErrOut, "Warning: reading counters failed: %v\n", err)
+	}
+
+	snap, err := pc.Snapshot()
+	if err != nil {
+		return fmt.Errorf("get final snapshot: %w", err)
+	}
+
+	if o.Format == "text" {
+		fmt.Fprintln(o.Out, "\n=== Final Statistics ===")
+	}
+
+	outputter, err := o.ToPrinter(o.Out)
+	if err != nil {
+		return err
+	}
+
+	meta := environment.RunMetadata(started, time.Now(), env)
+	meta.Programs = []bpfsv1.Program{*prog}
+	params := []bpfsv1.Parameter{snap}
+	if !o.Timelines {
+		params = withoutTimelines(params...)
+	}
+	report := bpfsv1.NewReport(meta, params...)
+	if err := outputter.OutputReport(report, o.Out); err != nil {
+		return fmt.Errorf("output statistics: %w", err)
+	}
+	return o.Gate.Evaluate(report, o.ErrOut)
+}
@@ -0,0 +1,196 @@
+/*
+Copyright © 2026 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/environment"
+	"github.com/Tjaarda1/bpfstats/internal/program"
+	"github.com/Tjaarda1/bpfstats/internal/record"
+	"github.com/cilium/ebpf"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdRecord returns the record command
+func NewCmdRecord(parent string) *cobra.Command {
+	flags := NewRecordFlags()
+	cmd := &cobra.Command{
+		Use:                   "record",
+		DisableFlagsInUseLine: true,
+		Short:                 recordShort,
+		Long:                  recordLong,
+		Example:               recordExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o, err := flags.ToOptions(parent, args)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true // flags are valid, errors are runtime failures
+			return o.Run()
+		},
+	}
+
+	flags.AddFlags(cmd)
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(NewCmdRecord(rootCmd.Name()))
+}
+
+var (
+	recordLong = `
+		Record the raw kernel statistics of an eBPF program for offline analysis.
+
+		This command polls the run count, cumulative runtime and recursion misses of the
+		selected program every interval, exactly as the latency command does, and saves every
+		observation with its timestamp to a compact binary file. Failed polls are saved too.
+		The file also holds the program description and a fingerprint of the host.
+
+		Use "analyze" to replay a recording through the same collectors, statistics and
+		printers, e.g. to try other percentiles, warmup periods or output formats without
+		measuring again, or to analyze on another machine.`
+
+	recordExample = `
+		# Record program 42 for 60 seconds
+		bpfstat record --id 42 --duration 60s --output-file run.bpfrec
+
+		# Analyze the recording later, discarding the first 5 seconds
+		bpfstat analyze run.bpfrec --warmup 5s -o json`
+	recordShort = "Record the raw kernel statistics of an eBPF program for offline analysis."
+)
+
+// RecordFlags are converted to RecordOptions
+type RecordFlags struct {
+
+	// Target selection
+	ID uint32
+
+	// Measurement window
+	Duration time.Duration
+	Interval time.Duration
+
+	// Destination
+	OutputFile string
+}
+
+// NewRecordFlags returns a default RecordFlags
+func NewRecordFlags() *RecordFlags {
+	return &RecordFlags{
+		Interval: 100 * time.Millisecond,
+	}
+}
+
+// AddFlags registers flags for a cli
+func (flags *RecordFlags) AddFlags(cmd *cobra.Command) {
+	// Target selection
+	cmd.Flags().Uint32Var(&flags.ID, "id", flags.ID,
+		"eBPF program identifier to record (typically the kernel bpf_prog id).")
+
+	// Measurement window
+	cmd.Flags().DurationVar(&flags.Duration, "duration", flags.Duration,
+		"How long to record for (e.g. 10s, 1m).")
+	cmd.Flags().DurationVar(&flags.Interval, "interval", flags.Interval,
+		"How often to poll the program statistics.")
+
+	// Destination
+	cmd.Flags().StringVar(&flags.OutputFile, "output-file", flags.OutputFile,
+		"File to write the recording to, e.g. run.bpfrec.")
+}
+
+func (flags *RecordFlags) ToOptions(parent string, args []string) (*RecordOptions, error) {
+	// Validation
+	if flags.ID == 0 {
+		return nil, fmt.Errorf("--id is required")
+	}
+	if flags.Duration == 0 {
+		return nil, fmt.Errorf("--duration is required")
+	}
+	if flags.Interval <= 0 {
+		return nil, fmt.Errorf("--interval must be positive")
+	}
+	if flags.OutputFile == "" {
+		return nil, fmt.Errorf("--output-file is required")
+	}
+
+	return &RecordOptions{
+		ID:         flags.ID,
+		Duration:   flags.Duration,
+		Interval:   flags.Interval,
+		OutputPath: flags.OutputFile,
+		ErrOut:     os.Stderr,
+	}, nil
+}
+
+type RecordOptions struct {
+
+	// Target selection
+	ID uint32
+
+	// Measurement window
+	Duration time.Duration
+	Interval time.Duration
+
+	// Destination
+	OutputPath string
+	ErrOut     io.Writer // progress, warnings and diagnostics
+}
+
+func (o *RecordOptions) Run() error {
+	// Fingerprint the host before recording
+	env := environment.Capture()
+	printEnvironmentWarnings(o.ErrOut, env)
+
+	prog, err := program.Describe(o.ID)
+	if err != nil {
+		return fmt.Errorf("describe program %d: %w", o.ID, err)
+	}
+
+	f, err := os.Create(o.OutputPath)
+	if err != nil {
+		return fmt.Errorf("create recording: %w", err)
+	}
+	defer f.Close()
+
+	started := time.Now()
+	meta := environment.RunMetadata(started, started, env)
+	meta.Ended = nil // unknown until the recording ends
+	meta.Programs = []bpfsv1.Program{*prog}
+
+	rw, err := record.NewWriter(f, record.Header{
+		ID:       o.ID,
+		Interval: o.Interval,
+		Started:  started,
+		Metadata: meta,
+	})
+	if err != nil {
+		return fmt.Errorf("write recording: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.Duration)
+	defer cancel()
+
+	fmt.Fprintf(o.ErrOut, "Recording eBPF program %d to %s for %v...\n", o.ID, o.OutputPath, o.Duration)
+	var polls int
+	onPoll := func(stats *ebpf.ProgramStats) {
+		polls++
+		fmt.Fprintf(o.ErrOut, "\r\033[KObservations: %d | Runs: %d", polls, stats.RunCount)
+	}
+	if err := record.Capture(ctx, rw, o.ID, o.Interval, onPoll); err != nil {
+		return fmt.Errorf("write recording: %w", err)
+	}
+	fmt.Fprintln(o.ErrOut)
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close recording: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+// printEnvironmentWarnings reports conditions that inflate variance before a
+// measurement starts, so users can fix them instead of discarding results.
+func printEnvironmentWarnings(w io.Writer, env *bpfsv1.Environment) {
+	for _, warning := range env.Warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+}
+
+// printIntegrityWarnings reports polls that did not contribute to the
+// results, so incomplete measurements are noticed even in machine-readable
+// output.
+func printIntegrityWarnings(w io.Writer, in *bpfsv1.Integrity) {
+	for _, warning := range in.Warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+}
+
+// withoutTimelines returns params without their per-interval timelines,
+// which grow with the length of the run and are only reported on request
+// (--timeline, or --plot to draw them).
+func withoutTimelines(params ...bpfsv1.Parameter) []bpfsv1.Parameter {
+	out := make([]bpfsv1.Parameter, len(params))
+	for i, p := range params {
+		switch t := p.(type) {
+		case bpfsv1.Latency:
+			t.Timeline = nil
+			p = t
+		case bpfsv1.Throughput:
+			t.Timeline = nil
+			p = t
+		case bpfsv1.Bench:
+			t.Timeline = nil
+			p = t
+		case bpfsv1.ABTest:
+			t.Baseline.Timeline, t.Candidate.Timeline = nil, nil
+			p = t
+		case bpfsv1.Profile:
+			metrics := make(map[string]bpfsv1.CounterStats, len(t.Metrics))
+			for name, m := range t.Metrics {
+				m.Timeline = nil
+				metrics[name] = m
+			}
+			t.Metrics = metrics
+			p = t
+		}
+		out[i] = p
+	}
+	return out
+}
@@ -4,8 +4,10 @@ Copyright © 2026 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"os"
 
+	"github.com/Tjaarda1/bpfstats/internal/gate"
 	"github.com/spf13/cobra"
 )
 
@@ -26,6 +28,10 @@ quantifiable overhead.`,
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+	if errors.Is(err, gate.ErrFailed) {
+		// Distinguish violated --assert/--baseline conditions from failures
+		os.Exit(2)
+	}
 	if err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,213 @@
+// Package bench drives a loaded eBPF program with BPF_PROG_TEST_RUN over a
+// fixed input, which gives per-run durations that do not depend on live
+// traffic. Supported program types include XDP, TC and socket filters.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/Tjaarda1/bpfstats/internal/program"
+	"github.com/cilium/ebpf"
+)
+
+// Options configure a benchmark.
+type Options struct {
+	// Inputs are passed to the program in order: a single input, or the
+	// packets of a capture.
+	Inputs    [][]byte
+	InputName string // reported with the results, e.g. the file name
+	Capture   bool   // Inputs come from a packet capture
+
+	Repeat       int // runs per input and trial
+	Trials       int // measured trials
+	WarmupTrials int // trials run first and discarded
+
+	// Percentile keys to report, e.g. "p50", "p99_9"
+	Percentiles []string
+}
+
+// Run benchmarks the program with the given kernel ID. Each trial makes one
+// BPF_PROG_TEST_RUN call of Repeat runs per input, and each call contributes
+// its average duration per run as a sample. For captures, samples are also
+// grouped by the program's return code. Cancelling ctx stops between calls.
+func Run(ctx context.Context, id uint32, opts Options) (bpfsv1.Bench, error) {
+	if opts.Repeat <= 0 || opts.Trials <= 0 {
+		return bpfsv1.Bench{}, fmt.Errorf("repeat and trials must be positive")
+	}
+	if len(opts.Inputs) == 0 {
+		return bpfsv1.Bench{}, fmt.Errorf("no input to run the program with")
+	}
+
+	prog, err := ebpf.NewProgramFromID(ebpf.ProgramID(id))
+	if err != nil {
+		return bpfsv1.Bench{}, fmt.Errorf("NewProgramFromID: %w", err)
+	}
+	defer prog.Close()
+
+	started := time.Now()
+	s := &collector.Stats{}
+	classes := make(map[uint32]*collector.Stats)
+	var retval uint32
+	for trial := 0; trial < opts.WarmupTrials+opts.Trials; trial++ {
+		for i, in := range opts.Inputs {
+			if err := ctx.Err(); err != nil {
+				return bpfsv1.Bench{}, err
+			}
+			ret, perRun, err := prog.Benchmark(in, opts.Repeat, nil)
+			if errors.Is(err, ebpf.ErrNotSupported) {
+				return bpfsv1.Bench{}, fmt.Errorf("program type %s does not support BPF_PROG_TEST_RUN: %w", prog.Type(), err)
+			}
+			if err != nil {
+				if opts.Capture {
+					return bpfsv1.Bench{}, fmt.Errorf("trial %d, packet %d: %w", trial+1, i+1, err)
+				}
+				return bpfsv1.Bench{}, fmt.Errorf("trial %d: %w", trial+1, err)
+			}
+			retval = ret
+			if trial < opts.WarmupTrials {
+				continue
+			}
+			ns := float64(perRun.Nanoseconds())
+			s.Add(ns)
+			if classes[ret] == nil {
+				classes[ret] = &collector.Stats{}
+			}
+			classes[ret].Add(ns)
+		}
+	}
+	ended := time.Now()
+
+	overall, err := summarize(s, opts.Percentiles)
+	if err != nil {
+		return bpfsv1.Bench{}, err
+	}
+
+	inputSize := 0
+	for _, in := range opts.Inputs {
+		inputSize += len(in)
+	}
+
+	b := bpfsv1.Bench{
+		ID:      id,
+		Started: &started,
+		Ended:   &ended,
+
+		Input:        opts.InputName,
+		InputSize:    inputSize,
+		Repeat:       opts.Repeat,
+		WarmupTrials: opts.WarmupTrials,
+		Retval:       retval,
+
+		Samples: s.Count(),
+		Runs:    s.Count() * uint64(opts.Repeat),
+
+		Mean:   overall.mean,
+		StdDev: overall.stddev,
+		CV:     overall.cv,
+		Min:    overall.min,
+		Max:    overall.max,
+
+		Percentiles: overall.percentiles,
+		Timeline:    overall.timeline,
+	}
+
+	if opts.Capture {
+		b.Packets = len(opts.Inputs)
+		if b.Classes, err = breakdown(prog.Type(), classes, s.Count(), opts.Percentiles); err != nil {
+			return bpfsv1.Bench{}, err
+		}
+	}
+	return b, nil
+}
+
+// breakdown summarizes each return code's samples, most frequent first.
+func breakdown(typ ebpf.ProgramType, classes map[uint32]*collector.Stats, total uint64, percentiles []string) ([]bpfsv1.BenchClass, error) {
+	out := make([]bpfsv1.BenchClass, 0, len(classes))
+	for ret, s := range classes {
+		sum, err := summarize(s, percentiles)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, bpfsv1.BenchClass{
+			Retval:  ret,
+			Verdict: program.Verdict(typ, ret),
+
+			Samples: s.Count(),
+			Share:   float64(s.Count()) / float64(total),
+
+			Mean:   sum.mean,
+			StdDev: sum.stddev,
+			CV:     sum.cv,
+			Min:    sum.min,
+			Max:    sum.max,
+
+			Percentiles: sum.percentiles,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Samples != out[j].Samples {
+			return out[i].Samples > out[j].Samples
+		}
+		return out[i].Retval < out[j].Retval
+	})
+	return out, nil
+}
+
+// summary holds the nanosecond statistics shared by Bench and BenchClass.
+type summary struct {
+	mean, stddev uint64
+	cv           *float64
+	min, max     *uint64
+	percentiles  *map[string]uint64
+	timeline     []uint64
+}
+
+func summarize(s *collector.Stats, keys []string) (summary, error) {
+	mean := s.Mean()
+	stddev := math.Sqrt(s.Variance())
+	min := uint64(s.Min())
+	max := uint64(s.Max())
+
+	// Coefficient of variation
+	var cv *float64
+	if mean > 0 {
+		v := stddev / mean
+		cv = &v
+	}
+
+	samples := s.Samples()
+	timeline := make([]uint64, len(samples))
+	for i, v := range samples {
+		timeline[i] = uint64(v)
+	}
+
+	var percentiles *map[string]uint64
+	if len(keys) > 0 {
+		values, err := s.PercentileMap(keys)
+		if err != nil {
+			return summary{}, err
+		}
+		m := make(map[string]uint64, len(values))
+		for k, v := range values {
+			m[k] = uint64(v)
+		}
+		percentiles = &m
+	}
+
+	return summary{
+		mean:        uint64(mean),
+		stddev:      uint64(stddev),
+		cv:          cv,
+		min:         &min,
+		max:         &max,
+		percentiles: percentiles,
+		timeline:    timeline,
+	}, nil
+}
@@ -0,0 +1,214 @@
+package collector
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/cilium/ebpf"
+)
+
+// PairedAnalyzer summarizes the differences between aligned baseline and
+// candidate samples, e.g. compare.Paired with fixed options.
+type PairedAnalyzer func(baseline, candidate []float64) (bpfsv1.PairedDifference, error)
+
+// ABCollector measures two programs in the same window. Its Runner reads
+// the statistics of both back to back every interval, alternating which one
+// is read first, and it keeps the pair of per-interval mean latencies when
+// both ran.
+type ABCollector struct {
+	runSpan
+
+	baselineID, candidateID uint32
+	analyze                 PairedAnalyzer
+	runner                  *Runner
+
+	// Percentile keys to report, e.g. "p50", "p99_9"
+	percentiles []string
+
+	// Both get a sample for every pair, so their reservoirs keep the same
+	// intervals and their samples stay aligned
+	mu                  sync.RWMutex
+	baseline, candidate *Stats
+
+	// Intervals in which only one of the programs ran
+	unpaired uint64
+	runs     [2]uint64
+
+	// Previous readings, to compute per-interval deltas
+	last   [2]ebpf.ProgramStats
+	primed bool
+}
+
+// NewABCollector creates a new collector comparing candidate against baseline
+func NewABCollector(baseline, candidate uint32, interval time.Duration, warmup *time.Duration, percentiles []string, analyze PairedAnalyzer) *ABCollector {
+	abC := &ABCollector{
+		runSpan:     runSpan{warmup: warmup},
+		baselineID:  baseline,
+		candidateID: candidate,
+		baseline:    &Stats{},
+		candidate:   &Stats{},
+		analyze:     analyze,
+		percentiles: percentiles,
+	}
+	abC.runner = newPairRunner(NewKernelSource(baseline), NewKernelSource(candidate), interval, warmup, abC)
+	return abC
+}
+
+// SetSources replaces the kernel as the source of the statistics of both
+// programs, e.g. with synthetic workloads. It must be called before Start.
+func (abC *ABCollector) SetSources(baseline, candidate StatsSource) {
+	abC.runner.setSources(baseline, candidate)
+}
+
+// Start polls both programs until ctx is done or Stop is called, see
+// Runner.Start.
+func (abC *ABCollector) Start(ctx context.Context) error {
+	return abC.runner.Start(ctx)
+}
+
+// Stop ends polling.
+func (abC *ABCollector) Stop() error {
+	return abC.runner.Stop()
+}
+
+// Err returns the most recent polling error, see Runner.Err.
+func (abC *ABCollector) Err() error {
+	return abC.runner.Err()
+}
+
+// Integrity returns the failed polls and the intervals in which neither
+// program ran.
+func (abC *ABCollector) Integrity() *bpfsv1.Integrity {
+	return abC.runner.Integrity()
+}
+
+func (abC *ABCollector) observePair(_ time.Time, baseline, candidate *ebpf.ProgramStats, warmup bool) error {
+	abC.mu.Lock()
+	defer abC.mu.Unlock()
+
+	cur := [2]ebpf.ProgramStats{*baseline, *candidate}
+	last, primed := abC.last, abC.primed
+	abC.last, abC.primed = cur, true
+
+	// Warmup and the first observation only establish the baseline
+	if !primed || warmup {
+		return nil
+	}
+
+	var means [2]float64
+	var ran [2]bool
+	var dRuns [2]uint64
+	for i := range cur {
+		if cur[i].RunCount > last[i].RunCount && cur[i].Runtime >= last[i].Runtime {
+			dRuns[i] = cur[i].RunCount - last[i].RunCount
+			means[i] = float64(cur[i].Runtime-last[i].Runtime) / float64(dRuns[i])
+			ran[i] = true
+		}
+	}
+
+	switch {
+	case ran[0] && ran[1]:
+		abC.baseline.Add(means[0])
+		abC.candidate.Add(means[1])
+		abC.runs[0] += dRuns[0]
+		abC.runs[1] += dRuns[1]
+	case ran[0] || ran[1]:
+		abC.unpaired++
+	}
+	return nil
+}
+
+// Snapshot captures current statistics without stopping collection
+func (abC *ABCollector) Snapshot() (bpfsv1.Parameter, error) {
+	abC.mu.RLock()
+	defer abC.mu.RUnlock()
+
+	pairs := abC.baseline.Count()
+	if pairs == 0 {
+		return nil, noSamples("ab")
+	}
+
+	baseline, err := abC.side(abC.baseline, abC.runs[0])
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := abC.side(abC.candidate, abC.runs[1])
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := abC.analyze(abC.baseline.Samples(), abC.candidate.Samples())
+	if err != nil {
+		return nil, err
+	}
+
+	started, ended, duration := abC.bounds()
+	ab := bpfsv1.ABTest{
+		BaselineID:  abC.baselineID,
+		CandidateID: abC.candidateID,
+
+		Duration: duration,
+		Warmup:   abC.warmup,
+		Started:  &started,
+		Ended:    &ended,
+
+		Pairs:    pairs,
+		Unpaired: abC.unpaired,
+
+		Baseline:  baseline,
+		Candidate: candidate,
+
+		Difference: diff,
+	}
+
+	return ab, nil
+}
+
+func (abC *ABCollector) side(s *Stats, runs uint64) (bpfsv1.ABSide, error) {
+	mean := s.Mean()
+	stddev := math.Sqrt(s.Variance())
+	min := uint64(s.Min())
+	max := uint64(s.Max())
+
+	// Coefficient of variation
+	var cv *float64
+	if mean > 0 {
+		v := stddev / mean
+		cv = &v
+	}
+
+	samples := s.Samples()
+	timeline := make([]uint64, len(samples))
+	for i, v := range samples {
+		timeline[i] = uint64(v)
+	}
+
+	var percentiles *map[string]uint64
+	if len(abC.percentiles) > 0 {
+		values, err := s.PercentileMap(abC.percentiles)
+		if err != nil {
+			return bpfsv1.ABSide{}, err
+		}
+		m := make(map[string]uint64, len(values))
+		for k, v := range values {
+			m[k] = uint64(v)
+		}
+		percentiles = &m
+	}
+
+	return bpfsv1.ABSide{
+		Runs: runs,
+
+		Mean:   uint64(mean),
+		StdDev: uint64(stddev),
+		CV:     cv,
+		Min:    &min,
+		Max:    &max,
+
+		Percentiles: percentiles,
+		Timeline:    timeline,
+	}, nil
+}
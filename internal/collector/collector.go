@@ -2,6 +2,7 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
@@ -13,14 +14,42 @@ type Collector interface {
 	Snapshot() (bpfsv1.Parameter, error)
 }
 
-// Optional: for bounded runs (CLI duration) with clear semantics.
+// ErrNoSamples is returned (wrapped) by Snapshot while a collector has no
+// interval with data, e.g. of a program that was never invoked.
+var ErrNoSamples = errors.New("no samples collected yet")
+
+// noSamplesError is ErrNoSamples of one metric.
+type noSamplesError struct {
+	metric string
+}
+
+func noSamples(metric string) error {
+	return noSamplesError{metric: metric}
+}
+
+func (e noSamplesError) Error() string {
+	return e.metric + ": " + ErrNoSamples.Error()
+}
+
+func (e noSamplesError) Is(target error) bool {
+	return target == ErrNoSamples
+}
+
+// Optional: for bounded runs (CLI duration) with clear semantics, and for
+// long-running measurements that report recent rather than cumulative stats.
 type Windowed interface {
-	// Reset clears previous state and sets the window semantics.
+	// Reset clears previous state and sets the window semantics: a positive
+	// window limits the stats to its trailing span, 0 means since the reset.
 	Reset(window time.Duration, warmup *time.Duration)
 	// Finalize freezes the stats (optional; Snapshot can also compute on demand).
 	Finalize()
 }
 
+var (
+	_ Windowed = (*LatencyCollector)(nil)
+	_ Windowed = (*CpuCollector)(nil)
+)
+
 // func GetProgramId(name string) int64 {
 //       p, err := ebpf.NewProgramFromID(ebpf.ProgramID(0))
 //       if err != nil {
@@ -1,214 +1,334 @@
 package collector
 
 import (
-	"context"
-	"fmt"
 	"math"
 	"sync"
 	"time"
 
 	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/environment"
+	"github.com/Tjaarda1/bpfstats/internal/probe"
 	"github.com/cilium/ebpf"
 )
 
 type CpuCollector struct {
-	id       uint32
-	s        *Stats
-	interval time.Duration
-
-	// Lifecycle management
-	mu      sync.RWMutex
-	running bool
-	done    chan struct{}
-	errCh   chan error
-
-	// Measurement metadata
-	started time.Time
-	warmup  *time.Duration
+	polled
+	runSpan
+
+	id uint32
+	s  *Stats
+
+	// Machine capacity in CPUs, to normalize cores consumed
+	cpus int
+
+	// Optional per-CPU attribution: counters accumulated since the first
+	// post-warmup observation, and the latest reading
+	probe      *probe.Probe
+	perCPU     []probe.Counters
+	perCPULast []probe.Counters
+
+	// Previous observation, to compute per-interval deltas
+	lastRuntime time.Duration
+	lastTime    *time.Time
+
+	// Window semantics set by Reset, and recent-window statistics
+	trailing  *window   // when set, statistics cover its span instead of s
+	windows   []*window // reported besides the main statistics
+	warmupEnd time.Time // warmup restarted by Reset
+	finalized bool      // observations are ignored until Reset
+
+	mu sync.RWMutex
 }
 
 // NewCpuCollector creates a new cpu collector
 func NewCPUCollector(id uint32, interval time.Duration, warmup *time.Duration) *CpuCollector {
-	return &CpuCollector{
-		id:       id,
-		s:        &Stats{},
-		interval: interval,
-		warmup:   warmup,
-		done:     make(chan struct{}),
-		errCh:    make(chan error, 1), // buffered to prevent goroutine leak
+	cpuC := &CpuCollector{
+		runSpan: runSpan{warmup: warmup},
+		id:      id,
+		s:       &Stats{},
+		cpus:    environment.AvailableCPUs(),
 	}
+	cpuC.runner = newRunner(NewKernelSource(id), interval, warmup, cpuC)
+	return cpuC
 }
 
-// Start begins collecting cpu statistics
-func (cpuC *CpuCollector) Start(ctx context.Context) error {
-	cpuC.mu.Lock()
-	if cpuC.running {
-		cpuC.mu.Unlock()
-		return fmt.Errorf("collector already running")
-	}
-	cpuC.running = true
-	cpuC.started = time.Now()
-	cpuC.mu.Unlock()
-
-	ticker := time.NewTicker(cpuC.interval)
-	defer ticker.Stop()
-
-	warmupEnd := cpuC.started
-	if cpuC.warmup != nil {
-		warmupEnd = cpuC.started.Add(*cpuC.warmup)
-	}
-
-	var lastRuntime time.Duration
-	var lastTime *time.Time
-	for {
-		select {
-		case <-ctx.Done():
-			// Graceful shutdown pattern from net/http.Server
-			cpuC.mu.Lock()
-			cpuC.running = false
-			cpuC.mu.Unlock()
-			// close(cpuC.done)
-			return ctx.Err()
-
-		case <-cpuC.done:
-			// Explicit Stop() called
-			return nil
-
-		case <-ticker.C:
-			prog, err := ebpf.NewProgramFromID(ebpf.ProgramID(cpuC.id))
-			if err != nil { /* ... */
-				continue
-			}
-
-			stats, err := prog.Stats()
-			prog.Close()
-			if err != nil { /* ... */
-				continue
+func init() {
+	Register(Registration{
+		Name: "cpu",
+		Kind: bpfsv1.Cpu{}.Kind(),
+		New: func(opts Options) Collector {
+			cpuC := NewCPUCollector(opts.ID, opts.Interval, opts.Warmup)
+			if opts.PerCPUProbe != nil {
+				cpuC.SetProbe(opts.PerCPUProbe)
 			}
+			cpuC.SetWindows(opts.Windows)
+			return cpuC
+		},
+	})
+}
 
-			now := time.Now()
+// SetProbe enables the per-CPU breakdown using fentry/fexit probes attached
+// to the program. It must be called before Start.
+func (cpuC *CpuCollector) SetProbe(p *probe.Probe) {
+	cpuC.mu.Lock()
+	defer cpuC.mu.Unlock()
+	cpuC.probe = p
+}
 
-			// Warmup handling: keep baseline aligned to wall clock and runtime
-			if cpuC.warmup != nil && now.Before(warmupEnd) {
-				t := now
-				lastTime = &t
-				lastRuntime = stats.Runtime
-				continue
-			}
+// SetWindows adds recent-window statistics of the cores consumed to the
+// snapshots. It must be called before Start.
+func (cpuC *CpuCollector) SetWindows(specs []WindowSpec) {
+	cpuC.mu.Lock()
+	defer cpuC.mu.Unlock()
+	cpuC.windows = newWindows(specs)
+}
 
-			// Prime baseline on first post-warmup observation
-			if lastTime == nil {
-				t := now
-				lastTime = &t
-				lastRuntime = stats.Runtime
-				continue
-			}
+// SetCapacity overrides the machine capacity used to normalize cores
+// consumed, e.g. with the CPUs of the host a recording was made on.
+func (cpuC *CpuCollector) SetCapacity(cpus int) {
+	cpuC.mu.Lock()
+	defer cpuC.mu.Unlock()
+	if cpus > 0 {
+		cpuC.cpus = cpus
+	}
+}
 
-			dRuntime := stats.Runtime - lastRuntime
-			if stats.RunCount == 0 || dRuntime == 0 {
-				// Optional: you might still want to advance wall time baseline here,
-				// depending on your definition of "interval"
-				continue
-			}
+// Reset implements Windowed, see LatencyCollector.Reset. The per-CPU
+// breakdown restarts from the next observation.
+func (cpuC *CpuCollector) Reset(span time.Duration, warmup *time.Duration) {
+	cpuC.mu.Lock()
+	defer cpuC.mu.Unlock()
 
-			dWall := now.Sub(*lastTime)
-			if dWall <= 0 {
-				// avoid divide-by-zero / negative intervals
-				t := now
-				lastTime = &t
-				lastRuntime = stats.Runtime
-				continue
-			}
+	now := time.Now()
+	cpuC.s = &Stats{}
+	cpuC.trailing = nil
+	if span > 0 {
+		cpuC.trailing = &window{spec: WindowSpec{Span: span, Mode: WindowSliding}}
+	}
+	cpuC.windows = newWindows(specsOf(cpuC.windows))
 
-			avgCpuFrac := float64(dRuntime) / float64(dWall) // both are durations
+	cpuC.lastTime = nil
+	cpuC.perCPU = nil
+	cpuC.perCPULast = nil
 
-			cpuC.s.Add(avgCpuFrac)
-			t := now
-			lastTime = &t
-			lastRuntime = stats.Runtime
-		}
+	cpuC.finalized = false
+	cpuC.warmupEnd = time.Time{}
+	if warmup != nil {
+		cpuC.warmupEnd = now.Add(*warmup)
 	}
+	cpuC.restart(now, warmup)
 }
 
-// Stop gracefully stops the collector
-func (cpuC *CpuCollector) Stop() error {
+// Finalize implements Windowed. It freezes the statistics as of now.
+func (cpuC *CpuCollector) Finalize() {
 	cpuC.mu.Lock()
 	defer cpuC.mu.Unlock()
+	cpuC.finalized = true
+	cpuC.finish(time.Now())
+}
 
-	if !cpuC.running {
+// observe processes one reading of the program's kernel statistics.
+func (cpuC *CpuCollector) observe(now time.Time, stats *ebpf.ProgramStats, warmup bool) error {
+	cpuC.mu.Lock()
+	defer cpuC.mu.Unlock()
+	if cpuC.finalized {
 		return nil
 	}
+	warmup = warmup || now.Before(cpuC.warmupEnd)
+
+	err := cpuC.observePerCPU(warmup)
+
+	// Warmup, the first observation and counter resets only move the
+	// baseline, aligned to wall clock and runtime
+	if warmup || cpuC.lastTime == nil || stats.Runtime < cpuC.lastRuntime {
+		cpuC.lastTime = &now
+		cpuC.lastRuntime = stats.Runtime
+		return err
+	}
+
+	dRuntime := stats.Runtime - cpuC.lastRuntime
+	if stats.RunCount == 0 || dRuntime == 0 {
+		return err
+	}
+
+	dWall := now.Sub(*cpuC.lastTime)
+	if dWall <= 0 {
+		// avoid divide-by-zero / negative intervals
+		cpuC.lastTime = &now
+		cpuC.lastRuntime = stats.Runtime
+		return err
+	}
+
+	cores := float64(dRuntime) / float64(dWall) // both are durations
 
-	cpuC.running = false
-	close(cpuC.done)
+	if cpuC.trailing != nil {
+		cpuC.trailing.add(now, cores)
+	} else {
+		cpuC.s.Add(cores)
+	}
+	for _, w := range cpuC.windows {
+		w.add(now, cores)
+	}
+	cpuC.lastTime = &now
+	cpuC.lastRuntime = stats.Runtime
+	return err
+}
 
+// observePerCPU adds the increase of the per-CPU probe counters since the
+// previous reading. During warmup only the baseline moves, so the breakdown
+// covers the same window as the stats. cpuC.mu must be held.
+func (cpuC *CpuCollector) observePerCPU(warmup bool) error {
+	if cpuC.probe == nil {
+		return nil
+	}
+	counters, err := cpuC.probe.PerCPU()
+	if err != nil {
+		return err
+	}
+	if !warmup && cpuC.perCPULast != nil {
+		cpuC.perCPU = addCounters(cpuC.perCPU, cpuC.perCPULast, counters)
+	}
+	cpuC.perCPULast = counters
 	return nil
 }
 
+// addCounters adds the increase of every CPU's counters from last to cur to
+// total and returns it. A CPU whose counters decreased was reset: its
+// interval is skipped and counting resumes from the new totals.
+func addCounters(total, last, cur []probe.Counters) []probe.Counters {
+	for len(total) < len(cur) {
+		total = append(total, probe.Counters{})
+	}
+	for cpu, c := range cur {
+		var l probe.Counters
+		if cpu < len(last) {
+			l = last[cpu]
+		}
+		if c.Runs < l.Runs || c.RuntimeNs < l.RuntimeNs {
+			continue
+		}
+		total[cpu].Runs += c.Runs - l.Runs
+		total[cpu].RuntimeNs += c.RuntimeNs - l.RuntimeNs
+	}
+	return total
+}
+
+// perCPUBreakdown summarizes the per-CPU counter deltas over the window.
+func (cpuC *CpuCollector) perCPUBreakdown() *bpfsv1.PerCPUBreakdown {
+	if cpuC.perCPULast == nil {
+		return nil
+	}
+
+	breakdown := &bpfsv1.PerCPUBreakdown{Source: "fentry/fexit", CPUs: []bpfsv1.CPUCost{}}
+	var total, maxRuntime uint64
+	for cpu, c := range cpuC.perCPU {
+		runs := c.Runs
+		if runs == 0 {
+			continue
+		}
+		runtime := c.RuntimeNs
+		breakdown.CPUs = append(breakdown.CPUs, bpfsv1.CPUCost{
+			CPU:     cpu,
+			Runs:    runs,
+			Runtime: runtime,
+			Mean:    runtime / runs,
+		})
+		total += runtime
+		if runtime > maxRuntime {
+			maxRuntime = runtime
+		}
+	}
+
+	breakdown.ActiveCPUs = len(breakdown.CPUs)
+	if total > 0 {
+		for i := range breakdown.CPUs {
+			breakdown.CPUs[i].RuntimeShare = float64(breakdown.CPUs[i].Runtime) / float64(total)
+		}
+		// Idle CPUs are left out, so they do not dilute the figure
+		meanRuntime := float64(total) / float64(breakdown.ActiveCPUs)
+		breakdown.Imbalance = float64(maxRuntime) / meanRuntime
+	}
+	return breakdown
+}
+
 // Snapshot captures current statistics without stopping collection
 func (cpuC *CpuCollector) Snapshot() (bpfsv1.Parameter, error) {
 	cpuC.mu.RLock()
 	defer cpuC.mu.RUnlock()
 
+	// Statistics since the start, or over the trailing window set by Reset
+	started, ended, duration := cpuC.bounds()
+	s := cpuC.s
+	if cpuC.trailing != nil {
+		s = cpuC.trailing.stats(ended)
+		if duration > cpuC.trailing.spec.Span {
+			duration = cpuC.trailing.spec.Span
+		}
+	}
+
 	// Thread-safe read from Stats
-	count := cpuC.s.Count()
+	count := s.Count()
 	if count == 0 {
-		return nil, fmt.Errorf("no samples collected yet")
+		return nil, noSamples("cpu")
 	}
 
-	mean := cpuC.s.Mean()
-	variance := cpuC.s.Variance()
+	mean := s.Mean()
+	variance := s.Variance()
 	stddev := math.Sqrt(variance)
 
-	min := cpuC.s.Min()
-	max := cpuC.s.Max()
+	min := s.Min()
+	max := s.Max()
 
-	// Coefficient of variation
-	cv := stddev / mean
+	// Coefficient of variation (undefined for a zero mean)
+	var cv *float64
+	if mean > 0 {
+		v := stddev / mean
+		cv = &v
+	}
 
-	now := time.Now()
-	duration := now.Sub(cpuC.started)
+	// Sampling rate (undefined for an empty window)
+	var rate *float64
+	if duration > 0 {
+		r := float64(count) / duration.Seconds()
+		rate = &r
+	}
 
-	// Adjust duration if warmup was used
-	if cpuC.warmup != nil {
-		duration -= *cpuC.warmup
-		if duration < 0 {
-			duration = 0
-		}
+	windows, err := summarizeWindows(cpuC.windows, ended, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	rate := float64(count) / duration.Seconds()
+	// Normalize by machine capacity
+	capacity := float64(cpuC.cpus)
+	machineMin := min / capacity
+	machineMax := max / capacity
 
 	cpu := bpfsv1.Cpu{
 		ID:       cpuC.id,
 		Duration: duration,
 		Warmup:   cpuC.warmup,
-		Started:  &cpuC.started,
-		Ended:    &now,
+		Started:  &started,
+		Ended:    &ended,
 
 		Samples: count,
-		Rate:    &rate,
+		Rate:    rate,
 
 		Mean:   mean,
 		StdDev: stddev,
-		CV:     &cv,
+		CV:     cv,
 		Min:    &min,
 		Max:    &max,
 
-		// Note: Percentiles would require storing all samples or using a sketch
-		// For now, leaving it nil - see below for extension
-	}
+		CPUs:          cpuC.cpus,
+		MachineMean:   mean / capacity,
+		MachineStdDev: stddev / capacity,
+		MachineMin:    &machineMin,
+		MachineMax:    &machineMax,
 
-	return cpu, nil
-}
+		PerCPU: cpuC.perCPUBreakdown(),
 
-// Err returns the most recent non-fatal error (if any)
-// Pattern from:t Go's sql.DB
-func (cpuC *CpuCollector) Err() error {
-	select {
-	case err := <-cpuC.errCh:
-		return err
-	default:
-		return nil
+		Windows: windows,
 	}
+
+	return cpu, nil
 }
@@ -0,0 +1,30 @@
+package collector
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Tjaarda1/bpfstats/internal/probe"
+)
+
+func TestAddCounters(t *testing.T) {
+	readings := [][]probe.Counters{
+		{{Runs: 10, RuntimeNs: 1000}, {Runs: 5, RuntimeNs: 500}},
+		{{Runs: 20, RuntimeNs: 2000}, {Runs: 8, RuntimeNs: 800}},
+		{{Runs: 4, RuntimeNs: 400}, {Runs: 9, RuntimeNs: 900}}, // CPU 0 reset
+		{{Runs: 6, RuntimeNs: 600}, {Runs: 9, RuntimeNs: 900}, {Runs: 3, RuntimeNs: 300}},
+	}
+	var total []probe.Counters
+	for i := 1; i < len(readings); i++ {
+		total = addCounters(total, readings[i-1], readings[i])
+	}
+
+	want := []probe.Counters{
+		{Runs: 12, RuntimeNs: 1200}, // 10 before the reset, 2 after
+		{Runs: 4, RuntimeNs: 400},
+		{Runs: 3, RuntimeNs: 300}, // appeared in the last reading
+	}
+	if !slices.Equal(total, want) {
+		t.Errorf("total = %v, want %v", total, want)
+	}
+}
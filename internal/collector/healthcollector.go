@@ -0,0 +1,110 @@
+package collector
+
+import (
+	"sync"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/cilium/ebpf"
+)
+
+// healthCounters is one observation of the kernel's program counters.
+type healthCounters struct {
+	at       time.Time
+	runs     uint64
+	missed   uint64
+	observed bool
+}
+
+// HealthCollector reports how many invocations the kernel skipped because
+// the program was already running on the CPU (recursion misses). The
+// counters are totals, so runs and misses add up the increase between
+// consecutive post-warmup readings.
+type HealthCollector struct {
+	polled
+	runSpan
+
+	id uint32
+
+	mu           sync.RWMutex
+	first, last  healthCounters
+	runs, missed uint64 // since the first post-warmup reading
+	samples      uint64
+}
+
+// NewHealthCollector creates a new invocation health collector
+func NewHealthCollector(id uint32, interval time.Duration, warmup *time.Duration) *HealthCollector {
+	hC := &HealthCollector{id: id, runSpan: runSpan{warmup: warmup}}
+	hC.runner = newRunner(NewKernelSource(id), interval, warmup, hC)
+	return hC
+}
+
+func init() {
+	Register(Registration{
+		Name: "health",
+		Kind: bpfsv1.Health{}.Kind(),
+		New: func(opts Options) Collector {
+			return NewHealthCollector(opts.ID, opts.Interval, opts.Warmup)
+		},
+	})
+}
+
+func (hC *HealthCollector) observe(now time.Time, stats *ebpf.ProgramStats, warmup bool) error {
+	if warmup {
+		return nil
+	}
+
+	obs := healthCounters{at: now, runs: stats.RunCount, missed: stats.RecursionMisses, observed: true}
+	hC.mu.Lock()
+	defer hC.mu.Unlock()
+	switch {
+	case !hC.last.observed:
+		hC.first = obs
+	case obs.runs >= hC.last.runs && obs.missed >= hC.last.missed:
+		hC.runs += obs.runs - hC.last.runs
+		hC.missed += obs.missed - hC.last.missed
+	}
+	// After a counter reset the interval is skipped and counting resumes
+	// from the new totals
+	hC.last = obs
+	hC.samples++
+	return nil
+}
+
+// Snapshot reports the runs and misses between the first and the latest
+// post-warmup reading.
+func (hC *HealthCollector) Snapshot() (bpfsv1.Parameter, error) {
+	hC.mu.RLock()
+	defer hC.mu.RUnlock()
+
+	// Rates need two observations spanning a window
+	if hC.samples < 2 || !hC.last.at.After(hC.first.at) {
+		return nil, noSamples("health")
+	}
+
+	runs, missed := hC.runs, hC.missed
+	window := hC.last.at.Sub(hC.first.at).Seconds()
+
+	var missedFraction float64
+	if runs+missed > 0 {
+		missedFraction = float64(missed) / float64(runs+missed)
+	}
+
+	started, ended, duration := hC.bounds()
+	return bpfsv1.Health{
+		ID:       hC.id,
+		Duration: duration,
+		Warmup:   hC.warmup,
+		Started:  &started,
+		Ended:    &ended,
+
+		Samples: hC.samples,
+
+		Runs:            runs,
+		RecursionMisses: missed,
+
+		RunRate:        float64(runs) / window,
+		MissRate:       float64(missed) / window,
+		MissedFraction: missedFraction,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/cilium/ebpf"
+)
+
+// resetObservations returns one reading per second: 100 runs and 5 misses
+// per interval, with the counters restarting from zero after the third
+// reading, e.g. because the program was reloaded.
+func resetObservations(t0 time.Time) []Observation {
+	var observations []Observation
+	var stats ebpf.ProgramStats
+	for i := 0; i < 6; i++ {
+		if i == 3 {
+			stats = ebpf.ProgramStats{}
+		}
+		stats.RunCount += 100
+		stats.Runtime += 100 * time.Microsecond
+		stats.RecursionMisses += 5
+		observations = append(observations, Observation{At: t0.Add(time.Duration(i) * time.Second), Stats: stats})
+	}
+	return observations
+}
+
+func TestCounterReset(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	hC := NewHealthCollector(1, time.Second, nil)
+	latC := NewLatencyCollector(1, time.Second, nil, nil)
+	if err := Replay(t0, t0.Add(5*time.Second), nil, resetObservations(t0), hC, latC); err != nil {
+		t.Fatal(err)
+	}
+
+	// The interval across the reset is skipped: 4 of 5 intervals count
+	snap, err := hC.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	health := snap.(bpfsv1.Health)
+	if health.Runs != 400 || health.RecursionMisses != 20 {
+		t.Errorf("health: %d runs, %d misses, want 400 and 20", health.Runs, health.RecursionMisses)
+	}
+	if health.RunRate != 80 {
+		t.Errorf("run rate = %v/s, want 80/s over 5s", health.RunRate)
+	}
+
+	snap, err = latC.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lat := snap.(bpfsv1.Latency)
+	if lat.Dropped == nil || *lat.Dropped != 20 {
+		t.Errorf("dropped = %v, want 20", lat.Dropped)
+	}
+	if lat.Samples != 4 || lat.Mean != 1000 {
+		t.Errorf("latency: %d samples with mean %dns, want 4 with mean 1000ns", lat.Samples, lat.Mean)
+	}
+}
@@ -0,0 +1,159 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+)
+
+// Integrity counts the polls of a measurement that yielded no usable
+// reading: failed polls, polls finding no program and intervals without
+// invocations. A Runner keeps one; several runs may share one to account for
+// a measurement repeated in trials. It is safe for concurrent use.
+type Integrity struct {
+	mu sync.Mutex
+
+	polls           uint64
+	failed          uint64
+	missing         uint64
+	idle            uint64
+	collectorErrors uint64
+	errors          []bpfsv1.ErrorCount
+	empty           []string // metrics without samples
+
+	// Previous successful reading, for the activity of the next interval
+	prev   ebpf.ProgramStats
+	primed bool
+}
+
+// NewIntegrity returns an empty tracker.
+func NewIntegrity() *Integrity {
+	return &Integrity{}
+}
+
+// Failed records a poll at at that returned err.
+func (in *Integrity) Failed(at time.Time, err error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.polls++
+	in.failed++
+	if isMissingProgram(err) {
+		in.missing++
+	}
+	in.addError(at, err)
+}
+
+// Observed records a successful poll at at. Intervals ending within the
+// warmup are not checked for activity.
+func (in *Integrity) Observed(at time.Time, stats *ebpf.ProgramStats, warmup bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.polls++
+	prev, primed := in.prev, in.primed
+	in.prev, in.primed = *stats, true
+
+	// Counters going backwards mean the program was replaced: the reading
+	// is only the baseline of the next interval
+	if !primed || warmup || stats.RunCount < prev.RunCount {
+		return
+	}
+	if stats.RunCount == prev.RunCount {
+		in.idle++
+	}
+}
+
+// CollectorFailed records a collector failing to process the reading at at.
+func (in *Integrity) CollectorFailed(at time.Time, err error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.collectorErrors++
+	in.addError(at, err)
+}
+
+// NoSamples records that metric had no samples to report.
+func (in *Integrity) NoSamples(metric string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if !slices.Contains(in.empty, metric) {
+		in.empty = append(in.empty, metric)
+	}
+}
+
+// rebase forgets the previous reading, so the first interval of a new run
+// is not compared against the last reading of the previous one.
+func (in *Integrity) rebase() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.primed = false
+}
+
+// addError counts err under its message. The caller must hold mu.
+func (in *Integrity) addError(at time.Time, err error) {
+	msg := err.Error()
+	for i := range in.errors {
+		if e := &in.errors[i]; e.Message == msg {
+			e.Count++
+			e.Last = &at
+			return
+		}
+	}
+	if len(in.errors) < bpfsv1.MaxIntegrityErrors {
+		in.errors = append(in.errors, bpfsv1.ErrorCount{Message: msg, Count: 1, First: &at, Last: &at})
+	}
+}
+
+// Summary returns the counts so far and the warnings they call for.
+func (in *Integrity) Summary() *bpfsv1.Integrity {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	s := &bpfsv1.Integrity{
+		Polls:           in.polls,
+		FailedPolls:     in.failed,
+		MissingProgram:  in.missing,
+		ZeroActivity:    in.idle,
+		CollectorErrors: in.collectorErrors,
+		Errors:          append([]bpfsv1.ErrorCount(nil), in.errors...),
+		NoSamples:       slices.Clone(in.empty),
+	}
+	if in.polls > 0 {
+		s.ErrorRate = float64(in.failed) / float64(in.polls)
+	}
+
+	if in.failed > 0 {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("%d of %d polls failed (%.1f%%), results cover fewer intervals than measured",
+			in.failed, in.polls, 100*s.ErrorRate))
+	}
+	if in.missing > 0 {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("program was not loaded during %d polls", in.missing))
+	}
+	if in.idle > 0 {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("%d intervals without invocations, the program may be idle or detached", in.idle))
+	}
+	if len(in.empty) > 0 {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("%s: no samples, left out of the results", strings.Join(in.empty, ", ")))
+	}
+	if in.collectorErrors > 0 {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("collectors failed to process %d readings", in.collectorErrors))
+	}
+	return s
+}
+
+// isMissingProgram reports whether err means no program has the polled ID.
+// Errors read back from a recording keep only their message, so that is
+// checked as well.
+func isMissingProgram(err error) bool {
+	return errors.Is(err, os.ErrNotExist) || strings.HasSuffix(err.Error(), unix.ENOENT.Error())
+}
@@ -1,198 +1,380 @@
 package collector
 
 import (
-	"context"
-	"fmt"
 	"math"
+	"strconv"
 	"sync"
 	"time"
 
 	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/probe"
+	"github.com/Tjaarda1/bpfstats/internal/program"
 	"github.com/cilium/ebpf"
 )
 
 type LatencyCollector struct {
-	id       uint32
-	s        *Stats
-	interval time.Duration
-
-	// Lifecycle management
-	mu      sync.RWMutex
-	running bool
-	done    chan struct{}
-	errCh   chan error
-
-	// Measurement metadata
-	started time.Time
-	warmup  *time.Duration
+	polled
+	runSpan
+
+	id uint32
+	s  *Stats
+
+	// Percentile keys to report, e.g. "p50", "p99_9"
+	percentiles []string
+
+	// Recursion misses since the first post-warmup observation, and the
+	// latest total
+	misses, lastMisses uint64
+	missesObserved     bool
+
+	// Optional per-return-code breakdown from fexit probes
+	probe         *probe.Probe
+	retvalLast    map[uint32]probe.Counters
+	retvalStats   map[uint32]*Stats
+	retvalRuns    map[uint32]uint64
+	retvalObserve bool
+
+	// Previous observation, to compute per-interval deltas
+	lastRuntime time.Duration
+	lastCount   uint64
+	primed      bool
+
+	// Window semantics set by Reset, and recent-window statistics
+	trailing  *window   // when set, statistics cover its span instead of s
+	windows   []*window // reported besides the main statistics
+	warmupEnd time.Time // warmup restarted by Reset
+	finalized bool      // observations are ignored until Reset
+
+	mu sync.RWMutex
 }
 
 // NewLatencyCollector creates a new latency collector
-func NewLatencyCollector(id uint32, interval time.Duration, warmup *time.Duration) *LatencyCollector {
-	return &LatencyCollector{
-		id:       id,
-		s:        &Stats{},
-		interval: interval,
-		warmup:   warmup,
-		done:     make(chan struct{}),
-		errCh:    make(chan error, 1), // buffered to prevent goroutine leak
+func NewLatencyCollector(id uint32, interval time.Duration, warmup *time.Duration, percentiles []string) *LatencyCollector {
+	latC := &LatencyCollector{
+		runSpan:     runSpan{warmup: warmup},
+		id:          id,
+		s:           &Stats{},
+		percentiles: percentiles,
 	}
+	latC.runner = newRunner(NewKernelSource(id), interval, warmup, latC)
+	return latC
 }
 
-// Start begins collecting latency statistics
-func (latC *LatencyCollector) Start(ctx context.Context) error {
-	latC.mu.Lock()
-	if latC.running {
-		latC.mu.Unlock()
-		return fmt.Errorf("collector already running")
-	}
-	latC.running = true
-	latC.started = time.Now()
-	latC.mu.Unlock()
-
-	ticker := time.NewTicker(latC.interval)
-	defer ticker.Stop()
-
-	warmupEnd := latC.started
-	if latC.warmup != nil {
-		warmupEnd = latC.started.Add(*latC.warmup)
-	}
-	lastRuntime := time.Nanosecond * 0
-	var lastCount uint64 = 0
-
-	for {
-		select {
-		case <-ctx.Done():
-			// Graceful shutdown pattern from net/http.Server
-			latC.mu.Lock()
-			latC.running = false
-			latC.mu.Unlock()
-			// close(latC.done)
-			return ctx.Err()
-
-		case <-latC.done:
-			// Explicit Stop() called
-			return nil
-
-		case <-ticker.C:
-			// Collect sample
-			prog, err := ebpf.NewProgramFromID(ebpf.ProgramID(latC.id))
-			if err != nil {
-				// Non-fatal error handling inspired by Prometheus
-				select {
-				case latC.errCh <- fmt.Errorf("NewProgramFromID: %w", err):
-				default:
-					// Don't block if error channel is full
-				}
-				continue
+func init() {
+	Register(Registration{
+		Name: "latency",
+		Kind: bpfsv1.Latency{}.Kind(),
+		New: func(opts Options) Collector {
+			latC := NewLatencyCollector(opts.ID, opts.Interval, opts.Warmup, opts.Percentiles)
+			if opts.ReturnCodeProbe != nil {
+				latC.SetProbe(opts.ReturnCodeProbe)
 			}
+			latC.SetWindows(opts.Windows)
+			return latC
+		},
+	})
+}
 
-			stats, err := prog.Stats()
-			prog.Close() // Close immediately after use
-			if err != nil {
-				select {
-				case latC.errCh <- fmt.Errorf("Stats: %w", err):
-				default:
-				}
-				continue
-			}
+// SetProbe enables the per-return-code breakdown using fentry/fexit probes
+// attached with probe.Options.ReturnCodes. It must be called before Start.
+func (latC *LatencyCollector) SetProbe(p *probe.Probe) {
+	latC.mu.Lock()
+	defer latC.mu.Unlock()
+	latC.probe = p
+	latC.retvalStats = make(map[uint32]*Stats)
+	latC.retvalRuns = make(map[uint32]uint64)
+}
 
-			// Skip samples during warmup period
-			if latC.warmup != nil && time.Now().Before(warmupEnd) {
-				continue
-			}
+// SetWindows adds recent-window statistics, e.g. the last 10 seconds, to
+// the snapshots. It must be called before Start.
+func (latC *LatencyCollector) SetWindows(specs []WindowSpec) {
+	latC.mu.Lock()
+	defer latC.mu.Unlock()
+	latC.windows = newWindows(specs)
+}
 
-			// Record latency sample (runtime per invocation in nanoseconds)
-			if stats.RunCount > 0 && stats.RunCount != lastCount {
-				avgLatencyNs := float64((stats.Runtime)-lastRuntime) / float64((stats.RunCount)-lastCount)
-				latC.s.Add(avgLatencyNs)
-				lastRuntime = stats.Runtime
-				lastCount = stats.RunCount
-			}
-		}
+// Reset implements Windowed. It discards all samples and baselines, so the
+// next observation starts a new measurement with warmup applying from now.
+// With a positive window the statistics cover only its trailing span, which
+// keeps memory bounded in long-running measurements; with 0 they cover
+// everything since the reset. The recent windows and the per-return-code
+// breakdown restart too.
+func (latC *LatencyCollector) Reset(span time.Duration, warmup *time.Duration) {
+	latC.mu.Lock()
+	defer latC.mu.Unlock()
+
+	now := time.Now()
+	latC.s = &Stats{}
+	latC.trailing = nil
+	if span > 0 {
+		latC.trailing = &window{spec: WindowSpec{Span: span, Mode: WindowSliding}}
 	}
+	latC.windows = newWindows(specsOf(latC.windows))
+
+	latC.primed = false
+	latC.misses, latC.missesObserved = 0, false
+	if latC.probe != nil {
+		latC.retvalStats = make(map[uint32]*Stats)
+		latC.retvalRuns = make(map[uint32]uint64)
+		latC.retvalObserve = false
+	}
+
+	latC.finalized = false
+	latC.warmupEnd = time.Time{}
+	if warmup != nil {
+		latC.warmupEnd = now.Add(*warmup)
+	}
+	latC.restart(now, warmup)
 }
 
-// Stop gracefully stops the collector
-func (latC *LatencyCollector) Stop() error {
+// Finalize implements Windowed. It freezes the statistics as of now; later
+// observations are ignored until Reset.
+func (latC *LatencyCollector) Finalize() {
 	latC.mu.Lock()
 	defer latC.mu.Unlock()
+	latC.finalized = true
+	latC.finish(time.Now())
+}
 
-	if !latC.running {
+// observe processes one reading of the program's kernel statistics.
+func (latC *LatencyCollector) observe(at time.Time, stats *ebpf.ProgramStats, warmup bool) error {
+	latC.mu.Lock()
+	defer latC.mu.Unlock()
+	if latC.finalized {
 		return nil
 	}
+	warmup = warmup || at.Before(latC.warmupEnd)
+
+	// The first reading, readings during warmup and readings after a
+	// counter reset only move the baseline, so that no sample spans
+	// invocations from before the window
+	rebase := !latC.primed || warmup || stats.RunCount < latC.lastCount || stats.Runtime < latC.lastRuntime
+	err := latC.observeReturnCodes(warmup)
+	if rebase {
+		latC.lastRuntime = stats.Runtime
+		latC.lastCount = stats.RunCount
+		latC.primed = true
+	}
+	if warmup {
+		return err
+	}
 
-	latC.running = false
-	close(latC.done)
+	// Invocations the kernel skipped are dropped from the distribution. A
+	// decrease is a counter reset, after which counting resumes from the new
+	// total
+	if latC.missesObserved && stats.RecursionMisses >= latC.lastMisses {
+		latC.misses += stats.RecursionMisses - latC.lastMisses
+	}
+	latC.lastMisses = stats.RecursionMisses
+	latC.missesObserved = true
+
+	// Record latency sample (runtime per invocation in nanoseconds)
+	if !rebase && stats.RunCount != latC.lastCount {
+		avgLatencyNs := float64((stats.Runtime)-latC.lastRuntime) / float64((stats.RunCount)-latC.lastCount)
+		if latC.trailing != nil {
+			latC.trailing.add(at, avgLatencyNs)
+		} else {
+			latC.s.Add(avgLatencyNs)
+		}
+		for _, w := range latC.windows {
+			w.add(at, avgLatencyNs)
+		}
+		latC.lastRuntime = stats.Runtime
+		latC.lastCount = stats.RunCount
+	}
+	return err
+}
+
+// observeReturnCodes adds one sample per return code that ran since the
+// previous observation: the mean runtime of those runs. During warmup only
+// the baseline moves. latC.mu must be held.
+func (latC *LatencyCollector) observeReturnCodes(warmup bool) error {
+	if latC.probe == nil {
+		return nil
+	}
+	counters, err := latC.probe.ReturnCodes()
+	if err != nil {
+		return err
+	}
 
+	if !warmup && latC.retvalObserve {
+		for ret, cur := range counters {
+			last := latC.retvalLast[ret]
+			if cur.Runs <= last.Runs {
+				continue
+			}
+			dRuns := cur.Runs - last.Runs
+			if latC.retvalStats[ret] == nil {
+				latC.retvalStats[ret] = &Stats{}
+			}
+			latC.retvalStats[ret].Add(float64(cur.RuntimeNs-last.RuntimeNs) / float64(dRuns))
+			latC.retvalRuns[ret] += dRuns
+		}
+	}
+	latC.retvalLast = counters
+	latC.retvalObserve = true
 	return nil
 }
 
+// returnCodeBreakdown summarizes the per-return-code samples, or returns nil
+// without a probe.
+func (latC *LatencyCollector) returnCodeBreakdown() (map[string]bpfsv1.ReturnCodeLatency, error) {
+	if latC.probe == nil {
+		return nil, nil
+	}
+	var total uint64
+	for _, runs := range latC.retvalRuns {
+		total += runs
+	}
+
+	out := make(map[string]bpfsv1.ReturnCodeLatency, len(latC.retvalStats))
+	for ret, s := range latC.retvalStats {
+		mean := s.Mean()
+		stddev := math.Sqrt(s.Variance())
+		min := uint64(s.Min())
+		max := uint64(s.Max())
+
+		var cv *float64
+		if mean > 0 {
+			v := stddev / mean
+			cv = &v
+		}
+
+		var percentiles *map[string]uint64
+		if len(latC.percentiles) > 0 {
+			values, err := s.PercentileMap(latC.percentiles)
+			if err != nil {
+				return nil, err
+			}
+			m := make(map[string]uint64, len(values))
+			for k, v := range values {
+				m[k] = uint64(v)
+			}
+			percentiles = &m
+		}
+
+		out[strconv.FormatUint(uint64(ret), 10)] = bpfsv1.ReturnCodeLatency{
+			Retval:  ret,
+			Verdict: program.Verdict(latC.probe.ProgramType(), ret),
+
+			Runs:    latC.retvalRuns[ret],
+			Share:   float64(latC.retvalRuns[ret]) / float64(total),
+			Samples: s.Count(),
+
+			Mean:   uint64(mean),
+			StdDev: uint64(stddev),
+			CV:     cv,
+			Min:    &min,
+			Max:    &max,
+
+			Percentiles: percentiles,
+		}
+	}
+	return out, nil
+}
+
 // Snapshot captures current statistics without stopping collection
 func (latC *LatencyCollector) Snapshot() (bpfsv1.Parameter, error) {
 	latC.mu.RLock()
 	defer latC.mu.RUnlock()
 
+	// Statistics since the start, or over the trailing window set by Reset
+	started, ended, duration := latC.bounds()
+	s := latC.s
+	if latC.trailing != nil {
+		s = latC.trailing.stats(ended)
+		if duration > latC.trailing.spec.Span {
+			duration = latC.trailing.spec.Span
+		}
+	}
+
 	// Thread-safe read from Stats
-	count := latC.s.Count()
+	count := s.Count()
 	if count == 0 {
-		return nil, fmt.Errorf("no samples collected yet")
+		return nil, noSamples("latency")
 	}
 
-	mean := latC.s.Mean()
-	variance := latC.s.Variance()
+	mean := s.Mean()
+	variance := s.Variance()
 	stddev := math.Sqrt(variance)
 
-	min := uint64(latC.s.Min())
-	max := uint64(latC.s.Max())
+	min := uint64(s.Min())
+	max := uint64(s.Max())
 
-	// Coefficient of variation
-	cv := stddev / mean
+	// Coefficient of variation (undefined for a zero mean)
+	var cv *float64
+	if mean > 0 {
+		v := stddev / mean
+		cv = &v
+	}
 
-	now := time.Now()
-	duration := now.Sub(latC.started)
+	// Sampling rate (undefined for an empty window)
+	var rate *float64
+	if duration > 0 {
+		r := float64(count) / duration.Seconds()
+		rate = &r
+	}
 
-	// Adjust duration if warmup was used
-	if latC.warmup != nil {
-		duration -= *latC.warmup
-		if duration < 0 {
-			duration = 0
+	samples := s.Samples()
+	timeline := make([]uint64, len(samples))
+	for i, v := range samples {
+		timeline[i] = uint64(v)
+	}
+
+	var percentiles *map[string]uint64
+	if len(latC.percentiles) > 0 {
+		values, err := s.PercentileMap(latC.percentiles)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]uint64, len(values))
+		for k, v := range values {
+			m[k] = uint64(v)
 		}
+		percentiles = &m
 	}
 
-	rate := float64(count) / duration.Seconds()
+	windows, err := summarizeWindows(latC.windows, ended, latC.percentiles)
+	if err != nil {
+		return nil, err
+	}
+
+	byRetval, err := latC.returnCodeBreakdown()
+	if err != nil {
+		return nil, err
+	}
+
+	var dropped *uint64
+	if latC.missesObserved {
+		d := latC.misses
+		dropped = &d
+	}
 
 	latency := bpfsv1.Latency{
 		ID:       latC.id,
 		Duration: duration,
 		Warmup:   latC.warmup,
-		Started:  &latC.started,
-		Ended:    &now,
+		Started:  &started,
+		Ended:    &ended,
 
 		Samples: count,
-		Rate:    &rate,
+		Dropped: dropped,
+		Rate:    rate,
 
 		Mean:   uint64(mean),
 		StdDev: uint64(stddev),
-		CV:     &cv,
+		CV:     cv,
 		Min:    &min,
 		Max:    &max,
 
-		// Note: Percentiles would require storing all samples or using a sketch
-		// For now, leaving it nil - see below for extension
-		Percentiles: nil,
-	}
+		Percentiles: percentiles,
 
-	return latency, nil
-}
+		Timeline: timeline,
 
-// Err returns the most recent non-fatal error (if any)
-// Pattern from: Go's sql.DB
-func (latC *LatencyCollector) Err() error {
-	select {
-	case err := <-latC.errCh:
-		return err
-	default:
-		return nil
+		ByReturnCode: byRetval,
+
+		Windows: windows,
 	}
+
+	return latency, nil
 }
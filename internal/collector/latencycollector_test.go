@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/cilium/ebpf"
+)
+
+func TestSnapshotWithoutRateOrCV(t *testing.T) {
+	// Replayed into a window of zero length, no sampling rate can be
+	// computed. Runs without measurable runtime and an idle program have a
+	// zero mean latency and throughput, so no coefficient of variation.
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	replay := func(c Collector, runs uint64, runtime time.Duration) bpfsv1.Parameter {
+		t.Helper()
+		var observations []Observation
+		for i := 0; i < 3; i++ {
+			stats := ebpf.ProgramStats{RunCount: uint64(i) * runs, Runtime: time.Duration(i) * runtime}
+			observations = append(observations, Observation{At: t0.Add(time.Duration(i) * time.Second), Stats: stats})
+		}
+		if err := Replay(t0, t0, nil, observations, c); err != nil {
+			t.Fatal(err)
+		}
+		snap, err := c.Snapshot()
+		if err != nil {
+			t.Fatalf("%T: %v", c, err)
+		}
+		if _, err := json.Marshal(snap); err != nil {
+			t.Errorf("%T: %v", c, err)
+		}
+		return snap
+	}
+
+	lat := replay(NewLatencyCollector(1, time.Second, nil, nil), 10, 0).(bpfsv1.Latency)
+	if lat.Rate != nil || lat.CV != nil {
+		t.Errorf("latency rate %v and cv %v, want neither", lat.Rate, lat.CV)
+	}
+	tp := replay(NewThroughputCollector(1, time.Second, nil, nil), 0, 0).(bpfsv1.Throughput)
+	if tp.Rate != nil || tp.CV != nil {
+		t.Errorf("throughput rate %v and cv %v, want neither", tp.Rate, tp.CV)
+	}
+	cpu := replay(NewCPUCollector(1, time.Second, nil), 10, time.Millisecond).(bpfsv1.Cpu)
+	if cpu.Rate != nil || cpu.CV == nil {
+		t.Errorf("cpu rate %v and cv %v, want only a cv", cpu.Rate, cpu.CV)
+	}
+}
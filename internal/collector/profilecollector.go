@@ -0,0 +1,232 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/probe"
+	"github.com/cilium/ebpf"
+)
+
+// ipcMetric is derived from the cycles and instructions counters.
+const ipcMetric = "ipc"
+
+type ProfileCollector struct {
+	id       uint32
+	profiler *probe.Profiler
+
+	// One Stats per metric, e.g. "cycles_per_run"
+	metrics []string
+	s       map[string]*Stats
+	runs    uint64
+
+	// Percentile keys to report, e.g. "p50", "p99_9"
+	percentiles []string
+
+	// Counter totals at the previous reading, nil until the first one
+	last                 *probe.ProfileCounters
+	cycles, instructions int // event indexes, -1 when not read
+
+	// Polling when started on its own
+	runner *Runner
+	mu     sync.RWMutex
+
+	// Measurement metadata
+	started time.Time
+	warmup  *time.Duration
+	ended   *time.Time // end of the window once polling stopped
+}
+
+// NewProfileCollector creates a new hardware counter collector reading the
+// counters of an attached profiler
+func NewProfileCollector(id uint32, profiler *probe.Profiler, interval time.Duration, warmup *time.Duration, percentiles []string) *ProfileCollector {
+	pC := &ProfileCollector{
+		id:          id,
+		profiler:    profiler,
+		s:           make(map[string]*Stats),
+		percentiles: percentiles,
+		warmup:      warmup,
+	}
+	pC.cycles, pC.instructions = pC.eventIndex(probe.Cycles), pC.eventIndex(probe.Instructions)
+	for _, ev := range profiler.Events() {
+		pC.metrics = append(pC.metrics, perRunMetric(ev))
+	}
+	if pC.cycles >= 0 && pC.instructions >= 0 {
+		pC.metrics = append(pC.metrics, ipcMetric)
+	}
+	for _, m := range pC.metrics {
+		pC.s[m] = &Stats{}
+	}
+	pC.runner = newRunner(NewKernelSource(id), interval, warmup, pC)
+	return pC
+}
+
+func perRunMetric(ev probe.Event) string {
+	return ev.Name + "_per_run"
+}
+
+func (pC *ProfileCollector) eventIndex(ev probe.Event) int {
+	for i, e := range pC.profiler.Events() {
+		if e.Name == ev.Name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Start begins collecting per-invocation counter statistics
+func (pC *ProfileCollector) Start(ctx context.Context) error {
+	return pC.runner.Start(ctx)
+}
+
+func (pC *ProfileCollector) begin(at time.Time) {
+	pC.mu.Lock()
+	defer pC.mu.Unlock()
+	pC.started = at
+}
+
+// observe reads the profiler's counter totals on every poll. The program's
+// kernel statistics are not used: the probes count the runs they measured.
+func (pC *ProfileCollector) observe(now time.Time, _ *ebpf.ProgramStats, warmup bool) error {
+	cur, err := pC.profiler.Totals()
+	if err != nil {
+		return err
+	}
+
+	pC.mu.Lock()
+	defer pC.mu.Unlock()
+
+	// Warmup and first observation only establish the baseline
+	last := pC.last
+	pC.last = &cur
+	if last == nil || warmup {
+		return nil
+	}
+
+	dRuns := cur.Runs - last.Runs
+	if dRuns == 0 {
+		// Nothing ran: per-run metrics are undefined for this interval
+		return nil
+	}
+
+	delta := make([]float64, len(cur.Values))
+	for i := range cur.Values {
+		delta[i] = float64(cur.Values[i] - last.Values[i])
+	}
+	for i, ev := range pC.profiler.Events() {
+		pC.s[perRunMetric(ev)].Add(delta[i] / float64(dRuns))
+	}
+	if pC.cycles >= 0 && pC.instructions >= 0 && delta[pC.cycles] > 0 {
+		pC.s[ipcMetric].Add(delta[pC.instructions] / delta[pC.cycles])
+	}
+	pC.runs += dRuns
+	return nil
+}
+
+func (pC *ProfileCollector) finish(at time.Time) {
+	pC.mu.Lock()
+	defer pC.mu.Unlock()
+	pC.ended = &at
+}
+
+// Stop gracefully stops the collector
+func (pC *ProfileCollector) Stop() error {
+	return pC.runner.Stop()
+}
+
+// Snapshot captures current statistics without stopping collection
+func (pC *ProfileCollector) Snapshot() (bpfsv1.Parameter, error) {
+	pC.mu.RLock()
+	defer pC.mu.RUnlock()
+
+	count := pC.s[pC.metrics[0]].Count()
+	if count == 0 {
+		return nil, fmt.Errorf("no samples collected yet")
+	}
+
+	metrics := make(map[string]bpfsv1.CounterStats, len(pC.metrics))
+	for _, name := range pC.metrics {
+		s := pC.s[name]
+		if s.Count() == 0 {
+			continue
+		}
+		cs, err := pC.counterStats(s)
+		if err != nil {
+			return nil, err
+		}
+		metrics[name] = cs
+	}
+
+	now := time.Now()
+	if pC.ended != nil {
+		now = *pC.ended
+	}
+	duration := now.Sub(pC.started)
+
+	// Adjust duration if warmup was used
+	if pC.warmup != nil {
+		duration -= *pC.warmup
+		if duration < 0 {
+			duration = 0
+		}
+	}
+
+	profile := bpfsv1.Profile{
+		ID:       pC.id,
+		Duration: duration,
+		Warmup:   pC.warmup,
+		Started:  &pC.started,
+		Ended:    &now,
+
+		Samples: count,
+		Runs:    pC.runs,
+		Source:  "fentry/fexit+perf",
+
+		Metrics:     metrics,
+		Unavailable: pC.profiler.Unavailable(),
+	}
+
+	return profile, nil
+}
+
+func (pC *ProfileCollector) counterStats(s *Stats) (bpfsv1.CounterStats, error) {
+	mean := s.Mean()
+	stddev := math.Sqrt(s.Variance())
+	min := s.Min()
+	max := s.Max()
+
+	// Coefficient of variation (undefined for a zero mean, e.g. no misses)
+	var cv *float64
+	if mean > 0 {
+		v := stddev / mean
+		cv = &v
+	}
+
+	var percentiles *map[string]float64
+	if len(pC.percentiles) > 0 {
+		m, err := s.PercentileMap(pC.percentiles)
+		if err != nil {
+			return bpfsv1.CounterStats{}, err
+		}
+		percentiles = &m
+	}
+
+	return bpfsv1.CounterStats{
+		Mean:        mean,
+		StdDev:      stddev,
+		CV:          cv,
+		Min:         &min,
+		Max:         &max,
+		Percentiles: percentiles,
+		Timeline:    s.Samples(),
+	}, nil
+}
+
+// Err returns the most recent error reading the counters, see Runner.Err.
+func (pC *ProfileCollector) Err() error {
+	return pC.runner.Err()
+}
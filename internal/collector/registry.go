@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tjaarda1/bpfstats/internal/probe"
+)
+
+// Options configure a collector built from the registry.
+type Options struct {
+	ID          uint32
+	Interval    time.Duration
+	Warmup      *time.Duration // nil => no warmup/discard
+	Percentiles []string       // normalized keys, e.g. "p50", "p99_9"
+	Windows     []WindowSpec   // recent windows reported by collectors that support them
+
+	// Optional fentry/fexit probes, nil when the breakdown is not requested
+	PerCPUProbe     *probe.Probe // per-CPU cost breakdown
+	ReturnCodeProbe *probe.Probe // per-return-code latency breakdown
+}
+
+// Factory builds a collector from options.
+type Factory func(opts Options) Collector
+
+// Registration describes a collector available to measuring commands.
+type Registration struct {
+	Name string  // name selected with --metrics, e.g. "latency"
+	Kind string  // kind of the parameter its Snapshot returns
+	New  Factory // constructor
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Registration{}
+)
+
+// Register makes a collector available under r.Name. It panics if the name
+// is already registered, mirroring database/sql.Register.
+func Register(r Registration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if r.New == nil {
+		panic("collector: Register factory is nil")
+	}
+	if _, dup := registry[r.Name]; dup {
+		panic("collector: Register called twice for collector " + r.Name)
+	}
+	registry[r.Name] = r
+}
+
+// Names returns the sorted names of the registered collectors.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the registration of the collector called name.
+func Lookup(name string) (Registration, error) {
+	registryMu.RLock()
+	r, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return Registration{}, fmt.Errorf("unknown metric %q (allowed: %s)", name, strings.Join(Names(), ", "))
+	}
+	return r, nil
+}
+
+// New builds the collectors called names, in order.
+func New(names []string, opts Options) ([]Collector, error) {
+	collectors := make([]Collector, 0, len(names))
+	for _, name := range names {
+		r, err := Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		collectors = append(collectors, r.New(opts))
+	}
+	return collectors, nil
+}
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+// Observation is one reading of a program's kernel statistics, as polled by
+// the collectors every interval.
+type Observation struct {
+	At    time.Time
+	Stats ebpf.ProgramStats
+}
+
+// Replay feeds recorded observations through the collectors as if a Runner
+// had polled them live, starting at started and ending at ended, with the
+// same warmup handling. Snapshot then reports the recorded window instead of
+// the wall clock. The collectors must not be started. Errors of individual
+// observations, e.g. from probes, are returned joined after the replay.
+func Replay(started, ended time.Time, warmup *time.Duration, observations []Observation, collectors ...Collector) error {
+	observers, err := observersOf(collectors)
+	if err != nil {
+		return err
+	}
+
+	warmupEnd := started
+	if warmup != nil {
+		warmupEnd = started.Add(*warmup)
+	}
+
+	for _, o := range observers {
+		o.begin(started)
+	}
+	var errs []error
+	for i := range observations {
+		at := observations[i].At
+		for _, o := range observers {
+			if err := o.observe(at, &observations[i].Stats, at.Before(warmupEnd)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, o := range observers {
+		o.finish(ended)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,421 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/cilium/ebpf"
+)
+
+// observer is implemented by collectors that compute their statistics from
+// prog.Stats() readings alone, so that a Runner or Replay can drive them.
+type observer interface {
+	// begin resets the measurement window to start at at.
+	begin(at time.Time)
+	// observe processes one reading. During warmup it only moves the
+	// baselines deltas are computed against.
+	observe(at time.Time, stats *ebpf.ProgramStats, warmup bool) error
+	// finish ends the measurement window at at.
+	finish(at time.Time)
+}
+
+// pairObserver is implemented by collectors comparing two programs polled
+// together, see newPairRunner.
+type pairObserver interface {
+	begin(at time.Time)
+	// observePair processes the readings of both sources in one interval.
+	observePair(at time.Time, baseline, candidate *ebpf.ProgramStats, warmup bool) error
+	finish(at time.Time)
+}
+
+// Runner polls a stats source on a single ticker and feeds every reading to
+// a set of collectors, so they share the program handle, the sampling
+// instants and the warmup window. Each collector's own Start runs a Runner
+// with only that collector.
+type Runner struct {
+	sources    []StatsSource // one, or a baseline and a candidate polled together
+	interval   time.Duration
+	warmup     *time.Duration
+	collectors []Collector
+	observers  []observer
+	pair       pairObserver // observes both sources instead of observers
+	onPoll     PollFunc
+	integrity  *Integrity
+
+	// Lifecycle management
+	mu      sync.RWMutex
+	running bool
+	done    chan struct{}
+	errCh   chan error
+}
+
+// NewRunner returns a runner polling source every interval for collectors.
+// Readings within warmup of the start only establish baselines. The
+// collectors must not be started on their own.
+func NewRunner(source StatsSource, interval time.Duration, warmup *time.Duration, collectors ...Collector) (*Runner, error) {
+	observers, err := observersOf(collectors)
+	if err != nil {
+		return nil, err
+	}
+	r := newRunner(source, interval, warmup, observers...)
+	r.collectors = collectors
+	return r, nil
+}
+
+func newRunner(source StatsSource, interval time.Duration, warmup *time.Duration, observers ...observer) *Runner {
+	return &Runner{
+		sources:   []StatsSource{source},
+		interval:  interval,
+		warmup:    warmup,
+		observers: observers,
+		integrity: NewIntegrity(),
+		done:      make(chan struct{}),
+		errCh:     make(chan error, 1), // buffered to prevent goroutine leak
+	}
+}
+
+// newPairRunner returns a runner polling baseline and candidate back to back
+// every interval for p, so both readings cover the same interval.
+func newPairRunner(baseline, candidate StatsSource, interval time.Duration, warmup *time.Duration, p pairObserver) *Runner {
+	r := newRunner(baseline, interval, warmup)
+	r.sources = append(r.sources, candidate)
+	r.pair = p
+	return r
+}
+
+func observersOf(collectors []Collector) ([]observer, error) {
+	observers := make([]observer, 0, len(collectors))
+	for _, c := range collectors {
+		o, ok := c.(observer)
+		if !ok {
+			return nil, fmt.Errorf("collector %T does not support shared polling", c)
+		}
+		observers = append(observers, o)
+	}
+	return observers, nil
+}
+
+// PollFunc receives every reading a Runner fed to its collectors.
+type PollFunc func(at time.Time, stats *ebpf.ProgramStats, warmup bool)
+
+// OnPoll sets fn to be called from the polling goroutine after the
+// collectors observed a reading of a single source. It must be set before
+// Start and must not block, or it delays the next poll.
+func (r *Runner) OnPoll(fn PollFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onPoll = fn
+}
+
+// SetIntegrity makes the runner count its polls in in, e.g. to account for
+// several trials together. It must be called before Start.
+func (r *Runner) SetIntegrity(in *Integrity) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.integrity = in
+}
+
+// Integrity returns the failed polls and idle intervals so far.
+func (r *Runner) Integrity() *bpfsv1.Integrity {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.integrity.Summary()
+}
+
+// setSources replaces the sources before Start, one per source polled.
+func (r *Runner) setSources(srcs ...StatsSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = srcs
+}
+
+// Start polls until ctx is done, Stop is called or the source is exhausted.
+// Sources implementing io.Closer are closed when Start returns.
+func (r *Runner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return fmt.Errorf("collector already running")
+	}
+	r.running = true
+	sources, onPoll, integrity := r.sources, r.onPoll, r.integrity
+	r.mu.Unlock()
+
+	for _, src := range sources {
+		if c, ok := src.(io.Closer); ok {
+			defer c.Close()
+		}
+	}
+
+	integrity.rebase()
+	started := time.Now()
+	for _, o := range r.observers {
+		o.begin(started)
+	}
+	if r.pair != nil {
+		r.pair.begin(started)
+	}
+	defer func() {
+		ended := time.Now()
+		for _, o := range r.observers {
+			o.finish(ended)
+		}
+		if r.pair != nil {
+			r.pair.finish(ended)
+		}
+	}()
+
+	warmupEnd := started
+	if r.warmup != nil {
+		warmupEnd = started.Add(*r.warmup)
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for tick := 0; ; tick++ {
+		select {
+		case <-ctx.Done():
+			// Graceful shutdown pattern from net/http.Server
+			r.mu.Lock()
+			r.running = false
+			r.mu.Unlock()
+			return ctx.Err()
+
+		case <-r.done:
+			// Explicit Stop() called
+			return nil
+
+		case <-ticker.C:
+			readings, err := read(sources, tick)
+			if errors.Is(err, io.EOF) {
+				// Source exhausted, e.g. the end of a recording
+				r.mu.Lock()
+				r.running = false
+				r.mu.Unlock()
+				return nil
+			}
+			now := time.Now()
+			if err != nil {
+				// Non-fatal error handling inspired by Prometheus
+				integrity.Failed(now, err)
+				r.report(err)
+				continue
+			}
+
+			warmup := now.Before(warmupEnd)
+			if r.pair != nil {
+				integrity.Observed(now, total(readings), warmup)
+				if err := r.pair.observePair(now, readings[0], readings[1], warmup); err != nil {
+					integrity.CollectorFailed(now, err)
+					r.report(err)
+				}
+				continue
+			}
+
+			stats := readings[0]
+			integrity.Observed(now, stats, warmup)
+			for _, o := range r.observers {
+				if err := o.observe(now, stats, warmup); err != nil {
+					integrity.CollectorFailed(now, err)
+					r.report(err)
+				}
+			}
+			if onPoll != nil {
+				onPoll(now, stats, warmup)
+			}
+		}
+	}
+}
+
+// read polls every source back to back. Several sources are read in an
+// order alternating with tick, so none is systematically read later in the
+// interval than the others. A failed read fails the whole poll.
+func read(sources []StatsSource, tick int) ([]*ebpf.ProgramStats, error) {
+	readings := make([]*ebpf.ProgramStats, len(sources))
+	for n := range sources {
+		i := n
+		if tick%2 == 1 {
+			i = len(sources) - 1 - n
+		}
+		stats, err := sources[i].Stats()
+		if err != nil {
+			return nil, err
+		}
+		readings[i] = stats
+	}
+	return readings, nil
+}
+
+// total sums the counters of readings, so polls of several programs count
+// as idle only when none of them ran.
+func total(readings []*ebpf.ProgramStats) *ebpf.ProgramStats {
+	var sum ebpf.ProgramStats
+	for _, stats := range readings {
+		sum.Runtime += stats.Runtime
+		sum.RunCount += stats.RunCount
+		sum.RecursionMisses += stats.RecursionMisses
+	}
+	return &sum
+}
+
+// report keeps err for Err without blocking the polling loop. An error not
+// read yet is replaced, so Err returns the most recent one; all of them are
+// counted by the runner's Integrity.
+func (r *Runner) report(err error) {
+	for {
+		select {
+		case r.errCh <- err:
+			return
+		default:
+			select {
+			case <-r.errCh:
+			default:
+			}
+		}
+	}
+}
+
+// Stop gracefully stops the runner
+func (r *Runner) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.running {
+		return nil
+	}
+
+	r.running = false
+	close(r.done)
+
+	return nil
+}
+
+// Err returns the most recent polling error not read yet, reading it clears it.
+func (r *Runner) Err() error {
+	select {
+	case err := <-r.errCh:
+		return err
+	default:
+		return nil
+	}
+}
+
+// Collectors returns the collectors fed by the runner, in order.
+func (r *Runner) Collectors() []Collector {
+	return r.collectors
+}
+
+// Snapshots returns the statistics of every collector, in order, see
+// Snapshots.
+func (r *Runner) Snapshots() ([]bpfsv1.Parameter, error) {
+	r.mu.RLock()
+	integrity := r.integrity
+	r.mu.RUnlock()
+	return Snapshots(integrity, r.collectors...)
+}
+
+// Snapshots returns the statistics of collectors, in order. Collectors
+// without samples, e.g. the latency of an idle program, are left out and
+// recorded in in; only if none has samples does it fail.
+func Snapshots(in *Integrity, collectors ...Collector) ([]bpfsv1.Parameter, error) {
+	params := make([]bpfsv1.Parameter, 0, len(collectors))
+	var empty error
+	for _, c := range collectors {
+		snap, err := c.Snapshot()
+		var ns noSamplesError
+		if errors.As(err, &ns) {
+			in.NoSamples(ns.metric)
+			empty = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, snap)
+	}
+	if len(params) == 0 && empty != nil {
+		return nil, empty
+	}
+	return params, nil
+}
+
+// polled gives a collector a Runner of its own, so it can be started alone.
+type polled struct {
+	runner *Runner
+}
+
+// Start polls until ctx is done or Stop is called, see Runner.Start.
+func (p polled) Start(ctx context.Context) error {
+	return p.runner.Start(ctx)
+}
+
+// Stop ends polling.
+func (p polled) Stop() error {
+	return p.runner.Stop()
+}
+
+// Err returns the most recent polling error, see Runner.Err.
+func (p polled) Err() error {
+	return p.runner.Err()
+}
+
+// SetSource sets where the counters are polled from, by default the kernel.
+// It must be called before Start.
+func (p polled) SetSource(src StatsSource) {
+	p.runner.setSources(src)
+}
+
+// runSpan is the measurement window of a collector fed by a Runner or
+// Replay, from begin until finish, or until now while still polling.
+type runSpan struct {
+	spanMu  sync.Mutex
+	started time.Time
+	warmup  *time.Duration
+	ended   *time.Time
+}
+
+func (s *runSpan) begin(at time.Time) {
+	s.spanMu.Lock()
+	defer s.spanMu.Unlock()
+	s.started, s.ended = at, nil
+}
+
+// finish ends the window at at, unless it was ended already, e.g. by
+// Windowed.Finalize before polling stopped.
+func (s *runSpan) finish(at time.Time) {
+	s.spanMu.Lock()
+	defer s.spanMu.Unlock()
+	if s.ended == nil {
+		s.ended = &at
+	}
+}
+
+// restart opens a new window at at with the given warmup.
+func (s *runSpan) restart(at time.Time, warmup *time.Duration) {
+	s.spanMu.Lock()
+	defer s.spanMu.Unlock()
+	s.started, s.ended, s.warmup = at, nil, warmup
+}
+
+// bounds returns the start and end of the window and its length after
+// the warmup.
+func (s *runSpan) bounds() (started, ended time.Time, duration time.Duration) {
+	s.spanMu.Lock()
+	defer s.spanMu.Unlock()
+
+	ended = time.Now()
+	if s.ended != nil {
+		ended = *s.ended
+	}
+	duration = ended.Sub(s.started)
+	if s.warmup != nil {
+		duration = max(duration-*s.warmup, 0)
+	}
+	return s.started, ended, duration
+}
@@ -0,0 +1,250 @@
+package collector_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/Tjaarda1/bpfstats/internal/record"
+)
+
+const testInterval = 20 * time.Millisecond
+
+// workload is the simulated program every test measures: 1000 runs of 2µs
+// per interval, i.e. 50000 runs/s and 0.1 cores at testInterval.
+var workload = collector.SyntheticConfig{
+	RunsPerPoll:   1000,
+	Latency:       2 * time.Microsecond,
+	MissesPerPoll: 10,
+	Polls:         11,
+}
+
+// run drives the latency, cpu, health and throughput collectors from src
+// until it is exhausted and returns their snapshots by kind.
+func run(t *testing.T, src collector.StatsSource) map[string]bpfsv1.Parameter {
+	t.Helper()
+	collectors, err := collector.New([]string{"latency", "cpu", "health", "throughput"}, collector.Options{
+		ID:          1,
+		Interval:    testInterval,
+		Percentiles: []string{"p50", "p99"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner, err := collector.NewRunner(src, testInterval, nil, collectors...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	params, err := runner.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	byKind := make(map[string]bpfsv1.Parameter)
+	for _, p := range params {
+		byKind[p.Kind()] = p
+	}
+	if in := runner.Integrity(); in.Polls != uint64(workload.Polls) || in.FailedPolls != 0 {
+		t.Errorf("integrity: %d polls, %d failed, want %d and 0", in.Polls, in.FailedPolls, workload.Polls)
+	}
+	return byKind
+}
+
+// checkWorkload compares the snapshots against workload. Rates and cores
+// depend on the wall-clock intervals of the ticker, so they are checked
+// loosely.
+func checkWorkload(t *testing.T, params map[string]bpfsv1.Parameter) {
+	t.Helper()
+	intervals := uint64(workload.Polls - 1)
+
+	lat, ok := params["latency"].(bpfsv1.Latency)
+	if !ok {
+		t.Fatalf("no latency in %v", params)
+	}
+	if lat.Samples != intervals {
+		t.Errorf("latency samples = %d, want %d", lat.Samples, intervals)
+	}
+	if want := uint64(workload.Latency.Nanoseconds()); lat.Mean != want {
+		t.Errorf("latency mean = %dns, want %dns", lat.Mean, want)
+	}
+	if p99 := (*lat.Percentiles)["p99"]; p99 != uint64(workload.Latency.Nanoseconds()) {
+		t.Errorf("latency p99 = %dns, want %v", p99, workload.Latency)
+	}
+
+	health, ok := params["health"].(bpfsv1.Health)
+	if !ok {
+		t.Fatalf("no health in %v", params)
+	}
+	if want := intervals * workload.RunsPerPoll; health.Runs != want {
+		t.Errorf("health runs = %d, want %d", health.Runs, want)
+	}
+	wantMissed := float64(workload.MissesPerPoll) / float64(workload.RunsPerPoll+workload.MissesPerPoll)
+	if math.Abs(health.MissedFraction-wantMissed) > 1e-9 {
+		t.Errorf("missed fraction = %v, want %v", health.MissedFraction, wantMissed)
+	}
+
+	tp, ok := params["throughput"].(bpfsv1.Throughput)
+	if !ok {
+		t.Fatalf("no throughput in %v", params)
+	}
+	if tp.Samples != intervals {
+		t.Errorf("throughput samples = %d, want %d", tp.Samples, intervals)
+	}
+	wantRate := float64(workload.RunsPerPoll) / testInterval.Seconds()
+	if !near(tp.Mean, wantRate) {
+		t.Errorf("throughput = %.0f/s, want about %.0f/s", tp.Mean, wantRate)
+	}
+
+	cpu, ok := params["cpu"].(bpfsv1.Cpu)
+	if !ok {
+		t.Fatalf("no cpu in %v", params)
+	}
+	wantCores := float64(workload.RunsPerPoll) * float64(workload.Latency) / float64(testInterval)
+	if !near(cpu.Mean, wantCores) {
+		t.Errorf("cpu cores = %.3f, want about %.3f", cpu.Mean, wantCores)
+	}
+}
+
+// near reports whether got is within half of want, allowing for late ticks
+// on a loaded machine.
+func near(got, want float64) bool {
+	return math.Abs(got-want) <= want/2
+}
+
+func TestRunnerSynthetic(t *testing.T) {
+	checkWorkload(t, run(t, collector.NewSyntheticSource(workload)))
+}
+
+func TestRunnerReplay(t *testing.T) {
+	// Record the workload with the recorded interval between polls
+	var buf bytes.Buffer
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rw, err := record.NewWriter(&buf, record.Header{ID: 1, Interval: testInterval, Started: started})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := collector.NewSyntheticSource(workload)
+	at := started
+	for i := 0; i < workload.Polls; i++ {
+		at = at.Add(testInterval)
+		stats, err := src.Stats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rw.Observe(at, stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rw.Close(at); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := record.Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Truncated || len(rec.Observations) != workload.Polls {
+		t.Fatalf("read %d observations (truncated %v), want %d", len(rec.Observations), rec.Truncated, workload.Polls)
+	}
+	checkWorkload(t, run(t, rec.Source()))
+}
+
+func TestRunnerIdleProgram(t *testing.T) {
+	idle := workload
+	idle.RunsPerPoll, idle.MissesPerPoll = 0, 0
+
+	collectors, err := collector.New([]string{"latency", "cpu", "health", "throughput"}, collector.Options{ID: 1, Interval: testInterval})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner, err := collector.NewRunner(collector.NewSyntheticSource(idle), testInterval, nil, collectors...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// Health and throughput have data, latency and cpu have none
+	params, err := runner.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	var kinds []string
+	for _, p := range params {
+		kinds = append(kinds, p.Kind())
+	}
+	if len(kinds) != 2 || kinds[0] != "health" || kinds[1] != "throughput" {
+		t.Errorf("kinds = %v, want [health throughput]", kinds)
+	}
+	in := runner.Integrity()
+	if len(in.NoSamples) != 2 || in.NoSamples[0] != "latency" || in.NoSamples[1] != "cpu" {
+		t.Errorf("no samples = %v, want [latency cpu]", in.NoSamples)
+	}
+	if want := uint64(idle.Polls - 1); in.ZeroActivity != want {
+		t.Errorf("zero activity intervals = %d, want %d", in.ZeroActivity, want)
+	}
+}
+
+func TestCollectorSetSource(t *testing.T) {
+	// Started on their own, the collectors poll the source they were given
+	latC := collector.NewLatencyCollector(1, testInterval, nil, []string{"p50", "p99"})
+	cpuC := collector.NewCPUCollector(1, testInterval, nil)
+	healthC := collector.NewHealthCollector(1, testInterval, nil)
+	tpC := collector.NewThroughputCollector(1, testInterval, nil, nil)
+	latC.SetSource(collector.NewSyntheticSource(workload))
+	cpuC.SetSource(collector.NewSyntheticSource(workload))
+	healthC.SetSource(collector.NewSyntheticSource(workload))
+	tpC.SetSource(collector.NewSyntheticSource(workload))
+
+	params := make(map[string]bpfsv1.Parameter)
+	for _, c := range []collector.Collector{latC, cpuC, healthC, tpC} {
+		if err := c.Start(context.Background()); err != nil {
+			t.Fatalf("Start %T: %v", c, err)
+		}
+		p, err := c.Snapshot()
+		if err != nil {
+			t.Fatalf("Snapshot %T: %v", c, err)
+		}
+		params[p.Kind()] = p
+	}
+	checkWorkload(t, params)
+}
+
+func TestABCollector(t *testing.T) {
+	candidate := workload
+	candidate.Latency = 3 * time.Microsecond
+
+	abC := collector.NewABCollector(1, 2, testInterval, nil, []string{"p50"},
+		func(baseline, candidate []float64) (bpfsv1.PairedDifference, error) {
+			var d bpfsv1.PairedDifference
+			for i := range baseline {
+				d.Mean += (candidate[i] - baseline[i]) / float64(len(baseline))
+			}
+			return d, nil
+		})
+	abC.SetSources(collector.NewSyntheticSource(workload), collector.NewSyntheticSource(candidate))
+	if err := abC.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	snap, err := abC.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab := snap.(bpfsv1.ABTest)
+	if want := uint64(workload.Polls - 1); ab.Pairs != want || ab.Unpaired != 0 {
+		t.Errorf("%d pairs, %d unpaired, want %d and 0", ab.Pairs, ab.Unpaired, want)
+	}
+	if ab.Baseline.Mean != 2000 || ab.Candidate.Mean != 3000 || ab.Difference.Mean != 1000 {
+		t.Errorf("means %d and %d, difference %v, want 2000, 3000 and 1000",
+			ab.Baseline.Mean, ab.Candidate.Mean, ab.Difference.Mean)
+	}
+}
@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+// StatsSource provides the cumulative kernel statistics of a program, the
+// way prog.Stats() does. Collectors poll it once per interval. A source that
+// has nothing more to provide returns an error wrapping io.EOF, which ends
+// the collection.
+type StatsSource interface {
+	Stats() (*ebpf.ProgramStats, error)
+}
+
+// KernelSource reads the statistics of a loaded program from the kernel.
+// It requires kernel.bpf_stats_enabled and the privileges to open programs.
+// The program handle is opened on the first poll and kept until Close.
+type KernelSource struct {
+	id uint32
+
+	mu   sync.Mutex
+	prog *ebpf.Program
+}
+
+// NewKernelSource returns a source for the program with the given ID.
+func NewKernelSource(id uint32) *KernelSource {
+	return &KernelSource{id: id}
+}
+
+// Stats reads the program's statistics. After a failed read the handle is
+// dropped and reopened on the next poll.
+func (k *KernelSource) Stats() (*ebpf.ProgramStats, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.prog == nil {
+		prog, err := ebpf.NewProgramFromID(ebpf.ProgramID(k.id))
+		if err != nil {
+			return nil, fmt.Errorf("NewProgramFromID(%d): %w", k.id, err)
+		}
+		k.prog = prog
+	}
+
+	stats, err := k.prog.Stats()
+	if err != nil {
+		k.prog.Close()
+		k.prog = nil
+		return nil, fmt.Errorf("Stats(%d): %w", k.id, err)
+	}
+	return stats, nil
+}
+
+// Close releases the program handle.
+func (k *KernelSource) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.prog == nil {
+		return nil
+	}
+	err := k.prog.Close()
+	k.prog = nil
+	return err
+}
+
+// ObservationSource replays recorded observations in order, one per poll,
+// ignoring their timestamps. It is exhausted after the last one.
+type ObservationSource struct {
+	mu           sync.Mutex
+	observations []Observation
+	next         int
+}
+
+// NewObservationSource returns a source replaying observations.
+func NewObservationSource(observations []Observation) *ObservationSource {
+	return &ObservationSource{observations: observations}
+}
+
+// Stats returns the next recorded observation.
+func (o *ObservationSource) Stats() (*ebpf.ProgramStats, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.next >= len(o.observations) {
+		return nil, fmt.Errorf("recording exhausted: %w", io.EOF)
+	}
+	stats := o.observations[o.next].Stats
+	o.next++
+	return &stats, nil
+}
+
+// SyntheticConfig describes the workload of a SyntheticSource.
+type SyntheticConfig struct {
+	RunsPerPoll   uint64        // invocations between two polls
+	Latency       time.Duration // mean runtime per invocation
+	Jitter        time.Duration // runtime per invocation varies uniformly by ±Jitter between polls
+	MissesPerPoll uint64        // recursion misses between two polls
+	Polls         int           // polls before the source is exhausted, 0 => unlimited
+	Seed          uint64        // seed of the jitter, the same seed yields the same counters
+}
+
+// SyntheticSource generates the counters of a simulated program. The
+// sequence of counters depends only on the configuration, so runs using it
+// are reproducible and need neither privileges nor a loaded program.
+type SyntheticSource struct {
+	mu    sync.Mutex
+	cfg   SyntheticConfig
+	rng   *rand.Rand
+	polls int
+	stats ebpf.ProgramStats
+}
+
+// NewSyntheticSource returns a synthetic source for cfg.
+func NewSyntheticSource(cfg SyntheticConfig) *SyntheticSource {
+	return &SyntheticSource{
+		cfg: cfg,
+		rng: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15)),
+	}
+}
+
+// Stats advances the simulated program by one poll and returns its counters.
+func (s *SyntheticSource) Stats() (*ebpf.ProgramStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg.Polls > 0 && s.polls >= s.cfg.Polls {
+		return nil, fmt.Errorf("synthetic source exhausted after %d polls: %w", s.polls, io.EOF)
+	}
+	s.polls++
+
+	latency := s.cfg.Latency
+	if s.cfg.Jitter > 0 {
+		latency += time.Duration(s.rng.Int64N(2*int64(s.cfg.Jitter)+1)) - s.cfg.Jitter
+	}
+	latency = max(latency, 0)
+
+	s.stats.RunCount += s.cfg.RunsPerPoll
+	s.stats.Runtime += time.Duration(s.cfg.RunsPerPoll) * latency
+	s.stats.RecursionMisses += s.cfg.MissesPerPoll
+
+	stats := s.stats
+	return &stats, nil
+}
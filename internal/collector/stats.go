@@ -15,12 +15,38 @@
 
 package collector
 
-import "sync"
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxSamples bounds the observations a Stats keeps for percentiles and the
+// timeline. Beyond it a uniform random subset is kept (reservoir sampling),
+// so memory stays constant on long runs; count, mean, variance, min and max
+// remain exact.
+const MaxSamples = 1 << 16
 
 type Stats struct {
 	mux               sync.Mutex
 	count             uint64
 	min, max, mean, s float64
+
+	// samples keeps up to MaxSamples observations with their position, so
+	// that the distribution (histogram, CDF) and a downsampled timeline can
+	// be reconstructed.
+	samples []sample
+	rng     *rand.Rand // reservoir replacement, fixed seed for reproducibility
+}
+
+// sample is an observation and its position among all added ones.
+type sample struct {
+	seq uint64
+	val float64
 }
 
 func (s *Stats) Add(val float64) {
@@ -42,12 +68,39 @@ func (s *Stats) Add(val float64) {
 	oldMean := s.mean
 	s.mean += (val - oldMean) / float64(s.count)
 	s.s += (val - oldMean) * (val - s.mean)
+
+	if len(s.samples) < MaxSamples {
+		s.samples = append(s.samples, sample{s.count - 1, val})
+		return
+	}
+	if s.rng == nil {
+		s.rng = rand.New(rand.NewPCG(1, 2))
+	}
+	if j := s.rng.Uint64N(s.count); j < MaxSamples {
+		s.samples[j] = sample{s.count - 1, val}
+	}
 }
 
 func (s *Stats) Reset() {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 	s.count, s.min, s.max, s.mean, s.s = 0, 0, 0, 0, 0
+	s.samples, s.rng = nil, nil
+}
+
+// Samples returns a copy of the kept observations in the order they were
+// added: all of them, or a uniform subset of MaxSamples once more were added.
+func (s *Stats) Samples() []float64 {
+	s.mux.Lock()
+	kept := append([]sample(nil), s.samples...)
+	s.mux.Unlock()
+
+	sort.Slice(kept, func(i, j int) bool { return kept[i].seq < kept[j].seq })
+	out := make([]float64, len(kept))
+	for i, k := range kept {
+		out[i] = k.val
+	}
+	return out
 }
 
 func (s *Stats) Min() float64  { return s.min }
@@ -61,3 +114,48 @@ func (s *Stats) Variance() float64 {
 	}
 	return 0
 }
+
+// Percentiles returns the requested percentiles (0..100) of the observations
+// using linear interpolation between closest ranks.
+func (s *Stats) Percentiles(ps ...float64) []float64 {
+	sorted := s.Samples()
+	sort.Float64s(sorted)
+
+	out := make([]float64, len(ps))
+	if len(sorted) == 0 {
+		return out
+	}
+	for i, p := range ps {
+		rank := math.Max(0, math.Min(1, p/100)) * float64(len(sorted)-1)
+		lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+		out[i] = sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+	}
+	return out
+}
+
+// PercentileMap computes the percentiles for normalized keys such as "p50" or
+// "p99_9" and returns them keyed the same way.
+func (s *Stats) PercentileMap(keys []string) (map[string]float64, error) {
+	ps := make([]float64, len(keys))
+	for i, key := range keys {
+		p, err := PercentileFromKey(key)
+		if err != nil {
+			return nil, err
+		}
+		ps[i] = p
+	}
+	m := make(map[string]float64, len(keys))
+	for i, v := range s.Percentiles(ps...) {
+		m[keys[i]] = v
+	}
+	return m, nil
+}
+
+// PercentileFromKey parses a normalized percentile key, e.g. "p99_9" -> 99.9
+func PercentileFromKey(key string) (float64, error) {
+	p, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimPrefix(key, "p"), "_", "."), 64)
+	if err != nil || p < 0 || p > 100 {
+		return 0, fmt.Errorf("invalid percentile %q", key)
+	}
+	return p, nil
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/cilium/ebpf"
+)
+
+// ThroughputCollector reports the invocation rate of a program, one sample
+// per polling interval.
+type ThroughputCollector struct {
+	polled
+	runSpan
+
+	id          uint32
+	percentiles []string // keys to report, e.g. "p50", "p99_9"
+
+	mu sync.RWMutex
+	s  *Stats // invocations per second
+
+	// Previous observation, to compute per-interval deltas
+	lastCount uint64
+	lastTime  *time.Time
+}
+
+// NewThroughputCollector creates a new invocation rate collector
+func NewThroughputCollector(id uint32, interval time.Duration, warmup *time.Duration, percentiles []string) *ThroughputCollector {
+	tpC := &ThroughputCollector{
+		runSpan:     runSpan{warmup: warmup},
+		id:          id,
+		s:           &Stats{},
+		percentiles: percentiles,
+	}
+	tpC.runner = newRunner(NewKernelSource(id), interval, warmup, tpC)
+	return tpC
+}
+
+func init() {
+	Register(Registration{
+		Name: "throughput",
+		Kind: bpfsv1.Throughput{}.Kind(),
+		New: func(opts Options) Collector {
+			return NewThroughputCollector(opts.ID, opts.Interval, opts.Warmup, opts.Percentiles)
+		},
+	})
+}
+
+func (tpC *ThroughputCollector) observe(now time.Time, stats *ebpf.ProgramStats, warmup bool) error {
+	tpC.mu.Lock()
+	defer tpC.mu.Unlock()
+
+	// Warmup and first observation only establish the baseline
+	if tpC.lastTime == nil || warmup {
+		tpC.lastTime = &now
+		tpC.lastCount = stats.RunCount
+		return nil
+	}
+
+	dWall := now.Sub(*tpC.lastTime)
+	if dWall <= 0 || stats.RunCount < tpC.lastCount {
+		// Clock went backwards or the counter was reset: rebase
+		tpC.lastTime = &now
+		tpC.lastCount = stats.RunCount
+		return nil
+	}
+
+	// Idle intervals are recorded as zero throughput on purpose
+	tpC.s.Add(float64(stats.RunCount-tpC.lastCount) / dWall.Seconds())
+	tpC.lastTime = &now
+	tpC.lastCount = stats.RunCount
+	return nil
+}
+
+// Snapshot summarizes the invocation rate of the intervals so far.
+func (tpC *ThroughputCollector) Snapshot() (bpfsv1.Parameter, error) {
+	tpC.mu.RLock()
+	defer tpC.mu.RUnlock()
+
+	count := tpC.s.Count()
+	if count == 0 {
+		return nil, noSamples("throughput")
+	}
+
+	mean := tpC.s.Mean()
+	stddev := math.Sqrt(tpC.s.Variance())
+	min := tpC.s.Min()
+	max := tpC.s.Max()
+
+	// Coefficient of variation (undefined for an idle program)
+	var cv *float64
+	if mean > 0 {
+		v := stddev / mean
+		cv = &v
+	}
+
+	started, ended, duration := tpC.bounds()
+	// Sampling rate (undefined for an empty window)
+	var rate *float64
+	if duration > 0 {
+		r := float64(count) / duration.Seconds()
+		rate = &r
+	}
+
+	var percentiles *map[string]float64
+	if len(tpC.percentiles) > 0 {
+		m, err := tpC.s.PercentileMap(tpC.percentiles)
+		if err != nil {
+			return nil, err
+		}
+		percentiles = &m
+	}
+
+	throughput := bpfsv1.Throughput{
+		ID:       tpC.id,
+		Duration: duration,
+		Warmup:   tpC.warmup,
+		Started:  &started,
+		Ended:    &ended,
+
+		Samples: count,
+		Rate:    rate,
+
+		Mean:   mean,
+		StdDev: stddev,
+		CV:     cv,
+		Min:    &min,
+		Max:    &max,
+
+		Percentiles: percentiles,
+		Timeline:    tpC.s.Samples(),
+	}
+
+	return throughput, nil
+}
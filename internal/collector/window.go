@@ -0,0 +1,208 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+// Window modes
+const (
+	WindowSliding  = "sliding"  // the trailing span, moving with every sample
+	WindowTumbling = "tumbling" // the last complete span of consecutive, non-overlapping spans
+)
+
+// WindowSpec selects recent-window statistics reported besides the
+// statistics since the start of the measurement.
+type WindowSpec struct {
+	Span time.Duration
+	Mode string // WindowSliding or WindowTumbling
+}
+
+// Name is the key of the window in reports, e.g. "last_10s" or "tumbling_1m".
+func (ws WindowSpec) Name() string {
+	prefix := "last_"
+	if ws.Mode == WindowTumbling {
+		prefix = "tumbling_"
+	}
+	return prefix + shortDuration(ws.Span)
+}
+
+// ParseWindows parses window lengths such as "10s" or "1m" in the given mode.
+func ParseWindows(spans []string, mode string) ([]WindowSpec, error) {
+	if mode != WindowSliding && mode != WindowTumbling {
+		return nil, fmt.Errorf("unknown window mode %q, use %s or %s", mode, WindowSliding, WindowTumbling)
+	}
+	specs := make([]WindowSpec, 0, len(spans))
+	for _, s := range spans {
+		span, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", s, err)
+		}
+		if span <= 0 {
+			return nil, fmt.Errorf("invalid window %q: must be positive", s)
+		}
+		specs = append(specs, WindowSpec{Span: span, Mode: mode})
+	}
+	return specs, nil
+}
+
+// shortDuration formats d without zero trailing units, e.g. "1m" not "1m0s".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// window keeps the samples of one recent window. Memory is bounded by the
+// samples of one span (two for tumbling windows), however long the
+// measurement runs.
+type window struct {
+	spec WindowSpec
+
+	// Sliding: samples within the span; tumbling: samples of the open span
+	at     []time.Time
+	values []float64
+
+	// Tumbling: the open span and the last complete one
+	open        time.Time
+	opened      bool
+	closed      []float64
+	closedStart time.Time
+	hasClosed   bool
+}
+
+func newWindows(specs []WindowSpec) []*window {
+	windows := make([]*window, len(specs))
+	for i, spec := range specs {
+		windows[i] = &window{spec: spec}
+	}
+	return windows
+}
+
+// specsOf returns the specs of windows, e.g. to restart them empty.
+func specsOf(windows []*window) []WindowSpec {
+	specs := make([]WindowSpec, len(windows))
+	for i, w := range windows {
+		specs[i] = w.spec
+	}
+	return specs
+}
+
+// add records a sample taken at at.
+func (w *window) add(at time.Time, v float64) {
+	if w.spec.Mode == WindowTumbling {
+		if !w.opened {
+			w.open = at
+			w.opened = true
+		}
+		if elapsed := at.Sub(w.open); elapsed >= w.spec.Span {
+			spans := elapsed / w.spec.Span
+			w.closed, w.closedStart = w.values, w.open
+			if spans > 1 {
+				// Whole spans passed without samples
+				w.closed, w.closedStart = nil, w.open.Add((spans-1)*w.spec.Span)
+			}
+			w.hasClosed = true
+			w.open = w.open.Add(spans * w.spec.Span)
+			w.values = nil
+		}
+		w.values = append(w.values, v)
+		return
+	}
+
+	w.at = append(w.at, at)
+	w.values = append(w.values, v)
+	w.evict(at)
+}
+
+// evict drops sliding samples older than the span before now, compacting
+// the slices once most of their backing arrays is unused.
+func (w *window) evict(now time.Time) {
+	cutoff := now.Add(-w.spec.Span)
+	i := 0
+	for i < len(w.at) && !w.at[i].After(cutoff) {
+		i++
+	}
+	if i == 0 {
+		return
+	}
+	w.at, w.values = w.at[i:], w.values[i:]
+	if cap(w.at) > 2*len(w.at)+64 {
+		w.at = append([]time.Time(nil), w.at...)
+		w.values = append([]float64(nil), w.values...)
+	}
+}
+
+// samples returns the samples in the window as of now and its bounds.
+func (w *window) samples(now time.Time) ([]float64, time.Time, time.Time) {
+	if w.spec.Mode == WindowTumbling {
+		if !w.hasClosed {
+			return nil, time.Time{}, time.Time{}
+		}
+		return w.closed, w.closedStart, w.closedStart.Add(w.spec.Span)
+	}
+	cutoff := now.Add(-w.spec.Span)
+	i := 0
+	for i < len(w.at) && !w.at[i].After(cutoff) {
+		i++
+	}
+	return w.values[i:], cutoff, now
+}
+
+// stats returns the statistics of the window as of now.
+func (w *window) stats(now time.Time) *Stats {
+	values, _, _ := w.samples(now)
+	s := &Stats{}
+	for _, v := range values {
+		s.Add(v)
+	}
+	return s
+}
+
+// summarizeWindows reports every window as of now. Tumbling windows without
+// a complete span yet are omitted.
+func summarizeWindows(windows []*window, now time.Time, percentiles []string) (map[string]bpfsv1.WindowStats, error) {
+	if len(windows) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]bpfsv1.WindowStats, len(windows))
+	for _, w := range windows {
+		values, start, end := w.samples(now)
+		if w.spec.Mode == WindowTumbling && !w.hasClosed {
+			continue
+		}
+
+		ws := bpfsv1.WindowStats{
+			Mode:    w.spec.Mode,
+			Span:    w.spec.Span,
+			Start:   &start,
+			End:     &end,
+			Samples: uint64(len(values)),
+		}
+		if len(values) > 0 {
+			s := w.stats(now)
+			min, max := s.Min(), s.Max()
+			ws.Mean = s.Mean()
+			ws.StdDev = math.Sqrt(s.Variance())
+			ws.Min, ws.Max = &min, &max
+			if len(percentiles) > 0 {
+				m, err := s.PercentileMap(percentiles)
+				if err != nil {
+					return nil, err
+				}
+				ws.Percentiles = m
+			}
+		}
+		out[w.spec.Name()] = ws
+	}
+	return out, nil
+}
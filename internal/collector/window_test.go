@@ -0,0 +1,278 @@
+package collector
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/cilium/ebpf"
+)
+
+func TestWindowBoundaries(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+
+	tests := []struct {
+		name    string
+		mode    string
+		samples []int // seconds after t0, each sample's value is its second
+		now     int
+		count   uint64
+		mean    float64
+		start   int
+		end     int
+		omitted bool // no complete tumbling span yet
+	}{
+		{
+			// The sample at the cutoff (t0+10s) is outside the span
+			name:    "sliding excludes the cutoff",
+			mode:    WindowSliding,
+			samples: []int{5, 10, 11, 15, 20},
+			now:     20,
+			count:   3, mean: 46.0 / 3, start: 10, end: 20,
+		},
+		{
+			name:    "sliding evicts samples as time passes",
+			mode:    WindowSliding,
+			samples: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+			now:     25,
+			count:   5, mean: 18, start: 15, end: 25,
+		},
+		{
+			name:    "sliding without recent samples",
+			mode:    WindowSliding,
+			samples: []int{1, 2},
+			now:     30,
+			count:   0, start: 20, end: 30,
+		},
+		{
+			name:    "tumbling before the first span completes",
+			mode:    WindowTumbling,
+			samples: []int{1, 5, 10},
+			now:     10,
+			omitted: true,
+		},
+		{
+			// Spans start at the first sample: [1s, 11s) closes at 11s
+			name:    "tumbling reports the last complete span",
+			mode:    WindowTumbling,
+			samples: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+			now:     12,
+			count:   10, mean: 5.5, start: 1, end: 11,
+		},
+		{
+			name:    "tumbling moves to the next complete span",
+			mode:    WindowTumbling,
+			samples: []int{1, 4, 11, 14, 17, 21},
+			now:     21,
+			count:   3, mean: 14, start: 11, end: 21,
+		},
+		{
+			// [1s, 11s) and [11s, 21s) passed, [21s, 31s) was empty
+			name:    "tumbling spans without samples",
+			mode:    WindowTumbling,
+			samples: []int{1, 35},
+			now:     35,
+			count:   0, start: 21, end: 31,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := WindowSpec{Span: 10 * time.Second, Mode: tt.mode}
+			windows := newWindows([]WindowSpec{spec})
+			for _, s := range tt.samples {
+				windows[0].add(at(s), float64(s))
+			}
+
+			out, err := summarizeWindows(windows, at(tt.now), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ws, ok := out[spec.Name()]
+			if tt.omitted {
+				if ok {
+					t.Fatalf("window %s reported before a complete span: %+v", spec.Name(), ws)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("window %s not reported", spec.Name())
+			}
+			if ws.Samples != tt.count {
+				t.Errorf("samples = %d, want %d", ws.Samples, tt.count)
+			}
+			if ws.Mean != tt.mean {
+				t.Errorf("mean = %v, want %v", ws.Mean, tt.mean)
+			}
+			if !ws.Start.Equal(at(tt.start)) || !ws.End.Equal(at(tt.end)) {
+				t.Errorf("bounds = [%v, %v], want [%v, %v]", ws.Start, ws.End, at(tt.start), at(tt.end))
+			}
+		})
+	}
+}
+
+func TestLatencyWindows(t *testing.T) {
+	// One poll per second; the latency of interval i is i*100ns
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var observations []Observation
+	var stats ebpf.ProgramStats
+	for i := 0; i <= 30; i++ {
+		stats.RunCount += 10
+		stats.Runtime += time.Duration(i*100*10) * time.Nanosecond
+		observations = append(observations, Observation{At: t0.Add(time.Duration(i) * time.Second), Stats: stats})
+	}
+
+	latC := NewLatencyCollector(1, time.Second, nil, nil)
+	latC.SetWindows([]WindowSpec{
+		{Span: 10 * time.Second, Mode: WindowSliding},
+		{Span: 10 * time.Second, Mode: WindowTumbling},
+	})
+	if err := Replay(t0, t0.Add(30*time.Second), nil, observations, latC); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := latC.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lat := snap.(bpfsv1.Latency)
+
+	// Intervals 21..30 end after the cutoff at 20s
+	if w := lat.Windows["last_10s"]; w.Samples != 10 || w.Mean != 2550 {
+		t.Errorf("last_10s: %d samples with mean %v, want 10 with mean 2550", w.Samples, w.Mean)
+	}
+	// Spans start at the first sample (1s): [11s, 21s) is the last complete one
+	if w := lat.Windows["tumbling_10s"]; w.Samples != 10 || w.Mean != 1550 {
+		t.Errorf("tumbling_10s: %d samples with mean %v, want 10 with mean 1550", w.Samples, w.Mean)
+	}
+	if lat.Samples != 30 {
+		t.Errorf("samples = %d, want 30", lat.Samples)
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	specs, err := ParseWindows([]string{"10s", " 1m "}, WindowTumbling)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range specs {
+		names = append(names, s.Name())
+	}
+	if len(names) != 2 || names[0] != "tumbling_10s" || names[1] != "tumbling_1m" {
+		t.Errorf("names = %v, want [tumbling_10s tumbling_1m]", names)
+	}
+
+	for _, bad := range []struct {
+		spans []string
+		mode  string
+	}{
+		{[]string{"10s"}, "hopping"},
+		{[]string{"ten"}, WindowSliding},
+		{[]string{"0s"}, WindowSliding},
+	} {
+		if _, err := ParseWindows(bad.spans, bad.mode); err == nil {
+			t.Errorf("ParseWindows(%v, %q) succeeded, want an error", bad.spans, bad.mode)
+		}
+	}
+}
+
+// readings returns one reading per second from t0 for the given seconds,
+// starting from stats, each interval adding 10 runs of latency ns.
+func readings(t0 time.Time, stats ebpf.ProgramStats, seconds int, latency time.Duration) ([]Observation, ebpf.ProgramStats) {
+	var observations []Observation
+	for i := 0; i <= seconds; i++ {
+		if i > 0 {
+			stats.RunCount += 10
+			stats.Runtime += 10 * latency
+		}
+		observations = append(observations, Observation{At: t0.Add(time.Duration(i) * time.Second), Stats: stats})
+	}
+	return observations, stats
+}
+
+func TestWindowedResetAndFinalize(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	latC := NewLatencyCollector(1, time.Second, nil, nil)
+	cpuC := NewCPUCollector(1, time.Second, nil)
+	snapshots := func() (bpfsv1.Latency, bpfsv1.Cpu) {
+		t.Helper()
+		lat, err := latC.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cpu, err := cpuC.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lat.(bpfsv1.Latency), cpu.(bpfsv1.Cpu)
+	}
+
+	// First measurement: 10 intervals of 100ns
+	first, stats := readings(t0, ebpf.ProgramStats{}, 10, 100)
+	if err := Replay(t0, t0.Add(10*time.Second), nil, first, latC, cpuC); err != nil {
+		t.Fatal(err)
+	}
+	latC.Finalize()
+	cpuC.Finalize()
+
+	// Finalized collectors ignore later observations
+	t1 := t0.Add(20 * time.Second)
+	ignored, _ := readings(t1, stats, 10, 900)
+	if err := Replay(t1, t1.Add(10*time.Second), nil, ignored, latC, cpuC); err != nil {
+		t.Fatal(err)
+	}
+	if lat, cpu := snapshots(); lat.Samples != 10 || lat.Mean != 100 || cpu.Samples != 10 {
+		t.Errorf("after Finalize: %d latency samples with mean %dns, %d cpu samples, want 10, 100ns and 10",
+			lat.Samples, lat.Mean, cpu.Samples)
+	}
+
+	// After Reset, the first reading only sets the baseline: no sample
+	// spans the pause since the first measurement
+	latC.Reset(0, nil)
+	cpuC.Reset(0, nil)
+	second, _ := readings(t1, ebpf.ProgramStats{RunCount: 5000, Runtime: time.Millisecond}, 5, 300)
+	if err := Replay(t1, t1.Add(5*time.Second), nil, second, latC, cpuC); err != nil {
+		t.Fatal(err)
+	}
+	lat, cpu := snapshots()
+	if lat.Samples != 5 || lat.Mean != 300 || lat.Duration != 5*time.Second {
+		t.Errorf("after Reset: %d latency samples with mean %dns over %v, want 5 with mean 300ns over 5s",
+			lat.Samples, lat.Mean, lat.Duration)
+	}
+	if want := 3e-6; cpu.Samples != 5 || math.Abs(cpu.Mean-want) > 1e-12 {
+		t.Errorf("after Reset: %d cpu samples with mean %v cores, want 5 with mean %v", cpu.Samples, cpu.Mean, want)
+	}
+}
+
+func TestWindowedTrailingSpan(t *testing.T) {
+	// The latency of interval i is i*100ns
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var observations []Observation
+	var stats ebpf.ProgramStats
+	for i := 0; i <= 30; i++ {
+		stats.RunCount += 10
+		stats.Runtime += time.Duration(i*100*10) * time.Nanosecond
+		observations = append(observations, Observation{At: t0.Add(time.Duration(i) * time.Second), Stats: stats})
+	}
+
+	latC := NewLatencyCollector(1, time.Second, nil, nil)
+	latC.Reset(5*time.Second, nil)
+	if err := Replay(t0, t0.Add(30*time.Second), nil, observations, latC); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := latC.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lat := snap.(bpfsv1.Latency)
+
+	// Intervals 26..30 end within the last 5s
+	if lat.Samples != 5 || lat.Mean != 2800 || lat.Duration != 5*time.Second {
+		t.Errorf("%d samples with mean %dns over %v, want 5 with mean 2800ns over 5s", lat.Samples, lat.Mean, lat.Duration)
+	}
+	if len(lat.Timeline) != 5 {
+		t.Errorf("timeline of %d samples, want the 5 of the span", len(lat.Timeline))
+	}
+}
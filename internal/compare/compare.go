@@ -0,0 +1,352 @@
+// Package compare contrasts the sample distributions of two sets of results,
+// e.g. a program before and after an optimisation, in the spirit of
+// benchstat: deltas in mean and percentiles with bootstrap confidence
+// intervals, and a significance test on the whole distribution.
+package compare
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+)
+
+// Significance tests.
+const (
+	TestMannWhitney = "mann-whitney"
+	TestWelch       = "welch"
+	TestBootstrap   = "bootstrap"
+)
+
+// Tests returns the supported significance tests.
+func Tests() []string {
+	return []string{TestMannWhitney, TestWelch, TestBootstrap}
+}
+
+// Options configure a comparison.
+type Options struct {
+	Test       string  // one of Tests()
+	Alpha      float64 // significance level, e.g. 0.05
+	Confidence float64 // level of the delta confidence intervals, e.g. 0.95
+	Resamples  int     // bootstrap resamples
+
+	// Percentile keys to compare besides the mean, e.g. "p50", "p99_9"
+	Percentiles []string
+}
+
+// Source is a named set of results, e.g. the parameters read from a file.
+type Source struct {
+	Name   string
+	Params []bpfsv1.Parameter
+}
+
+// series is the raw distribution of one metric of a parameter, along with
+// its recorded summary for results written without timelines.
+type series struct {
+	parameter string
+	metric    string
+	unit      string
+	samples   []float64
+
+	count   int                // samples behind the summary
+	summary map[string]float64 // "mean" and percentile keys, e.g. "p99"
+}
+
+// Compare matches the parameters of both sources by kind and order of
+// appearance and compares every metric that carries samples (timelines).
+// Metrics without samples on either side, e.g. results written without
+// --timeline, fall back to the deltas of their recorded summaries, without
+// confidence intervals or a significance test. Results of repeated trials are
+// compared over the samples of all trials.
+func Compare(baseline, candidate Source, opts Options) (bpfsv1.Comparison, error) {
+	stats, err := statistics(opts.Percentiles)
+	if err != nil {
+		return bpfsv1.Comparison{}, err
+	}
+	switch opts.Test {
+	case TestMannWhitney, TestWelch, TestBootstrap:
+	default:
+		return bpfsv1.Comparison{}, fmt.Errorf("unknown test %q, use one of %v", opts.Test, Tests())
+	}
+
+	cmp := bpfsv1.Comparison{
+		Baseline:   baseline.Name,
+		Candidate:  candidate.Name,
+		Test:       opts.Test,
+		Alpha:      opts.Alpha,
+		Confidence: opts.Confidence,
+		Resamples:  opts.Resamples,
+	}
+
+	candidates := byKind(pooled(candidate.Params))
+	seen := make(map[string]int)
+	for _, bp := range pooled(baseline.Params) {
+		i := seen[bp.Kind()]
+		seen[bp.Kind()]++
+		if i >= len(candidates[bp.Kind()]) {
+			continue
+		}
+		cp := candidates[bp.Kind()][i]
+
+		cseries := make(map[string]series)
+		for _, s := range seriesOf(cp) {
+			cseries[s.metric] = s
+		}
+		for _, bs := range seriesOf(bp) {
+			cs, ok := cseries[bs.metric]
+			switch {
+			case !ok:
+			case len(bs.samples) > 0 && len(cs.samples) > 0:
+				cmp.Metrics = append(cmp.Metrics, compareSeries(bs, cs, stats, opts))
+			case len(bs.summary) > 0 && len(cs.summary) > 0:
+				cmp.Metrics = append(cmp.Metrics, compareSummaries(bs, cs, stats))
+			}
+		}
+	}
+
+	if len(cmp.Metrics) == 0 {
+		return bpfsv1.Comparison{}, fmt.Errorf("%s and %s have no comparable metrics (latency, throughput, bench or profile results)", baseline.Name, candidate.Name)
+	}
+	return cmp, nil
+}
+
+func compareSeries(base, cand series, stats []statistic, opts Options) bpfsv1.MetricComparison {
+	a := sorted(base.samples)
+	b := sorted(cand.samples)
+
+	deltas := newBootstrap(a, b, opts.Resamples).deltas(stats)
+	tail := (1 - opts.Confidence) / 2
+
+	mc := bpfsv1.MetricComparison{
+		Parameter:        base.parameter,
+		Metric:           base.metric,
+		Unit:             base.unit,
+		BaselineSamples:  len(a),
+		CandidateSamples: len(b),
+	}
+	for i, st := range stats {
+		bv, cv := st.eval(a), st.eval(b)
+		d := bpfsv1.StatisticDelta{
+			Name:      st.name,
+			Baseline:  bv,
+			Candidate: cv,
+			Delta:     cv - bv,
+			CILow:     quantile(deltas[i], tail),
+			CIHigh:    quantile(deltas[i], 1-tail),
+		}
+		if bv != 0 {
+			pct := (cv - bv) / math.Abs(bv) * 100
+			d.DeltaPct = &pct
+		}
+		mc.Statistics = append(mc.Statistics, d)
+	}
+
+	switch opts.Test {
+	case TestWelch:
+		mc.PValue = welchTest(a, b)
+	case TestBootstrap:
+		mc.PValue = 1 // resampling a single sample shows no variability
+		if len(a) > 1 && len(b) > 1 {
+			mc.PValue = bootstrapPValue(deltas[0]) // difference in means
+		}
+	default:
+		mc.PValue = mannWhitneyTest(a, b)
+	}
+	mc.Significant = mc.PValue < opts.Alpha
+	return mc
+}
+
+// compareSummaries compares the recorded mean and percentiles of two metrics
+// without samples. Without the distributions there is nothing to resample or
+// test, so the intervals collapse onto the delta and the change is never
+// reported as significant.
+func compareSummaries(base, cand series, stats []statistic) bpfsv1.MetricComparison {
+	mc := bpfsv1.MetricComparison{
+		Parameter:        base.parameter,
+		Metric:           base.metric,
+		Unit:             base.unit,
+		BaselineSamples:  base.count,
+		CandidateSamples: cand.count,
+		PValue:           1,
+		SummaryOnly:      true,
+	}
+	for _, st := range stats {
+		bv, okb := base.summary[st.name]
+		cv, okc := cand.summary[st.name]
+		if !okb || !okc {
+			continue
+		}
+		d := bpfsv1.StatisticDelta{
+			Name:      st.name,
+			Baseline:  bv,
+			Candidate: cv,
+			Delta:     cv - bv,
+			CILow:     cv - bv,
+			CIHigh:    cv - bv,
+		}
+		if bv != 0 {
+			pct := (cv - bv) / math.Abs(bv) * 100
+			d.DeltaPct = &pct
+		}
+		mc.Statistics = append(mc.Statistics, d)
+	}
+	return mc
+}
+
+// statistics returns the mean followed by the requested percentiles.
+func statistics(keys []string) ([]statistic, error) {
+	stats := []statistic{meanStatistic()}
+	for _, key := range keys {
+		p, err := collector.PercentileFromKey(key)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, percentileStatistic(key, p))
+	}
+	return stats, nil
+}
+
+// pooled merges the per-trial parameters of results of repeated trials, one
+// of each kind per trial followed by the trials summary, into one parameter
+// of each kind holding the timelines of all trials and the pooled summary
+// of the trials. Other results are returned as they are.
+func pooled(params []bpfsv1.Parameter) []bpfsv1.Parameter {
+	i := slices.IndexFunc(params, isTrials)
+	if i < 0 {
+		return params
+	}
+	trials := params[i].(bpfsv1.Trials)
+
+	out := make([]bpfsv1.Parameter, 0, len(params))
+	first := make(map[string]int) // index in out of the first trial's parameter
+	for _, p := range params {
+		i, ok := first[p.Kind()]
+		if !ok {
+			first[p.Kind()] = len(out)
+			out = append(out, p)
+			continue
+		}
+		switch t := p.(type) {
+		case bpfsv1.Latency:
+			merged := out[i].(bpfsv1.Latency)
+			merged.Timeline = append(slices.Clip(merged.Timeline), t.Timeline...)
+			merged.Samples += t.Samples
+			out[i] = merged
+		case bpfsv1.Throughput:
+			merged := out[i].(bpfsv1.Throughput)
+			merged.Timeline = append(slices.Clip(merged.Timeline), t.Timeline...)
+			merged.Samples += t.Samples
+			out[i] = merged
+		}
+	}
+	for i, p := range out {
+		out[i] = pooledSummary(p, trials)
+	}
+	return out
+}
+
+// pooledSummary replaces the summary of the first trial's latency or
+// throughput with the pooled metrics of all trials.
+func pooledSummary(p bpfsv1.Parameter, trials bpfsv1.Trials) bpfsv1.Parameter {
+	switch t := p.(type) {
+	case bpfsv1.Latency:
+		if m, ok := trials.Metrics["latency_mean_ns"]; ok {
+			t.Mean = uint64(math.Round(m.Pooled))
+		}
+		percentiles := make(map[string]uint64)
+		for name, m := range trials.Metrics {
+			if strings.HasPrefix(name, "latency_p") && strings.HasSuffix(name, "_ns") {
+				key := strings.TrimSuffix(strings.TrimPrefix(name, "latency_"), "_ns")
+				percentiles[key] = uint64(math.Round(m.Pooled))
+			}
+		}
+		t.Percentiles = &percentiles
+		return t
+	case bpfsv1.Throughput:
+		if m, ok := trials.Metrics["throughput_mean_per_sec"]; ok {
+			t.Mean = m.Pooled
+		}
+		t.Percentiles = nil // trials keep the mean only
+		return t
+	}
+	return p
+}
+
+func isTrials(p bpfsv1.Parameter) bool {
+	_, ok := p.(bpfsv1.Trials)
+	return ok
+}
+
+func byKind(params []bpfsv1.Parameter) map[string][]bpfsv1.Parameter {
+	m := make(map[string][]bpfsv1.Parameter)
+	for _, p := range params {
+		m[p.Kind()] = append(m[p.Kind()], p)
+	}
+	return m
+}
+
+// seriesOf returns the comparable metrics of a parameter.
+func seriesOf(p bpfsv1.Parameter) []series {
+	switch t := p.(type) {
+	case bpfsv1.Latency:
+		return []series{{
+			parameter: "latency", metric: "latency", unit: "ns", samples: floats(t.Timeline),
+			count: int(t.Samples), summary: summary(float64(t.Mean), t.Percentiles),
+		}}
+	case bpfsv1.Throughput:
+		return []series{{
+			parameter: "throughput", metric: "throughput", unit: "per_sec", samples: t.Timeline,
+			count: int(t.Samples), summary: summary(t.Mean, t.Percentiles),
+		}}
+	case bpfsv1.Bench:
+		return []series{{
+			parameter: "bench", metric: "bench", unit: "ns", samples: floats(t.Timeline),
+			count: int(t.Samples), summary: summary(float64(t.Mean), t.Percentiles),
+		}}
+	case bpfsv1.Profile:
+		names := make([]string, 0, len(t.Metrics))
+		for name := range t.Metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		out := make([]series, 0, len(names))
+		for _, name := range names {
+			m := t.Metrics[name]
+			out = append(out, series{
+				parameter: "profile", metric: name, samples: m.Timeline,
+				count: int(t.Samples), summary: summary(m.Mean, m.Percentiles),
+			})
+		}
+		return out
+	}
+	return nil
+}
+
+// summary returns the recorded mean and percentiles keyed like statistics.
+func summary[T uint64 | float64](mean float64, percentiles *map[string]T) map[string]float64 {
+	out := map[string]float64{"mean": mean}
+	if percentiles != nil {
+		for k, v := range *percentiles {
+			out[k] = float64(v)
+		}
+	}
+	return out
+}
+
+func floats(v []uint64) []float64 {
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = float64(x)
+	}
+	return out
+}
+
+func sorted(v []float64) []float64 {
+	out := append([]float64(nil), v...)
+	sort.Float64s(out)
+	return out
+}
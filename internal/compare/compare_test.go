@@ -0,0 +1,89 @@
+package compare
+
+import (
+	"testing"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+func TestCompareWithoutTimelines(t *testing.T) {
+	latency := func(mean, p99 uint64) bpfsv1.Latency {
+		return bpfsv1.Latency{Samples: 10, Mean: mean, Percentiles: &map[string]uint64{"p99": p99}}
+	}
+	opts := Options{Test: TestMannWhitney, Alpha: 0.05, Confidence: 0.95, Resamples: 100, Percentiles: []string{"p99"}}
+
+	cmp, err := Compare(
+		Source{Name: "base", Params: []bpfsv1.Parameter{latency(100, 200)}},
+		Source{Name: "new", Params: []bpfsv1.Parameter{latency(150, 180)}},
+		opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmp.Metrics) != 1 {
+		t.Fatalf("%d metrics, want 1", len(cmp.Metrics))
+	}
+	mc := cmp.Metrics[0]
+	if !mc.SummaryOnly || mc.Significant {
+		t.Errorf("summary only %v, significant %v, want true and false", mc.SummaryOnly, mc.Significant)
+	}
+	if mc.BaselineSamples != 10 || mc.CandidateSamples != 10 {
+		t.Errorf("samples %d+%d, want 10+10", mc.BaselineSamples, mc.CandidateSamples)
+	}
+	want := map[string]float64{"mean": 50, "p99": -20}
+	if len(mc.Statistics) != len(want) {
+		t.Fatalf("statistics = %+v, want mean and p99", mc.Statistics)
+	}
+	for _, s := range mc.Statistics {
+		if s.Delta != want[s.Name] || s.CILow != s.Delta || s.CIHigh != s.Delta {
+			t.Errorf("%s: delta %v in [%v, %v], want %v", s.Name, s.Delta, s.CILow, s.CIHigh, want[s.Name])
+		}
+	}
+}
+
+func TestCompareTrialsWithoutTimelines(t *testing.T) {
+	// Two trials; the pooled metrics, not the first trial, are compared
+	trials := func(mean, p99 float64) []bpfsv1.Parameter {
+		return []bpfsv1.Parameter{
+			bpfsv1.Latency{Samples: 5, Mean: 1, Percentiles: &map[string]uint64{"p99": 1}},
+			bpfsv1.Latency{Samples: 5, Mean: 2, Percentiles: &map[string]uint64{"p99": 2}},
+			bpfsv1.Trials{Trials: 2, Metrics: map[string]bpfsv1.TrialMetric{
+				"latency_mean_ns": {Pooled: mean},
+				"latency_p99_ns":  {Pooled: p99},
+			}},
+		}
+	}
+	opts := Options{Test: TestWelch, Alpha: 0.05, Confidence: 0.95, Resamples: 100, Percentiles: []string{"p99"}}
+
+	cmp, err := Compare(Source{Name: "base", Params: trials(100, 300)}, Source{Name: "new", Params: trials(120, 330)}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := cmp.Metrics[0]
+	if mc.BaselineSamples != 10 {
+		t.Errorf("baseline samples = %d, want 10 over both trials", mc.BaselineSamples)
+	}
+	for _, s := range mc.Statistics {
+		if want := map[string]float64{"mean": 100, "p99": 300}[s.Name]; s.Baseline != want {
+			t.Errorf("%s baseline = %v, want pooled %v", s.Name, s.Baseline, want)
+		}
+	}
+}
+
+func TestCompareWithTimelines(t *testing.T) {
+	base := bpfsv1.Latency{Samples: 5, Mean: 3, Timeline: []uint64{1, 2, 3, 4, 5}}
+	cand := bpfsv1.Latency{Samples: 5, Mean: 8, Timeline: []uint64{6, 7, 8, 9, 10}}
+	opts := Options{Test: TestMannWhitney, Alpha: 0.05, Confidence: 0.95, Resamples: 100}
+
+	cmp, err := Compare(Source{Params: []bpfsv1.Parameter{base}}, Source{Params: []bpfsv1.Parameter{cand}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mc := cmp.Metrics[0]; mc.SummaryOnly || !mc.Significant {
+		t.Errorf("summary only %v, significant %v, want false and true", mc.SummaryOnly, mc.Significant)
+	}
+
+	// Nothing to compare without matching parameters
+	if _, err := Compare(Source{Params: []bpfsv1.Parameter{base}}, Source{}, opts); err == nil {
+		t.Error("Compare without candidate parameters succeeded, want an error")
+	}
+}
@@ -0,0 +1,60 @@
+package compare
+
+import "math"
+
+// MeanInterval returns the Student's t confidence interval for the mean of v,
+// e.g. of a metric's value across repeated trials. With fewer than two
+// values the interval collapses to the mean.
+func MeanInterval(v []float64, confidence float64) (low, high float64) {
+	mean, variance := meanVar(v)
+	if len(v) < 2 {
+		return mean, mean
+	}
+	n := float64(len(v))
+	half := tQuantile((1+confidence)/2, n-1) * math.Sqrt(variance/n)
+	return mean - half, mean + half
+}
+
+// BetweenShare estimates the fraction of the total variance of grouped
+// samples that is due to differences between the groups (the intraclass
+// correlation of a one-way random effects ANOVA). For repeated trials it
+// tells how much a single run's result depends on the run itself, e.g. on
+// JIT layout or cache state. ok is false with fewer than two groups or no
+// within-group replication.
+func BetweenShare(groups [][]float64) (share float64, ok bool) {
+	k := len(groups)
+	var total int
+	var sum, sumSq float64
+	for _, g := range groups {
+		total += len(g)
+		sumSq += float64(len(g) * len(g))
+		for _, x := range g {
+			sum += x
+		}
+	}
+	if k < 2 || total <= k {
+		return 0, false
+	}
+	grand := sum / float64(total)
+
+	var ssb, ssw float64
+	for _, g := range groups {
+		if len(g) == 0 {
+			continue
+		}
+		m, _ := meanVar(g)
+		ssb += float64(len(g)) * (m - grand) * (m - grand)
+		for _, x := range g {
+			ssw += (x - m) * (x - m)
+		}
+	}
+	msb := ssb / float64(k-1)
+	msw := ssw / float64(total-k)
+	n0 := (float64(total) - sumSq/float64(total)) / float64(k-1)
+
+	between := math.Max(0, (msb-msw)/n0)
+	if between+msw == 0 {
+		return 0, true
+	}
+	return between / (between + msw), true
+}
@@ -0,0 +1,149 @@
+package compare
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+// Paired significance tests, for samples taken in the same intervals.
+const (
+	TestWilcoxon = "wilcoxon"
+	TestPairedT  = "paired-t"
+)
+
+// PairedTests returns the supported paired significance tests.
+func PairedTests() []string {
+	return []string{TestWilcoxon, TestPairedT}
+}
+
+// Paired summarizes the differences candidate[i] - baseline[i] of aligned
+// samples. The interval is a bootstrap interval for the mean difference;
+// opts.Percentiles is ignored.
+func Paired(baseline, candidate []float64, opts Options) (bpfsv1.PairedDifference, error) {
+	switch opts.Test {
+	case TestWilcoxon, TestPairedT:
+	default:
+		return bpfsv1.PairedDifference{}, fmt.Errorf("unknown paired test %q, use one of %v", opts.Test, PairedTests())
+	}
+	if len(baseline) != len(candidate) {
+		return bpfsv1.PairedDifference{}, fmt.Errorf("paired samples differ in length: %d and %d", len(baseline), len(candidate))
+	}
+
+	pd := bpfsv1.PairedDifference{
+		Test:       opts.Test,
+		Alpha:      opts.Alpha,
+		Confidence: opts.Confidence,
+		PValue:     1,
+	}
+	if len(baseline) == 0 {
+		return pd, nil
+	}
+
+	d := make([]float64, len(baseline))
+	var positive int
+	for i := range baseline {
+		d[i] = candidate[i] - baseline[i]
+		if d[i] > 0 {
+			positive++
+		}
+	}
+
+	mean, variance := meanVar(d)
+	pd.Mean = mean
+	pd.StdDev = math.Sqrt(variance)
+	pd.Median = quantile(sorted(d), 0.5)
+	pd.Positive = float64(positive) / float64(len(d))
+	if bmean, _ := meanVar(baseline); bmean != 0 {
+		pct := mean / math.Abs(bmean) * 100
+		pd.MeanPct = &pct
+	}
+
+	means := newBootstrap(d, nil, opts.Resamples).means()
+	tail := (1 - opts.Confidence) / 2
+	pd.CILow = quantile(means, tail)
+	pd.CIHigh = quantile(means, 1-tail)
+
+	switch opts.Test {
+	case TestPairedT:
+		pd.PValue = pairedTTest(d)
+	default:
+		pd.PValue = wilcoxonSignedRankTest(d)
+	}
+	pd.Significant = pd.PValue < opts.Alpha
+	return pd, nil
+}
+
+// pairedTTest returns the two-sided p-value of the one-sample t-test that
+// the mean difference is zero.
+func pairedTTest(d []float64) float64 {
+	n := float64(len(d))
+	if n < 2 {
+		return 1
+	}
+	mean, variance := meanVar(d)
+	if variance == 0 {
+		if mean == 0 {
+			return 1
+		}
+		return 0
+	}
+	t := mean / math.Sqrt(variance/n)
+	df := n - 1
+	return regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// wilcoxonSignedRankTest returns the two-sided p-value of the Wilcoxon
+// signed-rank test, using the normal approximation with tie and continuity
+// corrections. Zero differences are dropped.
+func wilcoxonSignedRankTest(d []float64) float64 {
+	abs := make([]float64, 0, len(d))
+	neg := make(map[int]bool)
+	for _, v := range d {
+		if v == 0 {
+			continue
+		}
+		if v < 0 {
+			neg[len(abs)] = true
+		}
+		abs = append(abs, math.Abs(v))
+	}
+	if len(abs) == 0 {
+		return 1
+	}
+
+	idx := make([]int, len(abs))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return abs[idx[i]] < abs[idx[j]] })
+
+	// Rank with ties averaged, accumulating the tie correction term
+	var wPlus, ties float64
+	for i := 0; i < len(idx); {
+		j := i
+		for j < len(idx) && abs[idx[j]] == abs[idx[i]] {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if !neg[idx[k]] {
+				wPlus += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	n := float64(len(abs))
+	mu := n * (n + 1) / 4
+	sigma := math.Sqrt(n*(n+1)*(2*n+1)/24 - ties/48)
+	if sigma == 0 {
+		return 1
+	}
+	z := math.Max(0, math.Abs(wPlus-mu)-0.5) / sigma
+	return math.Erfc(z / math.Sqrt2)
+}
@@ -0,0 +1,280 @@
+package compare
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// welchTest returns the two-sided p-value of Welch's unequal-variances t-test
+// for a difference in means.
+func welchTest(a, b []float64) float64 {
+	na, nb := float64(len(a)), float64(len(b))
+	if na < 2 || nb < 2 {
+		return 1
+	}
+	ma, va := meanVar(a)
+	mb, vb := meanVar(b)
+	se2 := va/na + vb/nb
+	if se2 == 0 {
+		if ma == mb {
+			return 1
+		}
+		return 0
+	}
+	t := (ma - mb) / math.Sqrt(se2)
+	df := se2 * se2 / ((va/na)*(va/na)/(na-1) + (vb/nb)*(vb/nb)/(nb-1))
+	// P(|T| > t) for Student's t with df degrees of freedom
+	return regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// mannWhitneyTest returns the two-sided p-value of the Mann-Whitney U test,
+// using the normal approximation with tie and continuity corrections.
+func mannWhitneyTest(a, b []float64) float64 {
+	na, nb := len(a), len(b)
+	if na == 0 || nb == 0 {
+		return 1
+	}
+
+	type obs struct {
+		v     float64
+		fromA bool
+	}
+	all := make([]obs, 0, na+nb)
+	for _, v := range a {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range b {
+		all = append(all, obs{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// Rank with ties averaged, accumulating the tie correction term
+	var rankA, ties float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // mean of ranks i+1..j
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankA += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	n := float64(na + nb)
+	u := rankA - float64(na*(na+1))/2
+	mu := float64(na*nb) / 2
+	sigma := math.Sqrt(float64(na*nb) / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := math.Max(0, math.Abs(u-mu)-0.5) / sigma
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// bootstrap resamples both groups with replacement to estimate the sampling
+// distribution of stat(b) - stat(a) for each statistic.
+type bootstrap struct {
+	a, b      []float64
+	resamples int
+	rng       *rand.Rand
+}
+
+// newBootstrap uses a fixed seed so that comparisons are reproducible.
+func newBootstrap(a, b []float64, resamples int) *bootstrap {
+	return &bootstrap{a: a, b: b, resamples: resamples, rng: rand.New(rand.NewPCG(1, 2))}
+}
+
+// deltas returns, per statistic, the sorted bootstrap differences.
+func (bs *bootstrap) deltas(stats []statistic) [][]float64 {
+	out := make([][]float64, len(stats))
+	ra := make([]float64, len(bs.a))
+	rb := make([]float64, len(bs.b))
+	for r := 0; r < bs.resamples; r++ {
+		for i := range ra {
+			ra[i] = bs.a[bs.rng.IntN(len(bs.a))]
+		}
+		for i := range rb {
+			rb[i] = bs.b[bs.rng.IntN(len(bs.b))]
+		}
+		sort.Float64s(ra)
+		sort.Float64s(rb)
+		for i, st := range stats {
+			out[i] = append(out[i], st.eval(rb)-st.eval(ra))
+		}
+	}
+	for _, d := range out {
+		sort.Float64s(d)
+	}
+	return out
+}
+
+// means returns the sorted means of bootstrap resamples of the first group.
+func (bs *bootstrap) means() []float64 {
+	out := make([]float64, 0, bs.resamples)
+	ra := make([]float64, len(bs.a))
+	for r := 0; r < bs.resamples; r++ {
+		for i := range ra {
+			ra[i] = bs.a[bs.rng.IntN(len(bs.a))]
+		}
+		m, _ := meanVar(ra)
+		out = append(out, m)
+	}
+	sort.Float64s(out)
+	return out
+}
+
+// bootstrapPValue returns the two-sided p-value that the difference is zero,
+// from the share of bootstrap differences on either side of it. Differences
+// of exactly zero count half toward each side, so identical or discrete
+// samples do not appear significant.
+func bootstrapPValue(sortedDeltas []float64) float64 {
+	if len(sortedDeltas) == 0 {
+		return 1
+	}
+	below := sort.SearchFloat64s(sortedDeltas, 0)
+	above := len(sortedDeltas) - sort.Search(len(sortedDeltas), func(i int) bool { return sortedDeltas[i] > 0 })
+	ties := len(sortedDeltas) - below - above
+	p := 2 * (float64(min(below, above)) + float64(ties)/2) / float64(len(sortedDeltas))
+	return math.Min(1, p)
+}
+
+// statistic is a summary statistic evaluated over sorted samples.
+type statistic struct {
+	name string
+	eval func(sorted []float64) float64
+}
+
+func meanStatistic() statistic {
+	return statistic{name: "mean", eval: func(s []float64) float64 {
+		m, _ := meanVar(s)
+		return m
+	}}
+}
+
+func percentileStatistic(key string, p float64) statistic {
+	return statistic{name: key, eval: func(s []float64) float64 { return quantile(s, p/100) }}
+}
+
+func meanVar(v []float64) (float64, float64) {
+	var mean, m2 float64
+	for i, x := range v {
+		d := x - mean
+		mean += d / float64(i+1)
+		m2 += d * (x - mean)
+	}
+	if len(v) < 2 {
+		return mean, 0
+	}
+	return mean, m2 / float64(len(v)-1)
+}
+
+// quantile interpolates linearly between closest ranks, like
+// collector.Stats.Percentiles.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := math.Max(0, math.Min(1, q)) * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b),
+// evaluated with the continued fraction from Numerical Recipes.
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIter = 300
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1-qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return h
+}
+
+// tQuantile returns the q-quantile of Student's t distribution with df
+// degrees of freedom, by bisection on its CDF.
+func tQuantile(q, df float64) float64 {
+	if q == 0.5 {
+		return 0
+	}
+	if q < 0.5 {
+		return -tQuantile(1-q, df)
+	}
+	// P(T > t) = I_{df/(df+t^2)}(df/2, 1/2) / 2 for t > 0
+	upper := func(t float64) float64 { return regIncBeta(df/2, 0.5, df/(df+t*t)) / 2 }
+	lo, hi := 0.0, 1.0
+	for upper(hi) > 1-q {
+		hi *= 2
+	}
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if upper(mid) > 1-q {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
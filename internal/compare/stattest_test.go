@@ -0,0 +1,156 @@
+package compare
+
+import (
+	"math"
+	"testing"
+)
+
+// Reference values were computed independently of this package, by numeric
+// integration of Student's t density; Mann-Whitney values match R's
+// wilcox.test(exact = FALSE, correct = TRUE).
+
+func TestWelchTest(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"different means", []float64{1, 2, 3, 4, 5}, []float64{2, 4, 6, 8, 10}, 0.1075312},
+		{"unequal sizes", []float64{19.1, 20.3, 18.7, 21.0, 19.8, 20.5}, []float64{22.4, 21.9, 23.1, 22.8}, 0.0003265},
+		{"identical", []float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		{"all tied", []float64{5, 5, 5}, []float64{5, 5, 5}, 1},
+		{"tied apart", []float64{5, 5, 5}, []float64{6, 6, 6}, 0},
+		{"n=1", []float64{1}, []float64{2, 3}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := welchTest(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("welchTest = %.7f, want %.7f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMannWhitneyTest(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"separated", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 0.0121858},
+		{"with ties", []float64{1, 2, 2, 3}, []float64{2, 3, 3, 4}, 0.1720337},
+		{"identical", []float64{1, 2, 3, 4}, []float64{1, 2, 3, 4}, 1},
+		{"all tied", []float64{5, 5, 5}, []float64{5, 5, 5}, 1},
+		{"n=1", []float64{1}, []float64{2}, 1},
+		{"empty", nil, []float64{1, 2}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mannWhitneyTest(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("mannWhitneyTest = %.7f, want %.7f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBootstrapPValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		deltas []float64 // sorted
+		want   float64
+	}{
+		{"all zero", []float64{0, 0, 0, 0}, 1},
+		{"all positive", []float64{1, 2, 3, 4}, 0},
+		{"centered", []float64{-2, -1, 1, 2}, 1},
+		{"one below", []float64{-1, 1, 2, 3}, 0.5},
+		{"ties count half", []float64{0, 0, 1, 2}, 0.5},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bootstrapPValue(tt.deltas); got != tt.want {
+				t.Errorf("bootstrapPValue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBootstrapIdenticalSamples(t *testing.T) {
+	a := []float64{100, 101, 100, 102, 101, 100}
+	b := append([]float64(nil), a...)
+	deltas := newBootstrap(sorted(a), sorted(b), 1000).deltas([]statistic{meanStatistic()})
+	if p := bootstrapPValue(deltas[0]); p < 0.5 {
+		t.Errorf("p = %v for identical samples, want no significance", p)
+	}
+
+	// Every resample of constant samples differs by exactly zero
+	c := []float64{7, 7, 7}
+	deltas = newBootstrap(c, c, 100).deltas([]statistic{meanStatistic()})
+	if p := bootstrapPValue(deltas[0]); p != 1 {
+		t.Errorf("p = %v for all-tied samples, want 1", p)
+	}
+}
+
+func TestRegIncBeta(t *testing.T) {
+	tests := []struct {
+		a, b, x float64
+		want    float64
+	}{
+		{2, 1, 0.3, 0.09},            // x^a
+		{1, 3, 0.2, 1 - 0.8*0.8*0.8}, // 1 - (1-x)^b
+		{4.5, 4.5, 0.5, 0.5},         // symmetric
+		{0.5, 0.5, 0.25, 1.0 / 3},    // 2/pi asin(sqrt(x))
+		{2, 3, 0, 0},
+		{2, 3, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := regIncBeta(tt.a, tt.b, tt.x); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("regIncBeta(%v, %v, %v) = %.10f, want %.10f", tt.a, tt.b, tt.x, got, tt.want)
+		}
+	}
+}
+
+func TestTQuantile(t *testing.T) {
+	tests := []struct {
+		q, df float64
+		want  float64
+	}{
+		{0.975, 1, 12.7062047},
+		{0.95, 5, 2.0150484},
+		{0.975, 10, 2.2281389},
+		{0.975, 30, 2.0422725},
+		{0.025, 10, -2.2281389},
+		{0.5, 3, 0},
+	}
+	for _, tt := range tests {
+		if got := tQuantile(tt.q, tt.df); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("tQuantile(%v, %v) = %.7f, want %.7f", tt.q, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestMeanInterval(t *testing.T) {
+	low, high := MeanInterval([]float64{1, 2, 3, 4, 5}, 0.95)
+	// 3 ± t(0.975, 4) * sqrt(2.5/5)
+	half := 2.7764451 * math.Sqrt(0.5)
+	if math.Abs(low-(3-half)) > 1e-6 || math.Abs(high-(3+half)) > 1e-6 {
+		t.Errorf("MeanInterval = [%v, %v], want [%v, %v]", low, high, 3-half, 3+half)
+	}
+
+	if low, high := MeanInterval([]float64{4}, 0.95); low != 4 || high != 4 {
+		t.Errorf("MeanInterval of n=1 = [%v, %v], want [4, 4]", low, high)
+	}
+}
+
+func TestCompareSeriesSingleSample(t *testing.T) {
+	// One sample per side shows no variability to test against
+	base := series{parameter: "latency", metric: "latency", samples: []float64{100}}
+	cand := series{parameter: "latency", metric: "latency", samples: []float64{200}}
+	for _, test := range Tests() {
+		opts := Options{Test: test, Alpha: 0.05, Confidence: 0.95, Resamples: 100}
+		mc := compareSeries(base, cand, []statistic{meanStatistic()}, opts)
+		if mc.PValue != 1 || mc.Significant {
+			t.Errorf("%s: p = %v, significant %v, want 1 and false", test, mc.PValue, mc.Significant)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Package environment captures the host properties that affect the
+// reproducibility of eBPF measurements.
+package environment
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	procSys = "/proc/sys"
+	sysCPU  = "/sys/devices/system/cpu"
+	sysNode = "/sys/devices/system/node"
+)
+
+// Capture reads the host fingerprint and the variance warnings derived from
+// it. Capture never fails: unreadable properties are left empty.
+func Capture() *bpfsv1.Environment {
+	env := &bpfsv1.Environment{
+		BPFJITEnable:    readInt(filepath.Join(procSys, "net/core/bpf_jit_enable")),
+		BPFJITHarden:    readInt(filepath.Join(procSys, "net/core/bpf_jit_harden")),
+		BPFJITKallsyms:  readInt(filepath.Join(procSys, "net/core/bpf_jit_kallsyms")),
+		BPFStatsEnabled: readInt(filepath.Join(procSys, "kernel/bpf_stats_enabled")),
+
+		CPUModel:     cpuModel(),
+		SMT:          readString(filepath.Join(sysCPU, "smt/control")),
+		SMTActive:    readBool(filepath.Join(sysCPU, "smt/active")),
+		Governors:    governors(),
+		Turbo:        turbo(),
+		IsolatedCPUs: readString(filepath.Join(sysCPU, "isolated")),
+		NUMANodes:    numaNodes(),
+	}
+
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err == nil {
+		env.KernelVersion = unix.ByteSliceToString(uts.Version[:])
+	}
+	if online := readString(filepath.Join(sysCPU, "online")); online != "" {
+		env.OnlineCPUs = CountCPUList(online)
+	}
+	env.AvailableCPUs = AvailableCPUs()
+
+	env.Warnings = Warnings(env)
+	return env
+}
+
+// Warnings lists the properties of env known to inflate measurement variance
+// or to invalidate the measurement altogether.
+func Warnings(env *bpfsv1.Environment) []string {
+	var warnings []string
+	if v := env.BPFStatsEnabled; v != nil && *v == 0 {
+		warnings = append(warnings, "kernel.bpf_stats_enabled=0: program run time is not accounted unless another process enabled BPF stats")
+	}
+	if v := env.BPFJITEnable; v != nil && *v == 0 {
+		warnings = append(warnings, "net.core.bpf_jit_enable=0: programs run in the interpreter, results do not reflect JITed cost")
+	}
+	if v := env.BPFJITHarden; v != nil && *v > 0 {
+		warnings = append(warnings, fmt.Sprintf("net.core.bpf_jit_harden=%d: constant blinding adds instructions to JITed programs", *v))
+	}
+	for _, g := range env.Governors {
+		if g != "performance" {
+			warnings = append(warnings, fmt.Sprintf("CPU frequency governor %q: frequency scaling inflates variance, consider \"performance\"", g))
+		}
+	}
+	if env.Turbo != nil && *env.Turbo {
+		warnings = append(warnings, "turbo/boost enabled: clock speed varies with load and temperature")
+	}
+	if env.SMTActive != nil && *env.SMTActive {
+		warnings = append(warnings, "SMT active: sibling hyperthreads share execution resources with the measured program")
+	}
+	return warnings
+}
+
+// AvailableCPUs returns the number of online CPUs this process may run on,
+// which honours cgroup cpusets and taskset affinity.
+func AvailableCPUs() int {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &set); err == nil && set.Count() > 0 {
+		return set.Count()
+	}
+	return runtime.NumCPU()
+}
+
+// CountCPUList returns the number of CPUs in a kernel cpulist such as
+// "0-3,8,10-11".
+func CountCPUList(list string) int {
+	return len(ParseCPUList(list))
+}
+
+// ParseCPUList returns the CPU numbers of a kernel cpulist such as
+// "0-3,8,10-11", in ascending order. Malformed entries are skipped.
+func ParseCPUList(list string) []int {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(list), ",") {
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		a, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		b := a
+		if isRange {
+			if b, err = strconv.Atoi(hi); err != nil {
+				continue
+			}
+		}
+		for c := a; c <= b; c++ {
+			cpus = append(cpus, c)
+		}
+	}
+	sort.Ints(cpus)
+	return cpus
+}
+
+// OnlineCPUList returns the CPU numbers that are currently online.
+func OnlineCPUList() []int {
+	if cpus := ParseCPUList(readString("/sys/devices/system/cpu/online")); len(cpus) > 0 {
+		return cpus
+	}
+	cpus := make([]int, runtime.NumCPU())
+	for i := range cpus {
+		cpus[i] = i
+	}
+	return cpus
+}
+
+func cpuModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	// x86 reports "model name"; other architectures use different keys
+	keys := []string{"model name", "Model", "cpu model", "Hardware"}
+	found := map[string]string{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		k, v, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		k = strings.TrimSpace(k)
+		if _, seen := found[k]; !seen {
+			found[k] = strings.TrimSpace(v)
+		}
+	}
+	for _, k := range keys {
+		if v := found[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func governors() []string {
+	paths, _ := filepath.Glob(filepath.Join(sysCPU, "cpu[0-9]*/cpufreq/scaling_governor"))
+	seen := map[string]bool{}
+	var out []string
+	for _, p := range paths {
+		if g := readString(p); g != "" && !seen[g] {
+			seen[g] = true
+			out = append(out, g)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// turbo reports whether turbo/boost is enabled, checking intel_pstate first
+// and the generic cpufreq boost knob (acpi-cpufreq, amd-pstate) second.
+func turbo() *bool {
+	if noTurbo := readBool(filepath.Join(sysCPU, "intel_pstate/no_turbo")); noTurbo != nil {
+		enabled := !*noTurbo
+		return &enabled
+	}
+	return readBool(filepath.Join(sysCPU, "cpufreq/boost"))
+}
+
+func numaNodes() []bpfsv1.NUMANode {
+	paths, _ := filepath.Glob(filepath.Join(sysNode, "node[0-9]*/cpulist"))
+	var nodes []bpfsv1.NUMANode
+	for _, p := range paths {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(p)), "node"))
+		if err != nil {
+			continue
+		}
+		nodes = append(nodes, bpfsv1.NUMANode{ID: id, CPUs: readString(p)})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readInt(path string) *int {
+	v, err := strconv.Atoi(readString(path))
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func readBool(path string) *bool {
+	v := readInt(path)
+	if v == nil {
+		return nil
+	}
+	b := *v != 0
+	return &b
+}
@@ -0,0 +1,30 @@
+package environment
+
+import (
+	"os"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/version"
+	"golang.org/x/sys/unix"
+)
+
+// RunMetadata describes the current process and host for a report of a run
+// between started and ended. env may be nil.
+func RunMetadata(started, ended time.Time, env *bpfsv1.Environment) bpfsv1.RunMetadata {
+	meta := bpfsv1.RunMetadata{
+		CommandLine: os.Args,
+		ToolVersion: version.Get(),
+		Started:     &started,
+		Ended:       &ended,
+		Environment: env,
+	}
+	if host, err := os.Hostname(); err == nil {
+		meta.Host = host
+	}
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err == nil {
+		meta.Kernel = unix.ByteSliceToString(uts.Release[:])
+	}
+	return meta
+}
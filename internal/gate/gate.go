@@ -0,0 +1,297 @@
+// Package gate evaluates pass/fail conditions on measurement results, so that
+// CI pipelines get an exit status instead of having to parse output:
+// threshold assertions such as "p99 < 800ns" and relative regressions
+// against a baseline result file.
+package gate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+// ErrFailed is returned (wrapped) when at least one condition is violated.
+var ErrFailed = errors.New("gate failed")
+
+// Result is the outcome of one condition on one parameter.
+type Result struct {
+	Condition string  // as given by the user, e.g. "p99 < 800ns"
+	Field     string  // resolved field, e.g. "latency.percentiles_ns.p99"
+	Actual    float64 // measured value
+	Passed    bool
+	Message   string // why the condition failed, or could not be evaluated
+}
+
+func (r Result) String() string {
+	status := "PASS"
+	if !r.Passed {
+		status = "FAIL"
+	}
+	switch {
+	case r.Message != "":
+		return fmt.Sprintf("%s %s: %s", status, r.Condition, r.Message)
+	default:
+		return fmt.Sprintf("%s %s (%s = %s)", status, r.Condition, r.Field, formatFloat(r.Actual))
+	}
+}
+
+// Check returns an error wrapping ErrFailed if any result failed.
+func Check(results []Result) error {
+	var failed int
+	for _, r := range results {
+		if !r.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d conditions violated", ErrFailed, failed, len(results))
+	}
+	return nil
+}
+
+// Assertion is a threshold on one field, e.g. "p99 < 800ns" or
+// "cpu.mean < 0.05".
+type Assertion struct {
+	raw   string
+	kind  string   // optional parameter kind, e.g. "cpu"
+	path  []string // field path within the parameter, e.g. ["p99"]
+	op    string
+	value float64
+	unit  string // "ns" when the threshold was given as a duration
+}
+
+var assertionRe = regexp.MustCompile(`^\s*([A-Za-z0-9_.]+)\s*(<=|>=|==|!=|<|>)\s*(-?[0-9.]+(?:[eE][-+]?[0-9]+)?)\s*([A-Za-zµ%]*)\s*$`)
+
+// durationUnits converts threshold units to nanoseconds.
+var durationUnits = map[string]float64{
+	"ns": 1,
+	"us": 1e3,
+	"µs": 1e3,
+	"ms": 1e6,
+	"s":  1e9,
+}
+
+// ParseAssertion parses "FIELD OP VALUE[UNIT]". FIELD may be prefixed by a
+// parameter kind ("cpu.mean"); percentile keys accept dots ("p99.9").
+// Durations (ns, us, ms, s) compare against nanosecond fields and "%"
+// divides the value by 100.
+func ParseAssertion(s string) (Assertion, error) {
+	m := assertionRe.FindStringSubmatch(s)
+	if m == nil {
+		return Assertion{}, fmt.Errorf("invalid assertion %q, expected e.g. 'p99 < 800ns' or 'cpu.mean < 0.05'", s)
+	}
+	value, err := strconv.ParseFloat(m[3], 64)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("invalid assertion %q: %w", s, err)
+	}
+
+	a := Assertion{raw: strings.TrimSpace(s), op: m[2]}
+	switch unit := m[4]; {
+	case unit == "":
+	case unit == "%":
+		value /= 100
+	case durationUnits[unit] != 0:
+		value *= durationUnits[unit]
+		a.unit = "ns"
+	default:
+		return Assertion{}, fmt.Errorf("invalid assertion %q: unknown unit %q", s, unit)
+	}
+	a.value = value
+
+	path := splitPath(m[1])
+	if slices.Contains(bpfsv1.Kinds(), path[0]) && len(path) > 1 {
+		a.kind, path = path[0], path[1:]
+	}
+	a.path = path
+	return a, nil
+}
+
+// splitPath splits a dotted field, keeping percentile keys such as "p99.9"
+// together and normalizing them to "p99_9".
+func splitPath(field string) []string {
+	var out []string
+	for _, part := range strings.Split(field, ".") {
+		if n := len(out); n > 0 && isPercentileKey(out[n-1]) && isDigits(part) {
+			out[n-1] += "_" + part
+			continue
+		}
+		out = append(out, part)
+	}
+	return out
+}
+
+// Evaluate checks the assertion against the first parameter that has the
+// field (of the given kind, if any). With a duration threshold, only
+// nanosecond fields are considered. Results of repeated trials hold one
+// parameter of each kind per trial: the assertion must then hold in every
+// trial, and the result reports the first trial violating it, or else the
+// trial closest to the threshold.
+func (a Assertion) Evaluate(params []bpfsv1.Parameter) Result {
+	res := Result{Condition: a.raw}
+	trials := trialCount(params)
+	var kind string // of the first parameter having the field
+	var trial int
+	for _, p := range params {
+		if (a.kind != "" && p.Kind() != a.kind) || (kind != "" && p.Kind() != kind) {
+			continue
+		}
+		obj, err := toObject(p)
+		if err != nil {
+			res.Message = err.Error()
+			return res
+		}
+		keys, v, ok := resolve(obj, a.path)
+		if !ok || (a.unit == "ns" && !strings.Contains(strings.Join(keys, "."), "_ns")) {
+			continue
+		}
+		kind = p.Kind()
+		trial++
+
+		passed := compare(v, a.op, a.value)
+		if trial == 1 || (res.Passed && (!passed || a.closer(v, res.Actual))) {
+			res.Field = trialField(p.Kind()+"."+strings.Join(keys, "."), trial, trials)
+			res.Actual = v
+			res.Passed = passed
+		}
+		if trial >= trials {
+			return res
+		}
+	}
+	if kind != "" {
+		return res
+	}
+
+	target := "any parameter"
+	if a.kind != "" {
+		target = "the " + a.kind + " parameter"
+	}
+	res.Message = fmt.Sprintf("field %q not found in %s", strings.Join(a.path, "."), target)
+	return res
+}
+
+// closer reports whether v is closer than cur to violating the threshold.
+func (a Assertion) closer(v, cur float64) bool {
+	switch a.op {
+	case "<", "<=":
+		return v > cur
+	case ">", ">=":
+		return v < cur
+	default:
+		return false
+	}
+}
+
+func compare(v float64, op string, threshold float64) bool {
+	switch op {
+	case "<":
+		return v < threshold
+	case "<=":
+		return v <= threshold
+	case ">":
+		return v > threshold
+	case ">=":
+		return v >= threshold
+	case "==":
+		return v == threshold
+	default:
+		return v != threshold
+	}
+}
+
+// resolve walks path through a parameter's JSON object model. Each segment
+// matches a key exactly or with the unit affixes the API uses ("mean" finds
+// "mean_ns", "mean_per_sec" or "cores_mean"); percentile keys are looked up
+// in the percentile map, and profile metrics under "metrics".
+func resolve(obj map[string]interface{}, path []string) ([]string, float64, bool) {
+	var keys []string
+	cur := interface{}(obj)
+	for _, seg := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, 0, false
+		}
+		key, nested, ok := matchKey(m, seg)
+		if !ok {
+			return nil, 0, false
+		}
+		if nested {
+			keys = append(keys, key, seg)
+			cur = m[key].(map[string]interface{})[seg]
+			continue
+		}
+		keys = append(keys, key)
+		cur = m[key]
+	}
+
+	n, ok := cur.(json.Number)
+	if !ok {
+		return nil, 0, false
+	}
+	v, err := n.Float64()
+	if err != nil {
+		return nil, 0, false
+	}
+	return keys, v, true
+}
+
+// matchKey finds the key seg refers to in m. nested reports that seg is a
+// key of the map under the returned key, e.g. "p99" in "percentiles_ns".
+func matchKey(m map[string]interface{}, seg string) (key string, nested bool, ok bool) {
+	for _, key := range []string{seg, seg + "_ns", seg + "_per_sec", "cores_" + seg} {
+		if _, ok := m[key]; ok {
+			return key, false, true
+		}
+	}
+	for key, v := range m {
+		sub, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := sub[seg]; ok && (key == "metrics" || (isPercentileKey(seg) && strings.HasPrefix(key, "percentiles"))) {
+			return key, true, true
+		}
+	}
+	return "", false, false
+}
+
+func isPercentileKey(s string) bool {
+	return len(s) > 1 && s[0] == 'p' && isDigits(strings.ReplaceAll(s[1:], "_", ""))
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// toObject converts a parameter into its JSON object model.
+func toObject(p bpfsv1.Parameter) (map[string]interface{}, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
@@ -0,0 +1,208 @@
+package gate
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		in    string
+		kind  string
+		path  []string
+		op    string
+		value float64
+		unit  string
+	}{
+		{in: "p99 < 800ns", path: []string{"p99"}, op: "<", value: 800, unit: "ns"},
+		{in: "p99<2us", path: []string{"p99"}, op: "<", value: 2000, unit: "ns"},
+		{in: "p99 <= 1.5µs", path: []string{"p99"}, op: "<=", value: 1500, unit: "ns"},
+		{in: "mean < 2ms", path: []string{"mean"}, op: "<", value: 2e6, unit: "ns"},
+		{in: "max < 1s", path: []string{"max"}, op: "<", value: 1e9, unit: "ns"},
+		{in: "health.missed_fraction < 5%", kind: "health", path: []string{"missed_fraction"}, op: "<", value: 0.05},
+		{in: "cpu.mean < 0.05", kind: "cpu", path: []string{"mean"}, op: "<", value: 0.05},
+		{in: "throughput.mean >= 1e5", kind: "throughput", path: []string{"mean"}, op: ">=", value: 1e5},
+		{in: " p99.9 != -1 ", path: []string{"p99_9"}, op: "!=", value: -1},
+		{in: "latency.percentiles_ns.p99.99 < 1ms", kind: "latency", path: []string{"percentiles_ns", "p99_99"}, op: "<", value: 1e6, unit: "ns"},
+		{in: "latency < 5", path: []string{"latency"}, op: "<", value: 5}, // a kind alone is a field
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			a, err := ParseAssertion(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.kind != tt.kind || !slices.Equal(a.path, tt.path) || a.op != tt.op || a.value != tt.value || a.unit != tt.unit {
+				t.Errorf("got kind %q path %q %s %v%s, want kind %q path %q %s %v%s",
+					a.kind, a.path, a.op, a.value, a.unit, tt.kind, tt.path, tt.op, tt.value, tt.unit)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "p99", "p99 <", "p99 ~ 5", "p99 < 5 parsecs", "p99 < 5min", "p-99 < 5"} {
+		if _, err := ParseAssertion(bad); err == nil {
+			t.Errorf("ParseAssertion(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"p99", []string{"p99"}},
+		{"p99.9", []string{"p99_9"}},
+		{"p99.99.9", []string{"p99_99_9"}},
+		{"latency.p99.9", []string{"latency", "p99_9"}},
+		{"metrics.ipc.mean", []string{"metrics", "ipc", "mean"}},
+		{"windows.9", []string{"windows", "9"}}, // only percentile keys absorb digits
+		{"p99.x", []string{"p99", "x"}},
+	}
+	for _, tt := range tests {
+		if got := splitPath(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("splitPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchKey(t *testing.T) {
+	m := map[string]interface{}{
+		"samples":        1,
+		"mean_ns":        1,
+		"rate_per_sec":   1,
+		"cores_mean":     1,
+		"percentiles_ns": map[string]interface{}{"p99": 1, "p99_9": 1},
+		"metrics":        map[string]interface{}{"ipc": map[string]interface{}{"mean": 1}},
+		"per_cpu":        map[string]interface{}{"imbalance": 1},
+	}
+	tests := []struct {
+		seg    string
+		key    string
+		nested bool
+		ok     bool
+	}{
+		{seg: "samples", key: "samples", ok: true},
+		{seg: "mean", key: "mean_ns", ok: true},
+		{seg: "rate", key: "rate_per_sec", ok: true},
+		{seg: "p99", key: "percentiles_ns", nested: true, ok: true},
+		{seg: "p99_9", key: "percentiles_ns", nested: true, ok: true},
+		{seg: "ipc", key: "metrics", nested: true, ok: true},
+		{seg: "p50"},
+		{seg: "imbalance"}, // only percentiles and metrics are searched
+	}
+	for _, tt := range tests {
+		key, nested, ok := matchKey(m, tt.seg)
+		if key != tt.key || nested != tt.nested || ok != tt.ok {
+			t.Errorf("matchKey(%q) = %q, %v, %v, want %q, %v, %v", tt.seg, key, nested, ok, tt.key, tt.nested, tt.ok)
+		}
+	}
+
+	// "cores_" prefixes the cpu summary statistics
+	if key, _, ok := matchKey(map[string]interface{}{"cores_mean": 1}, "mean"); !ok || key != "cores_mean" {
+		t.Errorf("matchKey(mean) = %q, %v, want cores_mean", key, ok)
+	}
+}
+
+func latency(mean, p99 uint64) bpfsv1.Latency {
+	return bpfsv1.Latency{Samples: 10, Mean: mean, Percentiles: &map[string]uint64{"p99": p99, "p99_9": p99 * 2}}
+}
+
+func TestEvaluate(t *testing.T) {
+	cpu := bpfsv1.Cpu{Samples: 10, Mean: 0.25}
+	health := bpfsv1.Health{Samples: 10, Runs: 950, RecursionMisses: 50, MissedFraction: 0.05}
+	single := []bpfsv1.Parameter{cpu, latency(500, 800), health}
+
+	tests := []struct {
+		name      string
+		params    []bpfsv1.Parameter
+		assertion string
+		field     string
+		actual    float64
+		passed    bool
+		notFound  bool
+	}{
+		{name: "duration", params: single, assertion: "p99 < 1µs", field: "latency.percentiles_ns.p99", actual: 800, passed: true},
+		{name: "duration violated", params: single, assertion: "p99 < 0.5us", field: "latency.percentiles_ns.p99", actual: 800},
+		{name: "joined percentile key", params: single, assertion: "p99.9 <= 1.6us", field: "latency.percentiles_ns.p99_9", actual: 1600, passed: true},
+		{name: "percent", params: single, assertion: "missed_fraction < 5%", field: "health.missed_fraction", actual: 0.05},
+		{name: "percent passed", params: single, assertion: "health.missed_fraction <= 5%", field: "health.missed_fraction", actual: 0.05, passed: true},
+
+		// cpu comes first, but its mean is in cores: a duration only
+		// matches nanosecond fields
+		{name: "duration skips other units", params: single, assertion: "mean < 1ms", field: "latency.mean_ns", actual: 500, passed: true},
+		{name: "unitless takes the first match", params: single, assertion: "mean < 1", field: "cpu.cores_mean", actual: 0.25, passed: true},
+		{name: "kind prefix", params: single, assertion: "latency.mean > 100", field: "latency.mean_ns", actual: 500, passed: true},
+		{name: "duration without nanosecond field", params: single, assertion: "cpu.mean < 1ms", notFound: true},
+		{name: "missing field", params: single, assertion: "p50 < 1ms", notFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAssertion(tt.assertion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := a.Evaluate(tt.params)
+			if tt.notFound {
+				if res.Passed || res.Message == "" {
+					t.Errorf("result %+v, want a failed result with a message", res)
+				}
+				return
+			}
+			if res.Field != tt.field || res.Actual != tt.actual || res.Passed != tt.passed || res.Message != "" {
+				t.Errorf("result %+v, want field %q = %v, passed %v", res, tt.field, tt.actual, tt.passed)
+			}
+		})
+	}
+}
+
+func TestEvaluateTrials(t *testing.T) {
+	// Three trials of latency and cpu, then the trials summary
+	params := []bpfsv1.Parameter{
+		latency(100, 300), bpfsv1.Cpu{Mean: 0.1},
+		latency(120, 500), bpfsv1.Cpu{Mean: 0.3},
+		latency(110, 400), bpfsv1.Cpu{Mean: 0.2},
+		bpfsv1.Trials{Trials: 3},
+	}
+
+	tests := []struct {
+		assertion string
+		field     string
+		actual    float64
+		passed    bool
+	}{
+		// Passing: the trial closest to the threshold
+		{"p99 < 1us", "latency.percentiles_ns.p99 in trial 2", 500, true},
+		{"p99 > 100ns", "latency.percentiles_ns.p99 in trial 1", 300, true},
+		{"cpu.mean < 0.5", "cpu.cores_mean in trial 2", 0.3, true},
+		// Failing: the first violating trial, not the worst
+		{"p99 < 350ns", "latency.percentiles_ns.p99 in trial 2", 500, false},
+		{"p99 < 250ns", "latency.percentiles_ns.p99 in trial 1", 300, false},
+		{"mean >= 115", "latency.mean_ns in trial 1", 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.assertion, func(t *testing.T) {
+			a, err := ParseAssertion(tt.assertion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := a.Evaluate(params)
+			if res.Field != tt.field || res.Actual != tt.actual || res.Passed != tt.passed {
+				t.Errorf("result %+v, want field %q = %v, passed %v", res, tt.field, tt.actual, tt.passed)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check([]Result{{Passed: true}, {Passed: true}}); err != nil {
+		t.Errorf("Check of passed results = %v, want nil", err)
+	}
+	err := Check([]Result{{Passed: true}, {Passed: false}})
+	if !errors.Is(err, ErrFailed) {
+		t.Errorf("Check of a failed result = %v, want ErrFailed", err)
+	}
+}
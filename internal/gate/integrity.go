@@ -0,0 +1,27 @@
+package gate
+
+import (
+	"fmt"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+// ErrorRate fails when more than maxRate (a fraction, e.g. 0.1 for 10%) of
+// the polls of a run failed, since its results then cover a biased subset
+// of the measured intervals.
+func ErrorRate(in *bpfsv1.Integrity, maxRate float64) Result {
+	r := Result{
+		Condition: fmt.Sprintf("error_rate <= %s", formatPct(maxRate)),
+		Field:     "integrity.error_rate",
+	}
+	if in == nil || in.Polls == 0 {
+		r.Message = "no polls recorded"
+		return r
+	}
+	r.Actual = in.ErrorRate
+	r.Passed = in.ErrorRate <= maxRate
+	if !r.Passed {
+		r.Message = fmt.Sprintf("%d of %d polls failed (%s)", in.FailedPolls, in.Polls, formatPct(in.ErrorRate))
+	}
+	return r
+}
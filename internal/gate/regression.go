@@ -0,0 +1,106 @@
+package gate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+// Regressions compares the cost of the current results against a baseline
+// and fails every metric that grew by more than maxRegression (a fraction,
+// e.g. 0.05 for 5%). Parameters are matched by kind and order of appearance.
+// Only metrics where lower is better and that do not depend on the offered
+// load are checked: latency and bench means and percentiles, and the
+// per-run profile counters. Latencies of repeated trials are compared by
+// their values pooled over all trials.
+func Regressions(baseline, current []bpfsv1.Parameter, maxRegression float64) []Result {
+	baseline, current = pooled(baseline), pooled(current)
+	condition := fmt.Sprintf("regression <= %s", formatPct(maxRegression))
+
+	bases := make(map[string][]bpfsv1.Parameter)
+	for _, p := range baseline {
+		bases[p.Kind()] = append(bases[p.Kind()], p)
+	}
+
+	var results []Result
+	seen := make(map[string]int)
+	for _, cur := range current {
+		i := seen[cur.Kind()]
+		seen[cur.Kind()]++
+		if i >= len(bases[cur.Kind()]) {
+			continue
+		}
+		baseMetrics := costMetrics(bases[cur.Kind()][i])
+		curMetrics := costMetrics(cur)
+
+		names := make([]string, 0, len(curMetrics))
+		for name := range curMetrics {
+			if _, ok := baseMetrics[name]; ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			base, v := baseMetrics[name], curMetrics[name]
+			res := Result{
+				Condition: condition,
+				Field:     cur.Kind() + "." + name,
+				Actual:    v,
+				Passed:    true,
+			}
+			if base > 0 {
+				change := (v - base) / base
+				res.Passed = change <= maxRegression
+				if !res.Passed {
+					res.Message = fmt.Sprintf("%s regressed by %s (baseline %s, now %s)",
+						res.Field, formatPct(change), formatFloat(base), formatFloat(v))
+				}
+			}
+			results = append(results, res)
+		}
+	}
+
+	if len(results) == 0 {
+		results = append(results, Result{
+			Condition: condition,
+			Message:   "no latency, bench or profile results in common with the baseline",
+		})
+	}
+	return results
+}
+
+// costMetrics returns the lower-is-better metrics of a parameter by name.
+func costMetrics(p bpfsv1.Parameter) map[string]float64 {
+	m := make(map[string]float64)
+	addPercentiles := func(ps *map[string]uint64) {
+		if ps == nil {
+			return
+		}
+		for k, v := range *ps {
+			m["percentiles_ns."+k] = float64(v)
+		}
+	}
+
+	switch t := p.(type) {
+	case bpfsv1.Latency:
+		m["mean_ns"] = float64(t.Mean)
+		addPercentiles(t.Percentiles)
+	case bpfsv1.Bench:
+		m["mean_ns"] = float64(t.Mean)
+		addPercentiles(t.Percentiles)
+	case bpfsv1.Profile:
+		for name, s := range t.Metrics {
+			if strings.HasSuffix(name, "_per_run") {
+				m["metrics."+name+".mean"] = s.Mean
+			}
+		}
+	}
+	return m
+}
+
+func formatPct(fraction float64) string {
+	return fmt.Sprintf("%.2f%%", fraction*100)
+}
@@ -0,0 +1,74 @@
+package gate
+
+import (
+	"fmt"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+// trialCount returns the number of trials of a repeated measurement, whose
+// parameters hold one parameter of each kind per trial, in trial order,
+// followed by the trials summary. It returns 0 for a single run.
+func trialCount(params []bpfsv1.Parameter) int {
+	for _, p := range params {
+		if t, ok := p.(bpfsv1.Trials); ok {
+			return t.Trials
+		}
+	}
+	return 0
+}
+
+// pooled replaces the per-trial latency parameters of a repeated measurement
+// by one whose mean and percentiles are pooled over all trials, so costs are
+// compared over every trial rather than the first one. Other parameters and
+// single runs are returned as they are.
+func pooled(params []bpfsv1.Parameter) []bpfsv1.Parameter {
+	var summary *bpfsv1.Trials
+	for _, p := range params {
+		if t, ok := p.(bpfsv1.Trials); ok {
+			summary = &t
+			break
+		}
+	}
+	if summary == nil {
+		return params
+	}
+
+	out := make([]bpfsv1.Parameter, 0, len(params))
+	var merged bool
+	for _, p := range params {
+		lat, ok := p.(bpfsv1.Latency)
+		if !ok {
+			out = append(out, p)
+			continue
+		}
+		if merged {
+			continue
+		}
+		merged = true
+
+		if m, ok := summary.Metrics["latency_mean_ns"]; ok {
+			lat.Mean = uint64(m.Pooled)
+		}
+		if lat.Percentiles != nil {
+			ps := make(map[string]uint64, len(*lat.Percentiles))
+			for k, v := range *lat.Percentiles {
+				ps[k] = v
+				if m, ok := summary.Metrics["latency_"+k+"_ns"]; ok {
+					ps[k] = uint64(m.Pooled)
+				}
+			}
+			lat.Percentiles = &ps
+		}
+		out = append(out, lat)
+	}
+	return out
+}
+
+// trialField names field within trial i of n, or field alone for single runs.
+func trialField(field string, i, n int) string {
+	if n <= 1 {
+		return field
+	}
+	return fmt.Sprintf("%s in trial %d", field, i)
+}
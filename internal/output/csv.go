@@ -0,0 +1,115 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+func init() {
+	Register("csv", noArg("csv", func(opts OutputOptions) Printer {
+		return &CSVOutput{NoHeaders: opts.NoHeaders}
+	}))
+}
+
+// CSVOutput writes parameters in long format, one row per field:
+//
+//	kind,id,field,value
+//	latency,42,mean_ns,1532
+//	latency,42,percentiles_ns.p99,2210
+//
+// Nested objects and arrays are flattened into dotted field names, so every
+// parameter kind shares the same columns and a report is a single table.
+type CSVOutput struct {
+	NoHeaders bool
+
+	wroteHeader bool
+}
+
+var csvHeader = []string{"kind", "id", "field", "value"}
+
+func (c *CSVOutput) OutputReport(r *bpfsv1.Report, w io.Writer) error {
+	for _, p := range r.Params() {
+		if err := c.OutputParam(p, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CSVOutput) OutputParam(par bpfsv1.Parameter, w io.Writer) error {
+	obj, err := toGeneric(bpfsv1.TypedParameter{Parameter: par})
+	if err != nil {
+		return err
+	}
+	fields, ok := obj.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("csv: %s does not encode as an object", par.Kind())
+	}
+
+	cw := csv.NewWriter(w)
+	if !c.NoHeaders && !c.wroteHeader {
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+
+	id := ""
+	if v, ok := fields["id"]; ok {
+		id = csvValue(v)
+	}
+	var werr error
+	flatten("", fields, func(field, value string) {
+		if werr == nil && field != "id" && field != "kind" {
+			werr = cw.Write([]string{par.Kind(), id, field, value})
+		}
+	})
+	if werr != nil {
+		return werr
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// flatten walks a JSON object model depth-first in key order, calling fn with
+// dotted paths for every scalar leaf.
+func flatten(prefix string, v interface{}, fn func(field, value string)) {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(t) {
+			flatten(join(k), t[k], fn)
+		}
+	case []interface{}:
+		for i, e := range t {
+			flatten(join(strconv.Itoa(i)), e, fn)
+		}
+	default:
+		fn(prefix, csvValue(t))
+	}
+}
+
+func csvValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
@@ -16,6 +16,14 @@ type ParameterOutput interface {
 	OutputParam(bpfsv1.Parameter, io.Writer) error
 }
 
+// Printer formats single parameters as well as complete run reports. Every
+// registered format emits exactly one coherent document per report.
+type Printer interface {
+	ParameterOutput
+	OutputReport(*bpfsv1.Report, io.Writer) error
+}
+
+// OutputOptions configures printers built through NewPrinter.
 type OutputOptions struct {
 	NoHeaders    bool
 	ShowLabels   bool
@@ -24,4 +32,9 @@ type OutputOptions struct {
 	SortBy string
 
 	AllowMissingKeys bool
+
+	// Text output plots (see TextOutput)
+	Plot    bool
+	Width   int
+	Unicode bool
 }
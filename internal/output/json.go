@@ -7,11 +7,35 @@ import (
 	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
 )
 
-type JsonOutput struct{}
+func init() {
+	Register("json", noArg("json", func(OutputOptions) Printer { return &JsonOutput{} }))
+	Register("json-compact", noArg("json-compact", func(OutputOptions) Printer { return &JsonOutput{Compact: true} }))
+	Register("ndjson", noArg("ndjson", func(OutputOptions) Printer { return &NDJSONOutput{} }))
+}
+
+type JsonOutput struct {
+	// Compact disables indentation.
+	Compact bool
+}
 
 func (p *JsonOutput) OutputParam(par bpfsv1.Parameter, w io.Writer) error {
+	return p.write(bpfsv1.TypedParameter{Parameter: par}, w)
+}
+
+func (p *JsonOutput) OutputReport(r *bpfsv1.Report, w io.Writer) error {
+	return p.write(r, w)
+}
 
-	data, err := json.MarshalIndent(par, "", "    ")
+func (p *JsonOutput) write(v interface{}, w io.Writer) error {
+	var (
+		data []byte
+		err  error
+	)
+	if p.Compact {
+		data, err = json.Marshal(v)
+	} else {
+		data, err = json.MarshalIndent(v, "", "    ")
+	}
 	if err != nil {
 		return err
 	}
@@ -19,3 +43,15 @@ func (p *JsonOutput) OutputParam(par bpfsv1.Parameter, w io.Writer) error {
 	_, err = w.Write(data)
 	return err
 }
+
+// NDJSONOutput writes newline-delimited JSON: one compact document per line,
+// suitable for appending the reports of many runs to the same file.
+type NDJSONOutput struct{}
+
+func (p *NDJSONOutput) OutputParam(par bpfsv1.Parameter, w io.Writer) error {
+	return json.NewEncoder(w).Encode(bpfsv1.TypedParameter{Parameter: par})
+}
+
+func (p *NDJSONOutput) OutputReport(r *bpfsv1.Report, w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
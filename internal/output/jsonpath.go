@@ -0,0 +1,351 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+func init() {
+	Register("jsonpath", requireArg("jsonpath", func(arg string, opts OutputOptions) (Printer, error) {
+		return NewJSONPathOutput(arg, opts)
+	}))
+}
+
+// JSONPathOutput prints the fields selected by a kubectl-style JSONPath
+// template, e.g. "{.percentiles_ns.p99}" or "id={.id} mean={.mean_ns}{'\n'}".
+//
+// The supported subset covers what scripts typically need: child fields
+// (".a.b"), quoted fields ("['a']"), array indices ("[0]", "[-1]"),
+// wildcards (".*", "[*]") and equality filters ("[?(@.kind=='latency')]").
+// Multiple matches are joined with a space.
+type JSONPathOutput struct {
+	segments         []jsonPathSegment
+	allowMissingKeys bool
+}
+
+// jsonPathSegment is either literal text or an expression to evaluate.
+type jsonPathSegment struct {
+	literal string
+	steps   []jsonPathStep
+}
+
+type jsonPathStep struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+	filter   *jsonPathFilter
+}
+
+// jsonPathFilter keeps array elements whose path compares to value.
+type jsonPathFilter struct {
+	path   []jsonPathStep
+	value  string
+	negate bool
+}
+
+// NewJSONPathOutput parses a JSONPath template.
+func NewJSONPathOutput(tmpl string, opts OutputOptions) (*JSONPathOutput, error) {
+	jp := &JSONPathOutput{allowMissingKeys: opts.AllowMissingKeys}
+	for len(tmpl) > 0 {
+		open := strings.IndexByte(tmpl, '{')
+		if open < 0 {
+			jp.segments = append(jp.segments, jsonPathSegment{literal: tmpl})
+			break
+		}
+		if open > 0 {
+			jp.segments = append(jp.segments, jsonPathSegment{literal: tmpl[:open]})
+		}
+		end := closingBracket(tmpl[open:])
+		if end < 0 {
+			return nil, fmt.Errorf("jsonpath: unclosed action in %q", tmpl)
+		}
+		seg, err := parseJSONPathAction(tmpl[open+1 : open+end])
+		if err != nil {
+			return nil, err
+		}
+		jp.segments = append(jp.segments, seg)
+		tmpl = tmpl[open+end+1:]
+	}
+	return jp, nil
+}
+
+// parseJSONPathAction parses the contents of a single {...} action.
+func parseJSONPathAction(expr string) (jsonPathSegment, error) {
+	expr = strings.TrimSpace(expr)
+
+	// Quoted string literals, e.g. {'\n'} or {"\t"}
+	if len(expr) >= 2 && (expr[0] == '\'' || expr[0] == '"') && expr[len(expr)-1] == expr[0] {
+		lit, err := strconv.Unquote(`"` + strings.ReplaceAll(expr[1:len(expr)-1], `"`, `\"`) + `"`)
+		if err != nil {
+			return jsonPathSegment{}, fmt.Errorf("jsonpath: invalid literal %s: %w", expr, err)
+		}
+		return jsonPathSegment{literal: lit}, nil
+	}
+
+	expr = strings.TrimPrefix(expr, "$")
+	expr = strings.TrimPrefix(expr, "@")
+
+	var steps []jsonPathStep
+	for len(expr) > 0 {
+		switch expr[0] {
+		case '.':
+			expr = expr[1:]
+			n := strings.IndexAny(expr, ".[")
+			if n < 0 {
+				n = len(expr)
+			}
+			name := expr[:n]
+			expr = expr[n:]
+			switch name {
+			case "":
+				// "{.}" selects the root; a trailing "." is ignored
+			case "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			default:
+				steps = append(steps, jsonPathStep{field: name})
+			}
+		case '[':
+			n := closingBracket(expr)
+			if n < 0 {
+				return jsonPathSegment{}, fmt.Errorf("jsonpath: unclosed subscript in %q", expr)
+			}
+			sub := strings.TrimSpace(expr[1:n])
+			expr = expr[n+1:]
+			switch {
+			case sub == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case strings.HasPrefix(sub, "?(") && strings.HasSuffix(sub, ")"):
+				filter, err := parseJSONPathFilter(sub[2 : len(sub)-1])
+				if err != nil {
+					return jsonPathSegment{}, err
+				}
+				steps = append(steps, jsonPathStep{filter: filter})
+			case len(sub) >= 2 && (sub[0] == '\'' || sub[0] == '"') && sub[len(sub)-1] == sub[0]:
+				steps = append(steps, jsonPathStep{field: sub[1 : len(sub)-1]})
+			default:
+				i, err := strconv.Atoi(sub)
+				if err != nil {
+					return jsonPathSegment{}, fmt.Errorf("jsonpath: unsupported subscript [%s]", sub)
+				}
+				steps = append(steps, jsonPathStep{index: i, isIndex: true})
+			}
+		default:
+			return jsonPathSegment{}, fmt.Errorf("jsonpath: unexpected %q, expressions must start with '.'", expr)
+		}
+	}
+	return jsonPathSegment{steps: steps}, nil
+}
+
+// parseJSONPathFilter parses "@.path==value" or "@.path!=value".
+func parseJSONPathFilter(expr string) (*jsonPathFilter, error) {
+	i, negate := indexUnquoted(expr, "=="), false
+	if j := indexUnquoted(expr, "!="); j >= 0 && (i < 0 || j < i) {
+		i, negate = j, true
+	}
+	var lhs, rhs string
+	if i >= 0 {
+		lhs, rhs = strings.TrimSpace(expr[:i]), strings.TrimSpace(expr[i+2:])
+	}
+	if i < 0 || !strings.HasPrefix(lhs, "@") {
+		return nil, fmt.Errorf("jsonpath: unsupported filter ?(%s), use ?(@.field==value)", expr)
+	}
+	path, err := parseJSONPathAction(lhs)
+	if err != nil {
+		return nil, err
+	}
+	if len(rhs) >= 2 && (rhs[0] == '\'' || rhs[0] == '"') && rhs[len(rhs)-1] == rhs[0] {
+		rhs = rhs[1 : len(rhs)-1]
+	}
+	return &jsonPathFilter{path: path.steps, value: rhs, negate: negate}, nil
+}
+
+// closingBracket returns the index of the bracket closing the one at s[0],
+// e.g. the "}" of an action or the "]" of a subscript, or -1. Nested
+// brackets of the same kind and quoted strings are skipped, so that
+// "[?(@.a[0]=='x')]" and "{'}'}" parse as a whole.
+func closingBracket(s string) int {
+	left, right := s[0], byte('}')
+	if left == '[' {
+		right = ']'
+	}
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"':
+			end := closingQuote(s, i)
+			if end < 0 {
+				return -1
+			}
+			i = end
+		case left:
+			depth++
+		case right:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// closingQuote returns the index of the quote closing the one at s[i],
+// skipping backslash escapes, or -1.
+func closingQuote(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case s[i]:
+			return j
+		}
+	}
+	return -1
+}
+
+// indexUnquoted is strings.Index ignoring matches inside quoted strings.
+func indexUnquoted(s, substr string) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'' || s[i] == '"':
+			end := closingQuote(s, i)
+			if end < 0 {
+				return -1
+			}
+			i = end
+		case strings.HasPrefix(s[i:], substr):
+			return i
+		}
+	}
+	return -1
+}
+
+func (jp *JSONPathOutput) OutputParam(par bpfsv1.Parameter, w io.Writer) error {
+	return jp.execute(bpfsv1.TypedParameter{Parameter: par}, w)
+}
+
+func (jp *JSONPathOutput) OutputReport(r *bpfsv1.Report, w io.Writer) error {
+	return jp.execute(r, w)
+}
+
+func (jp *JSONPathOutput) execute(v interface{}, w io.Writer) error {
+	obj, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, seg := range jp.segments {
+		if seg.steps == nil {
+			buf.WriteString(seg.literal)
+			continue
+		}
+		results, err := jp.eval(obj, seg.steps)
+		if err != nil {
+			return err
+		}
+		for i, r := range results {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			if err := writeJSONPathValue(&buf, r); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func (jp *JSONPathOutput) eval(root interface{}, steps []jsonPathStep) ([]interface{}, error) {
+	cur := []interface{}{root}
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range cur {
+			switch {
+			case step.filter != nil:
+				arr, _ := v.([]interface{})
+				for _, e := range arr {
+					if step.filter.match(e) {
+						next = append(next, e)
+					}
+				}
+			case step.wildcard:
+				switch t := v.(type) {
+				case []interface{}:
+					next = append(next, t...)
+				case map[string]interface{}:
+					for _, k := range sortedKeys(t) {
+						next = append(next, t[k])
+					}
+				}
+			case step.isIndex:
+				arr, ok := v.([]interface{})
+				i := step.index
+				if ok && i < 0 {
+					i += len(arr)
+				}
+				if !ok || i < 0 || i >= len(arr) {
+					if jp.allowMissingKeys {
+						continue
+					}
+					return nil, fmt.Errorf("jsonpath: index [%d] is out of range", step.index)
+				}
+				next = append(next, arr[i])
+			default:
+				m, ok := v.(map[string]interface{})
+				val, found := m[step.field]
+				if !ok || !found {
+					if jp.allowMissingKeys {
+						continue
+					}
+					return nil, fmt.Errorf("jsonpath: %s is not found", step.field)
+				}
+				next = append(next, val)
+			}
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+func (f *jsonPathFilter) match(v interface{}) bool {
+	lenient := &JSONPathOutput{allowMissingKeys: true}
+	results, _ := lenient.eval(v, f.path)
+	found := false
+	for _, r := range results {
+		var buf bytes.Buffer
+		if err := writeJSONPathValue(&buf, r); err == nil && buf.String() == f.value {
+			found = true
+		}
+	}
+	return found != f.negate
+}
+
+// writeJSONPathValue prints scalars verbatim and composite values as JSON.
+func writeJSONPathValue(w *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		w.WriteString(t)
+	case json.Number:
+		w.WriteString(t.String())
+	case bool:
+		w.WriteString(strconv.FormatBool(t))
+	default:
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		w.Write(data)
+	}
+	return nil
+}
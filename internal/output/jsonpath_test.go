@@ -0,0 +1,96 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+const jsonPathInput = `{
+	"id": 7,
+	"kind": "report",
+	"labels": {"app.kubernetes.io/name": "xdp", "team": "net"},
+	"parameters": [
+		{"kind": "latency", "mean_ns": 120, "percentiles_ns": {"p50": 100, "p99": 300}, "tags": ["a", "b"]},
+		{"kind": "cpu", "mean": 0.25, "tags": ["c"]},
+		{"kind": "health", "runs": 1000, "tags": ["a"], "note": "x}y"}
+	]
+}`
+
+func TestJSONPath(t *testing.T) {
+	var input interface{}
+	if err := json.Unmarshal([]byte(jsonPathInput), &input); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		tmpl         string
+		allowMissing bool
+		want         string
+		wantErr      bool
+	}{
+		{name: "field", tmpl: "{.id}", want: "7"},
+		{name: "nested field", tmpl: "{.parameters[0].percentiles_ns.p99}", want: "300"},
+		{name: "root", tmpl: "{$.kind}", want: "report"},
+		{name: "object as json", tmpl: "{.parameters[0].percentiles_ns}", want: `{"p50":100,"p99":300}`},
+		{name: "quoted field", tmpl: "{.labels['app.kubernetes.io/name']}", want: "xdp"},
+		{name: "double quoted field", tmpl: `{.labels["team"]}`, want: "net"},
+		{name: "negative index", tmpl: "{.parameters[-1].kind}", want: "health"},
+		{name: "array wildcard", tmpl: "{.parameters[*].kind}", want: "latency cpu health"},
+		{name: "map wildcard", tmpl: "{.labels.*}", want: "xdp net"},
+		{name: "equality filter", tmpl: "{.parameters[?(@.kind=='cpu')].mean}", want: "0.25"},
+		{name: "double quoted filter", tmpl: `{.parameters[?(@.kind=="latency")].mean_ns}`, want: "120"},
+		{name: "inequality filter", tmpl: "{.parameters[?(@.kind!='cpu')].kind}", want: "latency health"},
+		{name: "filter on a subscript", tmpl: "{.parameters[?(@.tags[0]=='a')].kind}", want: "latency health"},
+		{name: "filter on a value with brackets", tmpl: "{.parameters[?(@.note=='x}y')].runs}", want: "1000"},
+		{name: "filter on an operator", tmpl: "{.parameters[?(@.kind=='a==b')].kind}", want: ""},
+		{name: "literals", tmpl: `id={.id}{'\t'}{"}"}{'\n'}`, want: "id=7\t}\n"},
+		{name: "plain text", tmpl: "no actions", want: "no actions"},
+
+		{name: "missing key", tmpl: "{.nope}", wantErr: true},
+		{name: "missing key allowed", tmpl: "a{.nope}b", allowMissing: true, want: "ab"},
+		{name: "index out of range", tmpl: "{.parameters[5]}", wantErr: true},
+		{name: "index out of range allowed", tmpl: "{.parameters[-5].kind}", allowMissing: true, want: ""},
+		{name: "missing in some elements", tmpl: "{.parameters[*].runs}", wantErr: true},
+		{name: "missing in some elements allowed", tmpl: "{.parameters[*].runs}", allowMissing: true, want: "1000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jp, err := NewJSONPathOutput(tt.tmpl, OutputOptions{AllowMissingKeys: tt.allowMissing})
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.tmpl, err)
+			}
+			var buf bytes.Buffer
+			err = jp.execute(input, &buf)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("%q printed %q, want an error", tt.tmpl, buf.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("execute %q: %v", tt.tmpl, err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("%q printed %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathParseErrors(t *testing.T) {
+	for _, tmpl := range []string{
+		"{.id",
+		"{.parameters[0}",
+		"{.parameters[?(@.kind=='cpu')}",
+		"{'unterminated}",
+		"{id}",
+		"{.parameters[one]}",
+		"{.parameters[?(@.kind)]}",
+	} {
+		if _, err := NewJSONPathOutput(tmpl, OutputOptions{}); err == nil {
+			t.Errorf("NewJSONPathOutput(%q) succeeded, want an error", tmpl)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+func init() {
+	Register("markdown", noArg("markdown", func(OutputOptions) Printer { return &MarkdownOutput{} }))
+}
+
+// MarkdownOutput writes GitHub-flavored Markdown tables, e.g. for pasting a
+// comparison into a pull request. Comparisons get one table per metric;
+// other parameters are listed as field/value tables without their timelines.
+type MarkdownOutput struct{}
+
+func (m *MarkdownOutput) OutputReport(r *bpfsv1.Report, w io.Writer) error {
+	for _, p := range r.Params() {
+		if err := m.OutputParam(p, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MarkdownOutput) OutputParam(par bpfsv1.Parameter, w io.Writer) error {
+	if c, ok := par.(bpfsv1.Comparison); ok {
+		return m.outputComparison(c, w)
+	}
+
+	obj, err := toGeneric(bpfsv1.TypedParameter{Parameter: par})
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### %s\n\n", par.Kind()))
+	sb.WriteString("| Field | Value |\n|---|---|\n")
+	flatten("", obj, func(field, value string) {
+		if field == "kind" || isTimelineField(field) {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s |\n", markdownEscape(field), markdownEscape(value)))
+	})
+	sb.WriteString("\n")
+
+	_, err = w.Write([]byte(sb.String()))
+	return err
+}
+
+func (m *MarkdownOutput) outputComparison(c bpfsv1.Comparison, w io.Writer) error {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("### %s vs %s\n\n", markdownEscape(c.Baseline), markdownEscape(c.Candidate)))
+	sb.WriteString(fmt.Sprintf("%s test, alpha=%g, %g%% bootstrap intervals (%d resamples).\n\n",
+		c.Test, c.Alpha, c.Confidence*100, c.Resamples))
+
+	for _, mc := range c.Metrics {
+		if mc.SummaryOnly {
+			sb.WriteString(fmt.Sprintf("**%s** (n=%d+%d, summaries only, not tested)\n\n", comparisonTitle(mc),
+				mc.BaselineSamples, mc.CandidateSamples))
+		} else {
+			verdict := "significant"
+			if !mc.Significant {
+				verdict = "not significant"
+			}
+			sb.WriteString(fmt.Sprintf("**%s** (p=%.4f, n=%d+%d, %s)\n\n", comparisonTitle(mc),
+				mc.PValue, mc.BaselineSamples, mc.CandidateSamples, verdict))
+		}
+		sb.WriteString("| Stat | Baseline | Candidate | Delta | CI |\n")
+		sb.WriteString("|---|--:|--:|--:|--:|\n")
+		for _, s := range mc.Statistics {
+			delta := formatSigned(s.Delta, mc.Unit)
+			if s.DeltaPct != nil {
+				delta = fmt.Sprintf("%+.2f%%", *s.DeltaPct)
+			}
+			ci := fmt.Sprintf("[%s, %s]", formatSigned(s.CILow, mc.Unit), formatSigned(s.CIHigh, mc.Unit))
+			switch {
+			case mc.SummaryOnly:
+				ci = "-"
+			case !mc.Significant:
+				delta = "~"
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n", s.Name,
+				formatValue(s.Baseline, mc.Unit), formatValue(s.Candidate, mc.Unit), delta, ci))
+		}
+		sb.WriteString("\n")
+	}
+
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
+// isTimelineField reports whether a flattened field lies inside a timeline
+// array, e.g. "timeline_ns.3" or "metrics.ipc.timeline.0".
+func isTimelineField(field string) bool {
+	for _, part := range strings.Split(field, ".") {
+		if strings.HasPrefix(part, "timeline") {
+			return true
+		}
+	}
+	return false
+}
+
+// markdownEscape keeps values from breaking table cells.
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
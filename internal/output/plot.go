@@ -0,0 +1,178 @@
+package output
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Glyph ramps used by the plots, from empty to full. Unicode blocks are used on
+// terminals; plain ASCII keeps redirected output readable in any viewer.
+var (
+	unicodeLevels = []rune(" ▁▂▃▄▅▆▇█")
+	asciiLevels   = []rune(" .:-=+*#%@")
+
+	unicodeEighths = []rune(" ▏▎▍▌▋▊▉█")
+)
+
+const (
+	histogramBins = 16
+	cdfHeight     = 8
+	minPlotWidth  = 20
+)
+
+// plotter renders small text plots of a sample set within a column budget.
+type plotter struct {
+	width   int
+	unicode bool
+}
+
+func (p plotter) levels() []rune {
+	if p.unicode {
+		return unicodeLevels
+	}
+	return asciiLevels
+}
+
+// bar renders a horizontal bar of frac (0..1) over width columns.
+func (p plotter) bar(frac float64, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	frac = math.Max(0, math.Min(1, frac))
+	if !p.unicode {
+		return strings.Repeat("#", int(math.Round(frac*float64(width))))
+	}
+	eighths := int(math.Round(frac * float64(width) * 8))
+	full, rem := eighths/8, eighths%8
+	s := strings.Repeat(string(unicodeEighths[8]), full)
+	if rem > 0 {
+		s += string(unicodeEighths[rem])
+	}
+	return s
+}
+
+// histogram renders a linear-bin histogram with one row per bin.
+func (p plotter) histogram(samples []float64, format func(float64) string) string {
+	lo, hi := minMax(samples)
+	bins := histogramBins
+	if hi == lo {
+		bins = 1
+	}
+	counts := make([]int, bins)
+	step := (hi - lo) / float64(bins)
+	for _, v := range samples {
+		i := 0
+		if step > 0 {
+			i = int((v - lo) / step)
+		}
+		if i >= bins {
+			i = bins - 1
+		}
+		counts[i]++
+	}
+
+	labels := make([]string, bins)
+	labelWidth, maxCount := 0, 0
+	for i := range counts {
+		labels[i] = fmt.Sprintf("%s - %s", format(lo+float64(i)*step), format(lo+float64(i+1)*step))
+		labelWidth = max(labelWidth, len([]rune(labels[i])))
+		maxCount = max(maxCount, counts[i])
+	}
+	countWidth := len(fmt.Sprint(maxCount))
+	barWidth := max(p.width-labelWidth-countWidth-4, minPlotWidth/2)
+
+	var sb strings.Builder
+	for i, c := range counts {
+		fmt.Fprintf(&sb, "%*s | %*d %s\n",
+			labelWidth, labels[i], countWidth, c,
+			p.bar(float64(c)/float64(maxCount), barWidth))
+	}
+	return sb.String()
+}
+
+// cdf renders the empirical cumulative distribution as a filled area chart.
+func (p plotter) cdf(samples []float64, format func(float64) string) string {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+
+	const axisWidth = 6 // "100% |"
+	cols := max(p.width-axisWidth, minPlotWidth)
+	frac := make([]float64, cols)
+	for c := range frac {
+		v := hi
+		if cols > 1 {
+			v = lo + (hi-lo)*float64(c)/float64(cols-1)
+		}
+		n := sort.Search(len(sorted), func(i int) bool { return sorted[i] > v })
+		frac[c] = float64(n) / float64(len(sorted))
+	}
+
+	levels := p.levels()
+	top := len(levels) - 1
+	var sb strings.Builder
+	for r := cdfHeight - 1; r >= 0; r-- {
+		switch r {
+		case cdfHeight - 1:
+			sb.WriteString("100% |")
+		case 0:
+			sb.WriteString("  0% |")
+		default:
+			sb.WriteString("     |")
+		}
+		for _, f := range frac {
+			fill := math.Max(0, math.Min(1, f*cdfHeight-float64(r)))
+			sb.WriteRune(levels[int(math.Round(fill*float64(top)))])
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(axisLabels(axisWidth, cols, format(lo), format(hi)))
+	return sb.String()
+}
+
+// sparkline renders the timeline as a single row, averaging samples that fall
+// into the same column when the timeline is longer than the column budget.
+func (p plotter) sparkline(timeline []float64, format func(float64) string) string {
+	cols := min(len(timeline), max(p.width, minPlotWidth))
+	points := make([]float64, cols)
+	for c := range points {
+		from := c * len(timeline) / cols
+		to := max((c+1)*len(timeline)/cols, from+1)
+		sum := 0.0
+		for _, v := range timeline[from:to] {
+			sum += v
+		}
+		points[c] = sum / float64(to-from)
+	}
+
+	lo, hi := minMax(points)
+	levels := p.levels()[1:] // a sparkline never renders blanks
+	var sb strings.Builder
+	for _, v := range points {
+		i := len(levels) / 2
+		if hi > lo {
+			i = int(math.Round((v - lo) / (hi - lo) * float64(len(levels)-1)))
+		}
+		sb.WriteRune(levels[i])
+	}
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "range: %s - %s over %d intervals\n", format(lo), format(hi), len(timeline))
+	return sb.String()
+}
+
+// axisLabels places lo at the left and hi at the right edge of the plot area.
+func axisLabels(indent, cols int, lo, hi string) string {
+	gap := max(cols-len([]rune(lo))-len([]rune(hi)), 1)
+	return strings.Repeat(" ", indent) + lo + strings.Repeat(" ", gap) + hi + "\n"
+}
+
+func minMax(vals []float64) (float64, float64) {
+	lo, hi := vals[0], vals[0]
+	for _, v := range vals[1:] {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	return lo, hi
+}
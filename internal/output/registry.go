@@ -0,0 +1,83 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PrinterFactory builds a printer. arg is the text after "=" in a format
+// string such as "jsonpath={.id}", and is empty for plain formats.
+type PrinterFactory func(arg string, opts OutputOptions) (Printer, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]PrinterFactory{}
+)
+
+// Register makes a printer available under name. It panics if name is already
+// registered, mirroring database/sql.Register.
+func Register(name string, factory PrinterFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("output: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("output: Register called twice for printer " + name)
+	}
+	registry[name] = factory
+}
+
+// Formats returns the sorted names of the registered printers.
+func Formats() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewPrinter returns the printer for a format string of the form "name" or
+// "name=arg", e.g. "json", "csv" or "template={{.mean_ns}}".
+// An empty format selects the text printer.
+func NewPrinter(format string, opts OutputOptions) (Printer, error) {
+	name, arg, _ := strings.Cut(format, "=")
+	if name == "" {
+		name = "text"
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q (allowed: %s)", name, strings.Join(Formats(), ", "))
+	}
+	return factory(arg, opts)
+}
+
+// requireArg wraps factories for formats that take a mandatory argument.
+func requireArg(name string, factory PrinterFactory) PrinterFactory {
+	return func(arg string, opts OutputOptions) (Printer, error) {
+		if arg == "" {
+			return nil, fmt.Errorf("%s format specified but no %s given, use -o %s=...", name, name, name)
+		}
+		return factory(arg, opts)
+	}
+}
+
+// noArg wraps factories for formats that do not take an argument.
+func noArg(name string, factory func(OutputOptions) Printer) PrinterFactory {
+	return func(arg string, opts OutputOptions) (Printer, error) {
+		if arg != "" {
+			return nil, fmt.Errorf("output format %s does not take an argument", name)
+		}
+		return factory(opts), nil
+	}
+}
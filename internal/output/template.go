@@ -0,0 +1,86 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/template"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+)
+
+func init() {
+	factory := func(arg string, opts OutputOptions) (Printer, error) {
+		return NewTemplateOutput(arg, opts)
+	}
+	Register("template", requireArg("template", factory))
+	Register("go-template", requireArg("go-template", factory))
+}
+
+// TemplateOutput executes a Go text/template against the JSON form of a
+// parameter, so field names match the JSON output (e.g. {{.mean_ns}}).
+type TemplateOutput struct {
+	tmpl *template.Template
+}
+
+// NewTemplateOutput parses a Go template.
+func NewTemplateOutput(text string, opts OutputOptions) (*TemplateOutput, error) {
+	missingKey := "missingkey=error"
+	if opts.AllowMissingKeys {
+		missingKey = "missingkey=zero"
+	}
+	tmpl, err := template.New("output").Option(missingKey).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	return &TemplateOutput{tmpl: tmpl}, nil
+}
+
+func (t *TemplateOutput) OutputParam(par bpfsv1.Parameter, w io.Writer) error {
+	return t.execute(bpfsv1.TypedParameter{Parameter: par}, w)
+}
+
+func (t *TemplateOutput) OutputReport(r *bpfsv1.Report, w io.Writer) error {
+	return t.execute(r, w)
+}
+
+func (t *TemplateOutput) execute(v interface{}, w io.Writer) error {
+	obj, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	// Render into a buffer so a failing template does not emit partial output
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, obj); err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// toGeneric converts a value into its JSON object model (maps, slices and
+// json.Number), which is what templates and JSONPath expressions address.
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,26 @@
+package output
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// defaultWidth is used when the writer is not a terminal and $COLUMNS is unset.
+const defaultWidth = 80
+
+// TerminalWidth reports the column width of w and whether w is a terminal.
+// Non-terminal writers fall back to $COLUMNS, then to defaultWidth.
+func TerminalWidth(w io.Writer) (int, bool) {
+	if f, ok := w.(*os.File); ok {
+		if ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ); err == nil && ws.Col > 0 {
+			return int(ws.Col), true
+		}
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols, false
+	}
+	return defaultWidth, false
+}
@@ -3,13 +3,31 @@ package output
 import (
 	"fmt"
 	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
 )
 
-type TextOutput struct{}
+func init() {
+	Register("text", noArg("text", func(opts OutputOptions) Printer {
+		return &TextOutput{Plot: opts.Plot, Width: opts.Width, Unicode: opts.Unicode}
+	}))
+}
+
+type TextOutput struct {
+	// Plot renders a histogram, CDF and timeline sparkline for latency.
+	Plot bool
+	// Width is the number of columns available to plots (0 => 80).
+	Width int
+	// Unicode selects block glyphs for plots instead of plain ASCII.
+	Unicode bool
+}
 
 func (t *TextOutput) OutputParam(par bpfsv1.Parameter, w io.Writer) error {
 	switch par.Kind() {
@@ -17,11 +35,177 @@ func (t *TextOutput) OutputParam(par bpfsv1.Parameter, w io.Writer) error {
 		return t.outputLatency(par.(bpfsv1.Latency), w)
 	case "cpu":
 		return t.outputCpu(par.(bpfsv1.Cpu), w)
+	case "health":
+		return t.outputHealth(par.(bpfsv1.Health), w)
+	case "throughput":
+		return t.outputThroughput(par.(bpfsv1.Throughput), w)
+	case "profile":
+		return t.outputProfile(par.(bpfsv1.Profile), w)
+	case "bench":
+		return t.outputBench(par.(bpfsv1.Bench), w)
+	case "comparison":
+		return t.outputComparison(par.(bpfsv1.Comparison), w)
+	case "ab":
+		return t.outputAB(par.(bpfsv1.ABTest), w)
+	case "trials":
+		return t.outputTrials(par.(bpfsv1.Trials), w)
 	default:
 		return fmt.Errorf("unsupported parameter kind: %s", par.Kind())
 	}
 }
 
+func (t *TextOutput) OutputReport(r *bpfsv1.Report, w io.Writer) error {
+	var sb strings.Builder
+	meta := r.Metadata
+
+	sb.WriteString("=== Run ===\n\n")
+	if len(meta.CommandLine) > 0 {
+		sb.WriteString(fmt.Sprintf("Command: %s\n", strings.Join(meta.CommandLine, " ")))
+	}
+	if meta.Host != "" {
+		sb.WriteString(fmt.Sprintf("Host: %s\n", meta.Host))
+	}
+	if meta.Kernel != "" {
+		sb.WriteString(fmt.Sprintf("Kernel: %s\n", meta.Kernel))
+	}
+	if meta.ToolVersion != "" {
+		sb.WriteString(fmt.Sprintf("bpfstats: %s\n", meta.ToolVersion))
+	}
+	if meta.Started != nil {
+		sb.WriteString(fmt.Sprintf("Started: %s\n", meta.Started.Format(time.RFC3339)))
+	}
+	if meta.Ended != nil {
+		sb.WriteString(fmt.Sprintf("Ended: %s\n", meta.Ended.Format(time.RFC3339)))
+	}
+	sb.WriteString("\n")
+
+	for i := range meta.Programs {
+		writeProgram(&sb, &meta.Programs[i])
+	}
+	if env := meta.Environment; env != nil {
+		writeEnvironment(&sb, env)
+	}
+	if in := meta.Integrity; in != nil {
+		writeIntegrity(&sb, in)
+	}
+
+	if _, err := w.Write([]byte(sb.String())); err != nil {
+		return err
+	}
+	for _, p := range r.Params() {
+		if err := t.OutputParam(p, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeProgram(sb *strings.Builder, p *bpfsv1.Program) {
+	sb.WriteString(fmt.Sprintf("--- Program %d ---\n", p.ID))
+	if p.Name != "" {
+		sb.WriteString(fmt.Sprintf("Name: %s\n", p.Name))
+	}
+	if p.Type != "" {
+		sb.WriteString(fmt.Sprintf("Type: %s\n", p.Type))
+	}
+	if p.Tag != "" {
+		sb.WriteString(fmt.Sprintf("Tag: %s\n", p.Tag))
+	}
+	if p.BTFID != nil {
+		sb.WriteString(fmt.Sprintf("BTF ID: %d\n", *p.BTFID))
+	}
+	if p.LoadedAt != nil {
+		sb.WriteString(fmt.Sprintf("Loaded: %s\n", p.LoadedAt.Format(time.RFC3339)))
+	}
+	if p.XlatedSize != nil {
+		sb.WriteString(fmt.Sprintf("Xlated: %d bytes\n", *p.XlatedSize))
+	}
+	if p.JitedSize != nil {
+		sb.WriteString(fmt.Sprintf("JITed: %d bytes\n", *p.JitedSize))
+	}
+	if p.VerifiedInstructions != nil {
+		sb.WriteString(fmt.Sprintf("Verified insns: %d\n", *p.VerifiedInstructions))
+	}
+	if len(p.MapIDs) > 0 {
+		ids := make([]string, len(p.MapIDs))
+		for i, id := range p.MapIDs {
+			ids[i] = fmt.Sprint(id)
+		}
+		sb.WriteString(fmt.Sprintf("Maps: %s\n", strings.Join(ids, ", ")))
+	}
+	for _, l := range p.Links {
+		sb.WriteString(fmt.Sprintf("Link: %d (%s)\n", l.ID, l.Type))
+	}
+	sb.WriteString("\n")
+}
+
+func writeEnvironment(sb *strings.Builder, env *bpfsv1.Environment) {
+	sb.WriteString("--- Environment ---\n")
+	if env.CPUModel != "" {
+		sb.WriteString(fmt.Sprintf("CPU: %s\n", env.CPUModel))
+	}
+	if env.OnlineCPUs > 0 {
+		sb.WriteString(fmt.Sprintf("Online CPUs: %d\n", env.OnlineCPUs))
+	}
+	if env.AvailableCPUs > 0 {
+		sb.WriteString(fmt.Sprintf("Available CPUs: %d\n", env.AvailableCPUs))
+	}
+	if env.SMT != "" {
+		sb.WriteString(fmt.Sprintf("SMT: %s\n", env.SMT))
+	}
+	if len(env.Governors) > 0 {
+		sb.WriteString(fmt.Sprintf("Governor: %s\n", strings.Join(env.Governors, ", ")))
+	}
+	if env.Turbo != nil {
+		sb.WriteString(fmt.Sprintf("Turbo: %t\n", *env.Turbo))
+	}
+	if env.IsolatedCPUs != "" {
+		sb.WriteString(fmt.Sprintf("Isolated CPUs: %s\n", env.IsolatedCPUs))
+	}
+	if len(env.NUMANodes) > 0 {
+		nodes := make([]string, len(env.NUMANodes))
+		for i, n := range env.NUMANodes {
+			nodes[i] = fmt.Sprintf("node%d=%s", n.ID, n.CPUs)
+		}
+		sb.WriteString(fmt.Sprintf("NUMA: %s\n", strings.Join(nodes, " ")))
+	}
+	for _, s := range []struct {
+		name string
+		val  *int
+	}{
+		{"bpf_jit_enable", env.BPFJITEnable},
+		{"bpf_jit_harden", env.BPFJITHarden},
+		{"bpf_jit_kallsyms", env.BPFJITKallsyms},
+		{"bpf_stats_enabled", env.BPFStatsEnabled},
+	} {
+		if s.val != nil {
+			sb.WriteString(fmt.Sprintf("%s: %d\n", s.name, *s.val))
+		}
+	}
+	for _, w := range env.Warnings {
+		sb.WriteString(fmt.Sprintf("Warning: %s\n", w))
+	}
+	sb.WriteString("\n")
+}
+
+func writeIntegrity(sb *strings.Builder, in *bpfsv1.Integrity) {
+	sb.WriteString("--- Integrity ---\n")
+	sb.WriteString(fmt.Sprintf("Polls: %d\n", in.Polls))
+	sb.WriteString(fmt.Sprintf("Failed polls: %d (%.2f%%)\n", in.FailedPolls, 100*in.ErrorRate))
+	sb.WriteString(fmt.Sprintf("Missing-program intervals: %d\n", in.MissingProgram))
+	sb.WriteString(fmt.Sprintf("Zero-activity intervals: %d\n", in.ZeroActivity))
+	if in.CollectorErrors > 0 {
+		sb.WriteString(fmt.Sprintf("Collector errors: %d\n", in.CollectorErrors))
+	}
+	for _, e := range in.Errors {
+		sb.WriteString(fmt.Sprintf("Error (x%d): %s\n", e.Count, e.Message))
+	}
+	for _, w := range in.Warnings {
+		sb.WriteString(fmt.Sprintf("Warning: %s\n", w))
+	}
+	sb.WriteString("\n")
+}
+
 func (t *TextOutput) outputLatency(lat bpfsv1.Latency, w io.Writer) error {
 	var sb strings.Builder
 
@@ -72,22 +256,36 @@ func (t *TextOutput) outputLatency(lat bpfsv1.Latency, w io.Writer) error {
 	// Percentiles
 	if lat.Percentiles != nil && len(*lat.Percentiles) > 0 {
 		sb.WriteString("--- Percentiles ---\n")
-		// Common ordering for percentiles
-		order := []string{"p50", "p90", "p95", "p99", "p99_9", "p99_99"}
-		for _, key := range order {
-			if val, ok := (*lat.Percentiles)[key]; ok {
-				sb.WriteString(fmt.Sprintf("%s: %s\n", key, formatNanos(val)))
-			}
+		for _, key := range percentileKeys(*lat.Percentiles) {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", key, formatNanos((*lat.Percentiles)[key])))
 		}
-		// Print any remaining percentiles not in standard order
-		for key, val := range *lat.Percentiles {
-			if !contains(order, key) {
-				sb.WriteString(fmt.Sprintf("%s: %s\n", key, formatNanos(val)))
+		sb.WriteString("\n")
+	}
+
+	// Recent windows against the whole run
+	if len(lat.Windows) > 0 {
+		total := bpfsv1.WindowStats{Samples: lat.Samples, Mean: float64(lat.Mean), StdDev: float64(lat.StdDev)}
+		if lat.Percentiles != nil {
+			total.Percentiles = make(map[string]float64, len(*lat.Percentiles))
+			for k, v := range *lat.Percentiles {
+				total.Percentiles[k] = float64(v)
 			}
 		}
+		writeWindows(&sb, lat.Windows, total, func(v float64) string { return formatNanos(uint64(v)) })
+	}
+
+	// Per return code
+	if len(lat.ByReturnCode) > 0 {
+		sb.WriteString("--- By Return Code ---\n")
+		writeReturnCodes(&sb, lat.ByReturnCode)
 		sb.WriteString("\n")
 	}
 
+	// Plots
+	if t.Plot && len(lat.Timeline) > 0 {
+		t.writeLatencyPlots(&sb, lat.Timeline)
+	}
+
 	// Metadata
 	if lat.Clock != nil || lat.Histogram != nil {
 		sb.WriteString("--- Measurement Info ---\n")
@@ -103,6 +301,124 @@ func (t *TextOutput) outputLatency(lat bpfsv1.Latency, w io.Writer) error {
 	return err
 }
 
+func (t *TextOutput) writeLatencyPlots(sb *strings.Builder, timeline []uint64) {
+	width := t.Width
+	if width <= 0 {
+		width = defaultWidth
+	}
+	p := plotter{width: width, unicode: t.Unicode}
+
+	samples := make([]float64, len(timeline))
+	for i, v := range timeline {
+		samples[i] = float64(v)
+	}
+	format := func(v float64) string { return formatNanos(uint64(v)) }
+
+	sb.WriteString("--- Distribution ---\n")
+	sb.WriteString(p.histogram(samples, format))
+	sb.WriteString("\n")
+
+	sb.WriteString("--- CDF ---\n")
+	sb.WriteString(p.cdf(samples, format))
+	sb.WriteString("\n")
+
+	sb.WriteString("--- Timeline ---\n")
+	sb.WriteString(p.sparkline(samples, format))
+	sb.WriteString("\n")
+}
+
+// writeReturnCodes prints one row per return code, most frequent first.
+func writeReturnCodes(sb *strings.Builder, byRetval map[string]bpfsv1.ReturnCodeLatency) {
+	codes := make([]bpfsv1.ReturnCodeLatency, 0, len(byRetval))
+	for _, rc := range byRetval {
+		codes = append(codes, rc)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if codes[i].Runs != codes[j].Runs {
+			return codes[i].Runs > codes[j].Runs
+		}
+		return codes[i].Retval < codes[j].Retval
+	})
+
+	var pkeys []string
+	if ps := codes[0].Percentiles; ps != nil {
+		pkeys = percentileKeys(*ps)
+	}
+
+	tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "RETVAL\tVERDICT\tSHARE\tRUNS\tMEAN\tSTDDEV")
+	for _, k := range pkeys {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(k))
+	}
+	fmt.Fprintln(tw, "\t")
+	for _, rc := range codes {
+		verdict := rc.Verdict
+		if verdict == "" {
+			verdict = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%.1f%%\t%d\t%s\t%s", rc.Retval, verdict, rc.Share*100, rc.Runs,
+			formatNanos(rc.Mean), formatNanos(rc.StdDev))
+		for _, k := range pkeys {
+			v := "-"
+			if rc.Percentiles != nil {
+				if pv, ok := (*rc.Percentiles)[k]; ok {
+					v = formatNanos(pv)
+				}
+			}
+			fmt.Fprintf(tw, "\t%s", v)
+		}
+		fmt.Fprintln(tw, "\t")
+	}
+	tw.Flush()
+}
+
+// writeWindows prints one row per recent window, shortest first, followed by
+// the whole run as "since start".
+func writeWindows(sb *strings.Builder, windows map[string]bpfsv1.WindowStats, total bpfsv1.WindowStats, format func(float64) string) {
+	names := make([]string, 0, len(windows))
+	for name := range windows {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := windows[names[i]], windows[names[j]]
+		if a.Span != b.Span {
+			return a.Span < b.Span
+		}
+		return names[i] < names[j]
+	})
+
+	pkeys := percentileKeys(total.Percentiles)
+
+	sb.WriteString("--- Recent Windows ---\n")
+	tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "WINDOW\tSAMPLES\tMEAN\tSTDDEV")
+	for _, k := range pkeys {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(k))
+	}
+	fmt.Fprintln(tw, "\t")
+	row := func(name string, ws bpfsv1.WindowStats) {
+		mean, stddev := "-", "-"
+		if ws.Samples > 0 {
+			mean, stddev = format(ws.Mean), format(ws.StdDev)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s", name, ws.Samples, mean, stddev)
+		for _, k := range pkeys {
+			v := "-"
+			if pv, ok := ws.Percentiles[k]; ok {
+				v = format(pv)
+			}
+			fmt.Fprintf(tw, "\t%s", v)
+		}
+		fmt.Fprintln(tw, "\t")
+	}
+	for _, name := range names {
+		row(strings.ReplaceAll(name, "_", " "), windows[name])
+	}
+	row("since start", total)
+	tw.Flush()
+	sb.WriteString("\n")
+}
+
 // formatNanos converts nanoseconds to a human-readable duration string
 func formatNanos(ns uint64) string {
 	d := time.Duration(ns)
@@ -119,13 +435,19 @@ func formatNanos(ns uint64) string {
 	}
 }
 
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
-		}
+// percentileKeys returns the keys of a percentile map ("p50", "p99_9", ...)
+// in ascending percentile order.
+func percentileKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	value := func(key string) float64 {
+		p, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimPrefix(key, "p"), "_", "."), 64)
+		return p
 	}
-	return false
+	sort.Slice(keys, func(i, j int) bool { return value(keys[i]) < value(keys[j]) })
+	return keys
 }
 
 func (t *TextOutput) outputCpu(cpu bpfsv1.Cpu, w io.Writer) error {
@@ -160,25 +482,37 @@ func (t *TextOutput) outputCpu(cpu bpfsv1.Cpu, w io.Writer) error {
 	}
 	sb.WriteString("\n")
 
-	// Summary stats (stored as ratios: 0..1)
+	// Summary stats: cores consumed, and share of machine capacity
 	sb.WriteString("--- Summary Statistics ---\n")
-	sb.WriteString(fmt.Sprintf("Mean: %.2f%%\n", 100.0*cpu.Mean))
-	sb.WriteString(fmt.Sprintf("StdDev: %.2f%%\n", 100.0*cpu.StdDev))
+	sb.WriteString(fmt.Sprintf("Machine capacity: %d CPUs\n", cpu.CPUs))
+	sb.WriteString(fmt.Sprintf("Mean: %.4f cores (%.2f%% of machine)\n", cpu.Mean, 100.0*cpu.MachineMean))
+	sb.WriteString(fmt.Sprintf("StdDev: %.4f cores (%.2f%% of machine)\n", cpu.StdDev, 100.0*cpu.MachineStdDev))
 
 	if cpu.CV != nil {
 		sb.WriteString(fmt.Sprintf("CV: %.4f\n", *cpu.CV))
 	}
 
-	if cpu.Min != nil {
-		sb.WriteString(fmt.Sprintf("Min: %.2f%%\n", 100.0*(*cpu.Min)))
+	if cpu.Min != nil && cpu.MachineMin != nil {
+		sb.WriteString(fmt.Sprintf("Min: %.4f cores (%.2f%% of machine)\n", *cpu.Min, 100.0*(*cpu.MachineMin)))
 	}
 
-	if cpu.Max != nil {
-		sb.WriteString(fmt.Sprintf("Max: %.2f%%\n", 100.0*(*cpu.Max)))
+	if cpu.Max != nil && cpu.MachineMax != nil {
+		sb.WriteString(fmt.Sprintf("Max: %.4f cores (%.2f%% of machine)\n", *cpu.Max, 100.0*(*cpu.MachineMax)))
 	}
 
 	sb.WriteString("\n")
 
+	// Recent windows against the whole run
+	if len(cpu.Windows) > 0 {
+		total := bpfsv1.WindowStats{Samples: cpu.Samples, Mean: cpu.Mean, StdDev: cpu.StdDev}
+		writeWindows(&sb, cpu.Windows, total, func(v float64) string { return fmt.Sprintf("%.4f", v) })
+	}
+
+	// Per-CPU breakdown
+	if cpu.PerCPU != nil {
+		writePerCPU(&sb, cpu.PerCPU)
+	}
+
 	// Metadata
 	if cpu.Clock != nil || cpu.Histogram != nil {
 		sb.WriteString("--- Measurement Info ---\n")
@@ -193,3 +527,562 @@ func (t *TextOutput) outputCpu(cpu bpfsv1.Cpu, w io.Writer) error {
 	_, err := w.Write([]byte(sb.String()))
 	return err
 }
+
+func writePerCPU(sb *strings.Builder, b *bpfsv1.PerCPUBreakdown) {
+	sb.WriteString(fmt.Sprintf("--- Per-CPU Breakdown (%s) ---\n", b.Source))
+	tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "CPU\tRuns\tRuntime\tMean\tShare\t")
+	for _, c := range b.CPUs {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%.2f%%\t\n",
+			c.CPU, c.Runs, formatNanos(c.Runtime), formatNanos(c.Mean), 100.0*c.RuntimeShare)
+	}
+	tw.Flush()
+	sb.WriteString(fmt.Sprintf("Active CPUs: %d\n", b.ActiveCPUs))
+	sb.WriteString(fmt.Sprintf("Imbalance (max/mean): %.2f\n", b.Imbalance))
+	sb.WriteString("\n")
+}
+
+func (t *TextOutput) outputHealth(h bpfsv1.Health, w io.Writer) error {
+	var sb strings.Builder
+
+	// Header
+	sb.WriteString("=== Invocation Health ===\n\n")
+
+	// Identity
+	sb.WriteString(fmt.Sprintf("ID: %d\n", h.ID))
+
+	// Measurement window
+	sb.WriteString(fmt.Sprintf("Duration: %s\n", h.Duration))
+	if h.Warmup != nil {
+		sb.WriteString(fmt.Sprintf("Warmup: %s\n", *h.Warmup))
+	}
+	sb.WriteString(fmt.Sprintf("Samples: %d\n", h.Samples))
+	sb.WriteString("\n")
+
+	// Counters
+	sb.WriteString("--- Invocations ---\n")
+	sb.WriteString(fmt.Sprintf("Runs: %d\n", h.Runs))
+	sb.WriteString(fmt.Sprintf("Recursion misses: %d\n", h.RecursionMisses))
+	sb.WriteString(fmt.Sprintf("Run rate: %.2f runs/sec\n", h.RunRate))
+	sb.WriteString(fmt.Sprintf("Miss rate: %.2f misses/sec\n", h.MissRate))
+	sb.WriteString(fmt.Sprintf("Missed: %.4f%%\n", 100.0*h.MissedFraction))
+	if h.RecursionMisses > 0 {
+		sb.WriteString("Warning: the kernel skipped invocations due to recursion; runtime statistics exclude them\n")
+	}
+	sb.WriteString("\n")
+
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
+func (t *TextOutput) outputThroughput(tp bpfsv1.Throughput, w io.Writer) error {
+	var sb strings.Builder
+
+	// Header
+	sb.WriteString("=== Throughput Statistics ===\n\n")
+
+	// Identity
+	sb.WriteString(fmt.Sprintf("ID: %d\n", tp.ID))
+
+	// Measurement window
+	sb.WriteString(fmt.Sprintf("Duration: %s\n", tp.Duration))
+	if tp.Warmup != nil {
+		sb.WriteString(fmt.Sprintf("Warmup: %s\n", *tp.Warmup))
+	}
+	sb.WriteString("\n")
+
+	// Volume / integrity
+	sb.WriteString(fmt.Sprintf("Samples: %d\n", tp.Samples))
+	if tp.Rate != nil {
+		sb.WriteString(fmt.Sprintf("Rate: %.2f samples/sec\n", *tp.Rate))
+	}
+	sb.WriteString("\n")
+
+	// Summary stats
+	sb.WriteString("--- Summary Statistics ---\n")
+	sb.WriteString(fmt.Sprintf("Mean: %.2f runs/sec\n", tp.Mean))
+	sb.WriteString(fmt.Sprintf("StdDev: %.2f runs/sec\n", tp.StdDev))
+	if tp.CV != nil {
+		sb.WriteString(fmt.Sprintf("CV: %.4f\n", *tp.CV))
+	}
+	if tp.Min != nil {
+		sb.WriteString(fmt.Sprintf("Min: %.2f runs/sec\n", *tp.Min))
+	}
+	if tp.Max != nil {
+		sb.WriteString(fmt.Sprintf("Max: %.2f runs/sec\n", *tp.Max))
+	}
+	sb.WriteString("\n")
+
+	// Percentiles
+	if tp.Percentiles != nil && len(*tp.Percentiles) > 0 {
+		sb.WriteString("--- Percentiles ---\n")
+		for _, key := range percentileKeys(*tp.Percentiles) {
+			sb.WriteString(fmt.Sprintf("%s: %.2f runs/sec\n", key, (*tp.Percentiles)[key]))
+		}
+		sb.WriteString("\n")
+	}
+
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
+// profileMetricOrder lists the well-known profile metrics in display order;
+// any other metric is printed after them, sorted by name.
+var profileMetricOrder = []string{
+	"cycles_per_run", "instructions_per_run", "ipc", "llc_misses_per_run", "branch_misses_per_run",
+}
+
+func (t *TextOutput) outputProfile(p bpfsv1.Profile, w io.Writer) error {
+	var sb strings.Builder
+
+	// Header
+	sb.WriteString("=== Hardware Counter Profile ===\n\n")
+
+	// Identity
+	sb.WriteString(fmt.Sprintf("ID: %d\n", p.ID))
+	sb.WriteString(fmt.Sprintf("Source: %s\n", p.Source))
+
+	// Measurement window
+	sb.WriteString(fmt.Sprintf("Duration: %s\n", p.Duration))
+	if p.Warmup != nil {
+		sb.WriteString(fmt.Sprintf("Warmup: %s\n", *p.Warmup))
+	}
+	sb.WriteString("\n")
+
+	// Volume / integrity
+	sb.WriteString(fmt.Sprintf("Samples: %d\n", p.Samples))
+	sb.WriteString(fmt.Sprintf("Runs: %d\n", p.Runs))
+	if len(p.Unavailable) > 0 {
+		sb.WriteString(fmt.Sprintf("Unavailable: %s (not provided by the PMU)\n", strings.Join(p.Unavailable, ", ")))
+	}
+	sb.WriteString("\n")
+
+	// One row per metric; percentile columns follow the first metric's keys
+	names := profileMetricNames(p.Metrics)
+	var pkeys []string
+	for _, name := range names {
+		if ps := p.Metrics[name].Percentiles; ps != nil {
+			pkeys = percentileKeys(*ps)
+			break
+		}
+	}
+
+	sb.WriteString("--- Per Invocation ---\n")
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "METRIC\tMEAN\tSTDDEV\tCV\tMIN\tMAX")
+	for _, k := range pkeys {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(k))
+	}
+	fmt.Fprintln(tw, "\t")
+	for _, name := range names {
+		m := p.Metrics[name]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s", name,
+			formatCount(m.Mean), formatCount(m.StdDev), formatOptional(m.CV, "%.4f"),
+			formatOptionalCount(m.Min), formatOptionalCount(m.Max))
+		for _, k := range pkeys {
+			v := "-"
+			if m.Percentiles != nil {
+				if pv, ok := (*m.Percentiles)[k]; ok {
+					v = formatCount(pv)
+				}
+			}
+			fmt.Fprintf(tw, "\t%s", v)
+		}
+		fmt.Fprintln(tw, "\t")
+	}
+	tw.Flush()
+	sb.WriteString("\n")
+
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
+func profileMetricNames(metrics map[string]bpfsv1.CounterStats) []string {
+	names := make([]string, 0, len(metrics))
+	for _, name := range profileMetricOrder {
+		if _, ok := metrics[name]; ok {
+			names = append(names, name)
+		}
+	}
+	var rest []string
+	for name := range metrics {
+		if !slices.Contains(profileMetricOrder, name) {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// formatCount prints large per-run counts without decimals and small ratios
+// (IPC, miss rates) with enough precision to compare.
+func formatCount(v float64) string {
+	if math.Abs(v) >= 100 {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
+
+func formatOptionalCount(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return formatCount(*v)
+}
+
+func formatOptional(v *float64, format string) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf(format, *v)
+}
+
+func (t *TextOutput) outputBench(b bpfsv1.Bench, w io.Writer) error {
+	var sb strings.Builder
+
+	// Header
+	sb.WriteString("=== Benchmark (BPF_PROG_TEST_RUN) ===\n\n")
+
+	// Identity
+	sb.WriteString(fmt.Sprintf("ID: %d\n", b.ID))
+	switch {
+	case b.Packets > 0:
+		sb.WriteString(fmt.Sprintf("Input: %s (%d packets, %d bytes)\n", b.Input, b.Packets, b.InputSize))
+	case b.Input != "":
+		sb.WriteString(fmt.Sprintf("Input: %s (%d bytes)\n", b.Input, b.InputSize))
+	default:
+		sb.WriteString(fmt.Sprintf("Input: %d bytes\n", b.InputSize))
+	}
+	if b.Packets == 0 {
+		sb.WriteString(fmt.Sprintf("Retval: %d\n", b.Retval))
+	}
+	sb.WriteString("\n")
+
+	// Volume
+	sb.WriteString(fmt.Sprintf("Samples: %d x %d runs", b.Samples, b.Repeat))
+	if b.WarmupTrials > 0 {
+		sb.WriteString(fmt.Sprintf(" (+%d warmup)", b.WarmupTrials))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("Runs: %d\n", b.Runs))
+	sb.WriteString("\n")
+
+	// Summary stats
+	sb.WriteString("--- Per Run ---\n")
+	sb.WriteString(fmt.Sprintf("Mean: %s\n", formatNanos(b.Mean)))
+	sb.WriteString(fmt.Sprintf("StdDev: %s\n", formatNanos(b.StdDev)))
+	if b.CV != nil {
+		sb.WriteString(fmt.Sprintf("CV: %.4f\n", *b.CV))
+	}
+	if b.Min != nil {
+		sb.WriteString(fmt.Sprintf("Min: %s\n", formatNanos(*b.Min)))
+	}
+	if b.Max != nil {
+		sb.WriteString(fmt.Sprintf("Max: %s\n", formatNanos(*b.Max)))
+	}
+	sb.WriteString("\n")
+
+	// Percentiles
+	if b.Percentiles != nil && len(*b.Percentiles) > 0 {
+		sb.WriteString("--- Percentiles ---\n")
+		for _, key := range percentileKeys(*b.Percentiles) {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", key, formatNanos((*b.Percentiles)[key])))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(b.Classes) > 0 {
+		sb.WriteString("--- By Return Code ---\n")
+		writeBenchClasses(&sb, b.Classes)
+		sb.WriteString("\n")
+	}
+
+	// Plots
+	if t.Plot && len(b.Timeline) > 0 {
+		t.writeLatencyPlots(&sb, b.Timeline)
+	}
+
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
+func writeBenchClasses(sb *strings.Builder, classes []bpfsv1.BenchClass) {
+	var pkeys []string
+	if ps := classes[0].Percentiles; ps != nil {
+		pkeys = percentileKeys(*ps)
+	}
+
+	tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "RETVAL\tVERDICT\tSHARE\tSAMPLES\tMEAN\tSTDDEV")
+	for _, k := range pkeys {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(k))
+	}
+	fmt.Fprintln(tw, "\t")
+	for _, c := range classes {
+		verdict := c.Verdict
+		if verdict == "" {
+			verdict = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%.1f%%\t%d\t%s\t%s", c.Retval, verdict, c.Share*100, c.Samples,
+			formatNanos(c.Mean), formatNanos(c.StdDev))
+		for _, k := range pkeys {
+			v := "-"
+			if c.Percentiles != nil {
+				if pv, ok := (*c.Percentiles)[k]; ok {
+					v = formatNanos(pv)
+				}
+			}
+			fmt.Fprintf(tw, "\t%s", v)
+		}
+		fmt.Fprintln(tw, "\t")
+	}
+	tw.Flush()
+}
+
+func (t *TextOutput) outputComparison(c bpfsv1.Comparison, w io.Writer) error {
+	var sb strings.Builder
+
+	// Header
+	sb.WriteString("=== Comparison ===\n\n")
+	sb.WriteString(fmt.Sprintf("Baseline: %s\n", c.Baseline))
+	sb.WriteString(fmt.Sprintf("Candidate: %s\n", c.Candidate))
+	sb.WriteString(fmt.Sprintf("Test: %s (alpha=%g)\n", c.Test, c.Alpha))
+	sb.WriteString(fmt.Sprintf("Intervals: %g%% bootstrap, %d resamples\n", c.Confidence*100, c.Resamples))
+	sb.WriteString("\n")
+
+	// One block per metric, one row per statistic, like benchstat
+	for _, m := range c.Metrics {
+		sb.WriteString(fmt.Sprintf("--- %s ---\n", comparisonTitle(m)))
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "STAT\tBASELINE\tCANDIDATE\tDELTA\tCI\t")
+		for _, s := range m.Statistics {
+			delta := formatSigned(s.Delta, m.Unit)
+			if s.DeltaPct != nil {
+				delta = fmt.Sprintf("%+.2f%%", *s.DeltaPct)
+			}
+			ci := fmt.Sprintf("[%s, %s]", formatSigned(s.CILow, m.Unit), formatSigned(s.CIHigh, m.Unit))
+			switch {
+			case m.SummaryOnly:
+				ci = "-"
+			case !m.Significant:
+				delta = "~"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", s.Name,
+				formatValue(s.Baseline, m.Unit), formatValue(s.Candidate, m.Unit), delta, ci)
+		}
+		tw.Flush()
+		if m.SummaryOnly {
+			sb.WriteString(fmt.Sprintf("n=%d+%d (summaries only, not tested: record with --timeline to test)\n\n",
+				m.BaselineSamples, m.CandidateSamples))
+			continue
+		}
+		verdict := "significant"
+		if !m.Significant {
+			verdict = "not significant"
+		}
+		sb.WriteString(fmt.Sprintf("p=%.4f n=%d+%d (%s)\n\n", m.PValue, m.BaselineSamples, m.CandidateSamples, verdict))
+	}
+
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
+func comparisonTitle(m bpfsv1.MetricComparison) string {
+	if m.Metric == m.Parameter {
+		return m.Metric
+	}
+	return m.Parameter + " " + m.Metric
+}
+
+// formatValue prints a compared statistic in its unit: durations for "ns",
+// rates for "per_sec" and plain counts otherwise.
+func formatValue(v float64, unit string) string {
+	switch unit {
+	case "ns":
+		return formatNanos(uint64(math.Round(math.Max(0, v))))
+	case "per_sec":
+		return fmt.Sprintf("%.1f/s", v)
+	default:
+		return formatCount(v)
+	}
+}
+
+// formatSigned is formatValue with an explicit sign, for deltas.
+func formatSigned(v float64, unit string) string {
+	sign := "+"
+	if v < 0 {
+		sign = "-"
+	}
+	return sign + formatValue(math.Abs(v), unit)
+}
+
+func (t *TextOutput) outputAB(ab bpfsv1.ABTest, w io.Writer) error {
+	var sb strings.Builder
+
+	// Header
+	sb.WriteString("=== A/B Comparison ===\n\n")
+
+	// Identity
+	sb.WriteString(fmt.Sprintf("Baseline: %d\n", ab.BaselineID))
+	sb.WriteString(fmt.Sprintf("Candidate: %d\n", ab.CandidateID))
+
+	// Measurement window
+	sb.WriteString(fmt.Sprintf("Duration: %s\n", ab.Duration))
+	if ab.Warmup != nil {
+		sb.WriteString(fmt.Sprintf("Warmup: %s\n", *ab.Warmup))
+	}
+	sb.WriteString("\n")
+
+	// Volume / integrity
+	sb.WriteString(fmt.Sprintf("Pairs: %d\n", ab.Pairs))
+	if ab.Unpaired > 0 {
+		sb.WriteString(fmt.Sprintf("Unpaired: %d (intervals in which only one program ran)\n", ab.Unpaired))
+	}
+	sb.WriteString("\n")
+
+	// Both sides next to each other
+	var pkeys []string
+	if ps := ab.Baseline.Percentiles; ps != nil {
+		pkeys = percentileKeys(*ps)
+	}
+	sb.WriteString("--- Latency ---\n")
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "PROGRAM\tID\tRUNS\tMEAN\tSTDDEV")
+	for _, k := range pkeys {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(k))
+	}
+	fmt.Fprintln(tw, "\t")
+	for _, side := range []struct {
+		name string
+		id   uint32
+		s    bpfsv1.ABSide
+	}{{"baseline", ab.BaselineID, ab.Baseline}, {"candidate", ab.CandidateID, ab.Candidate}} {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s", side.name, side.id, side.s.Runs,
+			formatNanos(side.s.Mean), formatNanos(side.s.StdDev))
+		for _, k := range pkeys {
+			v := "-"
+			if side.s.Percentiles != nil {
+				if pv, ok := (*side.s.Percentiles)[k]; ok {
+					v = formatNanos(pv)
+				}
+			}
+			fmt.Fprintf(tw, "\t%s", v)
+		}
+		fmt.Fprintln(tw, "\t")
+	}
+	tw.Flush()
+	sb.WriteString("\n")
+
+	// Paired difference
+	d := ab.Difference
+	sb.WriteString("--- Paired Difference (candidate - baseline) ---\n")
+	mean := formatSigned(d.Mean, "ns")
+	if d.MeanPct != nil {
+		mean += fmt.Sprintf(" (%+.2f%%)", *d.MeanPct)
+	}
+	sb.WriteString(fmt.Sprintf("Mean: %s\n", mean))
+	sb.WriteString(fmt.Sprintf("CI (%g%%): [%s, %s]\n", d.Confidence*100, formatSigned(d.CILow, "ns"), formatSigned(d.CIHigh, "ns")))
+	sb.WriteString(fmt.Sprintf("Median: %s\n", formatSigned(d.Median, "ns")))
+	sb.WriteString(fmt.Sprintf("StdDev: %s\n", formatValue(d.StdDev, "ns")))
+	sb.WriteString(fmt.Sprintf("Candidate slower: %.1f%% of intervals\n", d.Positive*100))
+	verdict := "significant"
+	if !d.Significant {
+		verdict = "not significant"
+	}
+	sb.WriteString(fmt.Sprintf("Test: %s p=%.4f (%s at alpha=%g)\n", d.Test, d.PValue, verdict, d.Alpha))
+	sb.WriteString("\n")
+
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
+func (t *TextOutput) outputTrials(tr bpfsv1.Trials, w io.Writer) error {
+	var sb strings.Builder
+
+	// Header
+	sb.WriteString("=== Between Trials ===\n\n")
+
+	// Identity
+	sb.WriteString(fmt.Sprintf("ID: %d\n", tr.ID))
+
+	// Repetition
+	sb.WriteString(fmt.Sprintf("Trials: %d x %s", tr.Trials, tr.Duration))
+	if tr.Warmup != nil {
+		sb.WriteString(fmt.Sprintf(" (+%s warmup)", *tr.Warmup))
+	}
+	sb.WriteString("\n")
+	if tr.Cooldown > 0 {
+		sb.WriteString(fmt.Sprintf("Cooldown: %s\n", tr.Cooldown))
+	}
+	sb.WriteString("\n")
+
+	// One row per metric: pooled, between-trial spread, then each trial
+	sb.WriteString("--- Metrics ---\n")
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "METRIC\tPOOLED\tMEAN\tSTDDEV\tCV\tCI %g%%\tBETWEEN", tr.Confidence*100)
+	for i := 1; i <= tr.Trials; i++ {
+		fmt.Fprintf(tw, "\tT%d", i)
+	}
+	fmt.Fprintln(tw, "\t")
+	for _, name := range trialMetricNames(tr.Metrics) {
+		m := tr.Metrics[name]
+		format := formatCount
+		if strings.HasSuffix(name, "_ns") {
+			format = func(v float64) string { return formatNanos(uint64(math.Round(math.Max(0, v)))) }
+		}
+		between := "-"
+		if m.BetweenShare != nil {
+			between = fmt.Sprintf("%.1f%%", *m.BetweenShare*100)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t[%s, %s]\t%s", name,
+			format(m.Pooled), format(m.Mean), format(m.StdDev), formatOptional(m.CV, "%.4f"),
+			format(m.CILow), format(m.CIHigh), between)
+		for _, v := range m.PerTrial {
+			fmt.Fprintf(tw, "\t%s", format(v))
+		}
+		fmt.Fprintln(tw, "\t")
+	}
+	tw.Flush()
+	sb.WriteString("\n")
+	sb.WriteString("BETWEEN is the share of sample variance due to differences between trials.\n\n")
+
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
+// trialMetricNames orders metrics by parameter (latency, throughput, cpu,
+// health), with latency percentiles in ascending order.
+func trialMetricNames(metrics map[string]bpfsv1.TrialMetric) []string {
+	var names []string
+	for _, prefix := range []string{"latency_", "throughput_", "cpu_", "health_"} {
+		var group []string
+		for name := range metrics {
+			if strings.HasPrefix(name, prefix) {
+				group = append(group, name)
+			}
+		}
+		sort.Slice(group, func(i, j int) bool {
+			ki, kj := trialPercentile(group[i]), trialPercentile(group[j])
+			if ki != kj {
+				return ki < kj
+			}
+			return group[i] < group[j]
+		})
+		names = append(names, group...)
+	}
+	return names
+}
+
+// trialPercentile returns the percentile of names like "latency_p99_9_ns",
+// and -1 for other metrics so that they sort first.
+func trialPercentile(name string) float64 {
+	_, rest, ok := strings.Cut(name, "_p")
+	if !ok {
+		return -1
+	}
+	p, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSuffix(rest, "_ns"), "_", "."), 64)
+	if err != nil {
+		return -1
+	}
+	return p
+}
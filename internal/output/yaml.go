@@ -0,0 +1,68 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"go.yaml.in/yaml/v3"
+)
+
+func init() {
+	Register("yaml", noArg("yaml", func(OutputOptions) Printer { return &YAMLOutput{} }))
+}
+
+// YAMLOutput writes each parameter or report as a YAML document. Field names
+// and units are taken from the JSON encoding, so YAML and JSON results are
+// interchangeable; subsequent documents are separated by "---".
+type YAMLOutput struct {
+	wroteDoc bool
+}
+
+func (p *YAMLOutput) OutputParam(par bpfsv1.Parameter, w io.Writer) error {
+	return p.write(bpfsv1.TypedParameter{Parameter: par}, w)
+}
+
+func (p *YAMLOutput) OutputReport(r *bpfsv1.Report, w io.Writer) error {
+	return p.write(r, w)
+}
+
+func (p *YAMLOutput) write(v interface{}, w io.Writer) error {
+	data, err := jsonToYAML(v)
+	if err != nil {
+		return err
+	}
+	if p.wroteDoc {
+		if _, err := io.WriteString(w, "---\n"); err != nil {
+			return err
+		}
+	}
+	p.wroteDoc = true
+	_, err = w.Write(data)
+	return err
+}
+
+// jsonToYAML encodes v as JSON and re-emits it as block-style YAML, keeping
+// the JSON field order.
+func jsonToYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is a subset of YAML, so the node tree preserves key order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	resetStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// resetStyle drops the flow/quoted styles inherited from the JSON source so
+// the encoder picks idiomatic block style.
+func resetStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
@@ -0,0 +1,198 @@
+// Package pcap reads the packets of a capture file in the classic pcap or the
+// pcapng format, so that recorded traffic can be replayed into a program with
+// BPF_PROG_TEST_RUN. Only packet data is kept; timestamps are discarded.
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// LinkTypeEthernet is the link-layer header type of Ethernet captures, the
+// only one XDP and TC programs accept.
+const LinkTypeEthernet = 1
+
+// Capture holds the packets of a capture file in file order.
+type Capture struct {
+	LinkType uint32
+	Packets  [][]byte
+}
+
+const (
+	pcapMagicMicros = 0xa1b2c3d4
+	pcapMagicNanos  = 0xa1b23c4d
+
+	pcapngSectionHeader   = 0x0a0d0d0a
+	pcapngByteOrderMagic  = 0x1a2b3c4d
+	pcapngInterfaceDesc   = 0x00000001
+	pcapngObsoletePacket  = 0x00000002
+	pcapngSimplePacket    = 0x00000003
+	pcapngEnhancedPacket  = 0x00000006
+	pcapngMinBlockLength  = 12
+	pcapGlobalHeaderLen   = 24
+	pcapRecordHeaderLen   = 16
+	pcapngSectionFixedLen = 16 // byte-order magic, version, section length
+)
+
+var errTruncated = errors.New("truncated capture")
+
+// IsCapture reports whether data starts like a pcap or pcapng file.
+func IsCapture(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(data) {
+	case pcapMagicMicros, pcapMagicNanos, pcapngSectionHeader:
+		return true
+	}
+	switch binary.BigEndian.Uint32(data) {
+	case pcapMagicMicros, pcapMagicNanos:
+		return true
+	}
+	return false
+}
+
+// Parse decodes a pcap or pcapng file.
+func Parse(data []byte) (*Capture, error) {
+	if len(data) < 4 {
+		return nil, errTruncated
+	}
+	if binary.LittleEndian.Uint32(data) == pcapngSectionHeader {
+		return parsePcapng(data)
+	}
+	return parsePcap(data)
+}
+
+func parsePcap(data []byte) (*Capture, error) {
+	if len(data) < pcapGlobalHeaderLen {
+		return nil, errTruncated
+	}
+
+	var order binary.ByteOrder
+	switch {
+	case isPcapMagic(binary.LittleEndian.Uint32(data)):
+		order = binary.LittleEndian
+	case isPcapMagic(binary.BigEndian.Uint32(data)):
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("not a pcap or pcapng file")
+	}
+
+	c := &Capture{LinkType: order.Uint32(data[20:24])}
+	for off := pcapGlobalHeaderLen; off < len(data); {
+		if len(data)-off < pcapRecordHeaderLen {
+			return nil, fmt.Errorf("packet %d: %w", len(c.Packets)+1, errTruncated)
+		}
+		caplen := int(order.Uint32(data[off+8:]))
+		off += pcapRecordHeaderLen
+		if caplen > len(data)-off {
+			return nil, fmt.Errorf("packet %d: %w", len(c.Packets)+1, errTruncated)
+		}
+		c.Packets = append(c.Packets, data[off:off+caplen])
+		off += caplen
+	}
+	return c, nil
+}
+
+func isPcapMagic(m uint32) bool {
+	return m == pcapMagicMicros || m == pcapMagicNanos
+}
+
+// parsePcapng walks the blocks of every section. Each section declares its
+// own byte order and interfaces, which packet blocks refer to by index.
+func parsePcapng(data []byte) (*Capture, error) {
+	c := &Capture{}
+	var (
+		order      binary.ByteOrder = binary.LittleEndian
+		interfaces []uint32         // link type per interface of the current section
+		linkSet    bool
+	)
+
+	addPacket := func(iface int, pkt []byte) error {
+		if iface >= len(interfaces) {
+			return fmt.Errorf("packet %d: unknown interface %d", len(c.Packets)+1, iface)
+		}
+		lt := interfaces[iface]
+		if !linkSet {
+			c.LinkType, linkSet = lt, true
+		} else if lt != c.LinkType {
+			return fmt.Errorf("packet %d: mixed link types %d and %d are not supported", len(c.Packets)+1, c.LinkType, lt)
+		}
+		c.Packets = append(c.Packets, pkt)
+		return nil
+	}
+
+	for off := 0; off < len(data); {
+		if len(data)-off < pcapngMinBlockLength {
+			return nil, errTruncated
+		}
+		blockType := order.Uint32(data[off:])
+		if blockType == pcapngSectionHeader {
+			// The byte-order magic follows the (byte-order independent) type
+			if len(data)-off < pcapngMinBlockLength+pcapngSectionFixedLen {
+				return nil, errTruncated
+			}
+			switch uint32(pcapngByteOrderMagic) {
+			case binary.LittleEndian.Uint32(data[off+8:]):
+				order = binary.LittleEndian
+			case binary.BigEndian.Uint32(data[off+8:]):
+				order = binary.BigEndian
+			default:
+				return nil, fmt.Errorf("pcapng: invalid byte-order magic")
+			}
+			interfaces = nil
+		}
+
+		length := int(order.Uint32(data[off+4:]))
+		if length < pcapngMinBlockLength || length%4 != 0 || length > len(data)-off {
+			return nil, fmt.Errorf("pcapng: invalid block length %d: %w", length, errTruncated)
+		}
+		body := data[off+8 : off+length-4]
+		off += length
+
+		switch blockType {
+		case pcapngInterfaceDesc:
+			if len(body) < 8 {
+				return nil, errTruncated
+			}
+			interfaces = append(interfaces, uint32(order.Uint16(body)))
+
+		case pcapngEnhancedPacket:
+			if len(body) < 20 {
+				return nil, errTruncated
+			}
+			caplen := int(order.Uint32(body[12:]))
+			if caplen > len(body)-20 {
+				return nil, errTruncated
+			}
+			if err := addPacket(int(order.Uint32(body)), body[20:20+caplen]); err != nil {
+				return nil, err
+			}
+
+		case pcapngSimplePacket:
+			// Always refers to the first interface; captured length is implied
+			if len(body) < 4 {
+				return nil, errTruncated
+			}
+			caplen := min(int(order.Uint32(body)), len(body)-4)
+			if err := addPacket(0, body[4:4+caplen]); err != nil {
+				return nil, err
+			}
+
+		case pcapngObsoletePacket:
+			if len(body) < 20 {
+				return nil, errTruncated
+			}
+			caplen := int(order.Uint32(body[12:]))
+			if caplen > len(body)-20 {
+				return nil, errTruncated
+			}
+			if err := addPacket(int(order.Uint16(body)), body[20:20+caplen]); err != nil {
+				return nil, err
+			}
+		}
+		// Other blocks (statistics, name resolution, ...) carry no packets
+	}
+	return c, nil
+}
@@ -0,0 +1,303 @@
+// Package probe attaches fentry/fexit tracing programs around a target eBPF
+// program to account each invocation on the CPU it ran on.
+//
+// The probes are assembled at runtime, so no BPF toolchain is needed to
+// build bpfstats. They require a kernel with BPF trampolines (5.5+) and a
+// target program that was loaded with BTF.
+package probe
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/btf"
+	"github.com/cilium/ebpf/link"
+)
+
+// Counters are the cumulative per-CPU counters maintained by the probes.
+// The layout matches the value of the "stats" map.
+type Counters struct {
+	Runs      uint64 // completed invocations
+	RuntimeNs uint64 // sum of fexit-fentry timestamps, including trampoline overhead
+}
+
+// Probe is a pair of fentry/fexit programs attached to a target program.
+type Probe struct {
+	coll     *ebpf.Collection
+	links    []link.Link
+	progType ebpf.ProgramType
+}
+
+// Options select optional accounting done by the probes.
+type Options struct {
+	// ReturnCodes additionally accounts runs and runtime per return value
+	// of the target, e.g. per XDP verdict.
+	ReturnCodes bool
+}
+
+const (
+	entryProg = "bpfstats_entry"
+	exitProg  = "bpfstats_exit"
+	startMap  = "start"   // per-CPU entry timestamp
+	statsMap  = "stats"   // per-CPU Counters
+	retvalMap = "retvals" // per-CPU Counters keyed by return value
+
+	// maxReturnCodes bounds the distinct return values accounted; runs with
+	// further values are only included in the totals.
+	maxReturnCodes = 256
+)
+
+// Attach loads the probes and attaches them to the program with the given
+// kernel ID. Close must be called to detach them.
+func Attach(id uint32, opts Options) (*Probe, error) {
+	target, err := ebpf.NewProgramFromID(ebpf.ProgramID(id))
+	if err != nil {
+		return nil, fmt.Errorf("NewProgramFromID: %w", err)
+	}
+	defer target.Close()
+
+	fn, err := entryFunction(target)
+	if err != nil {
+		return nil, err
+	}
+
+	// fexit receives the return value after the target's arguments
+	retvalOff := int16(-1)
+	if opts.ReturnCodes {
+		proto, ok := fn.Type.(*btf.FuncProto)
+		if !ok {
+			return nil, fmt.Errorf("function %s has no BTF prototype", fn.Name)
+		}
+		retvalOff = int16(8 * len(proto.Params))
+	}
+
+	spec := &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			startMap: {Type: ebpf.PerCPUArray, KeySize: 4, ValueSize: 8, MaxEntries: 1},
+			statsMap: {Type: ebpf.PerCPUArray, KeySize: 4, ValueSize: 16, MaxEntries: 1},
+		},
+		Programs: map[string]*ebpf.ProgramSpec{
+			entryProg: {
+				Type:         ebpf.Tracing,
+				AttachType:   ebpf.AttachTraceFEntry,
+				AttachTarget: target,
+				AttachTo:     fn.Name,
+				License:      "GPL",
+				Instructions: entryInstructions(),
+			},
+			exitProg: {
+				Type:         ebpf.Tracing,
+				AttachType:   ebpf.AttachTraceFExit,
+				AttachTarget: target,
+				AttachTo:     fn.Name,
+				License:      "GPL",
+				Instructions: exitInstructions(retvalOff),
+			},
+		},
+	}
+
+	if opts.ReturnCodes {
+		spec.Maps[retvalMap] = &ebpf.MapSpec{Type: ebpf.PerCPUHash, KeySize: 4, ValueSize: 16, MaxEntries: maxReturnCodes}
+	}
+
+	coll, err := ebpf.NewCollection(spec)
+	if err != nil {
+		return nil, fmt.Errorf("load probes: %w", err)
+	}
+
+	p := &Probe{coll: coll, progType: target.Type()}
+	for _, name := range []string{entryProg, exitProg} {
+		l, err := link.AttachTracing(link.TracingOptions{Program: coll.Programs[name]})
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("attach %s: %w", name, err)
+		}
+		p.links = append(p.links, l)
+	}
+	return p, nil
+}
+
+// PerCPU returns the cumulative counters of every possible CPU, indexed by
+// CPU number.
+func (p *Probe) PerCPU() ([]Counters, error) {
+	var values []Counters
+	if err := p.coll.Maps[statsMap].Lookup(uint32(0), &values); err != nil {
+		return nil, fmt.Errorf("lookup per-CPU stats: %w", err)
+	}
+	return values, nil
+}
+
+// ReturnCodes returns the cumulative counters per return value of the
+// target, summed over all CPUs. It is empty unless Options.ReturnCodes was
+// set.
+func (p *Probe) ReturnCodes() (map[uint32]Counters, error) {
+	out := make(map[uint32]Counters)
+	m, ok := p.coll.Maps[retvalMap]
+	if !ok {
+		return out, nil
+	}
+	var (
+		key    uint32
+		values []Counters
+	)
+	iter := m.Iterate()
+	for iter.Next(&key, &values) {
+		var total Counters
+		for _, v := range values {
+			total.Runs += v.Runs
+			total.RuntimeNs += v.RuntimeNs
+		}
+		out[key] = total
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterate return codes: %w", err)
+	}
+	return out, nil
+}
+
+// ProgramType returns the type of the target program.
+func (p *Probe) ProgramType() ebpf.ProgramType {
+	return p.progType
+}
+
+// Close detaches the probes and releases their resources.
+func (p *Probe) Close() error {
+	var errs []error
+	for _, l := range p.links {
+		errs = append(errs, l.Close())
+	}
+	p.links = nil
+	if p.coll != nil {
+		p.coll.Close()
+		p.coll = nil
+	}
+	return errors.Join(errs...)
+}
+
+// entryFunction returns the BTF function of the target's main function, whose
+// name is what fentry/fexit attach to. The program name reported by the
+// kernel is truncated and cannot be used for this.
+func entryFunction(target *ebpf.Program) (*btf.Func, error) {
+	info, err := target.Info()
+	if err != nil {
+		return nil, fmt.Errorf("Info: %w", err)
+	}
+	funcs, err := info.FuncInfos()
+	if err != nil {
+		return nil, fmt.Errorf("program has no BTF function info (required for fentry/fexit): %w", err)
+	}
+	for _, f := range funcs {
+		if f.Offset == 0 && f.Func != nil {
+			return f.Func, nil
+		}
+	}
+	return nil, fmt.Errorf("program has no BTF function at offset 0")
+}
+
+// entryInstructions stores the entry timestamp in the per-CPU start slot.
+// The timestamp is taken last to keep the probe's own cost out of the window.
+func entryInstructions() asm.Instructions {
+	return asm.Instructions{
+		// key = 0
+		asm.StoreImm(asm.RFP, -4, 0, asm.Word),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(startMap),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "out"),
+		asm.Mov.Reg(asm.R6, asm.R0),
+
+		// *start = ktime_get_ns()
+		asm.FnKtimeGetNs.Call(),
+		asm.StoreMem(asm.R6, 0, asm.R0, asm.DWord),
+
+		asm.Mov.Imm(asm.R0, 0).WithSymbol("out"),
+		asm.Return(),
+	}
+}
+
+// exitInstructions accounts the elapsed time since entry to this CPU's
+// counters. The timestamp is taken first for the same reason as above. If
+// retvalOff is not negative, the run is also accounted to the return value
+// read from that context offset.
+func exitInstructions(retvalOff int16) asm.Instructions {
+	insns := asm.Instructions{
+		asm.Mov.Reg(asm.R9, asm.R1), // ctx
+		asm.FnKtimeGetNs.Call(),
+		asm.Mov.Reg(asm.R7, asm.R0),
+
+		// start = *start; skip invocations that began before attachment
+		asm.StoreImm(asm.RFP, -4, 0, asm.Word),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(startMap),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "out"),
+		asm.LoadMem(asm.R8, asm.R0, 0, asm.DWord),
+		asm.JEq.Imm(asm.R8, 0, "out"),
+		asm.Mov.Imm(asm.R1, 0),
+		asm.StoreMem(asm.R0, 0, asm.R1, asm.DWord),
+		asm.Sub.Reg(asm.R7, asm.R8),
+
+		// stats->runs++, stats->runtime_ns += delta (per-CPU, no atomics needed)
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(statsMap),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "out"),
+		asm.LoadMem(asm.R1, asm.R0, 0, asm.DWord),
+		asm.Add.Imm(asm.R1, 1),
+		asm.StoreMem(asm.R0, 0, asm.R1, asm.DWord),
+		asm.LoadMem(asm.R1, asm.R0, 8, asm.DWord),
+		asm.Add.Reg(asm.R1, asm.R7),
+		asm.StoreMem(asm.R0, 8, asm.R1, asm.DWord),
+	}
+	if retvalOff >= 0 {
+		insns = append(insns, retvalInstructions(retvalOff)...)
+	}
+	return append(insns,
+		asm.Mov.Imm(asm.R0, 0).WithSymbol("out"),
+		asm.Return(),
+	)
+}
+
+// retvalInstructions accounts the run (runtime delta in R7) to the counters
+// of the return value, creating them on first use.
+func retvalInstructions(retvalOff int16) asm.Instructions {
+	return asm.Instructions{
+		// key = (u32)ctx[nargs]
+		asm.LoadMem(asm.R1, asm.R9, retvalOff, asm.DWord),
+		asm.StoreMem(asm.RFP, -8, asm.R1, asm.Word),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -8),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(retvalMap),
+		asm.FnMapLookupElem.Call(),
+		asm.JNE.Imm(asm.R0, 0, "retval_found"),
+
+		// first run with this value: insert zeroed counters (BPF_NOEXIST)
+		asm.Mov.Imm(asm.R1, 0),
+		asm.StoreMem(asm.RFP, -24, asm.R1, asm.DWord),
+		asm.StoreMem(asm.RFP, -16, asm.R1, asm.DWord),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(retvalMap),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -8),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -24),
+		asm.Mov.Imm(asm.R4, 1),
+		asm.FnMapUpdateElem.Call(),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -8),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(retvalMap),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "out"),
+
+		asm.LoadMem(asm.R1, asm.R0, 0, asm.DWord).WithSymbol("retval_found"),
+		asm.Add.Imm(asm.R1, 1),
+		asm.StoreMem(asm.R0, 0, asm.R1, asm.DWord),
+		asm.LoadMem(asm.R1, asm.R0, 8, asm.DWord),
+		asm.Add.Reg(asm.R1, asm.R7),
+		asm.StoreMem(asm.R0, 8, asm.R1, asm.DWord),
+	}
+}
@@ -0,0 +1,364 @@
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/Tjaarda1/bpfstats/internal/environment"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+)
+
+// Event is a hardware performance counter that can be read around each
+// invocation of the target program.
+type Event struct {
+	Name   string // e.g. "cycles"
+	Type   uint32 // perf_event_attr.type
+	Config uint64 // perf_event_attr.config
+}
+
+// The counters bpfstats profiles by default, matching bpftool prog profile.
+var (
+	Cycles       = Event{Name: "cycles", Type: unix.PERF_TYPE_HARDWARE, Config: unix.PERF_COUNT_HW_CPU_CYCLES}
+	Instructions = Event{Name: "instructions", Type: unix.PERF_TYPE_HARDWARE, Config: unix.PERF_COUNT_HW_INSTRUCTIONS}
+	LLCMisses    = Event{Name: "llc_misses", Type: unix.PERF_TYPE_HW_CACHE, Config: unix.PERF_COUNT_HW_CACHE_LL |
+		unix.PERF_COUNT_HW_CACHE_OP_READ<<8 | unix.PERF_COUNT_HW_CACHE_RESULT_MISS<<16}
+	BranchMisses = Event{Name: "branch_misses", Type: unix.PERF_TYPE_HARDWARE, Config: unix.PERF_COUNT_HW_BRANCH_MISSES}
+)
+
+// Events returns the counters that can be profiled.
+func Events() []Event {
+	return []Event{Cycles, Instructions, LLCMisses, BranchMisses}
+}
+
+// EventByName looks up a counter by its name.
+func EventByName(name string) (Event, bool) {
+	for _, ev := range Events() {
+		if ev.Name == name {
+			return ev, true
+		}
+	}
+	return Event{}, false
+}
+
+// ErrNoPMU is returned by AttachProfile when none of the requested counters
+// can be opened, which is typical of VMs without virtual PMU support.
+var ErrNoPMU = errors.New("hardware performance counters are unavailable (no PMU access, common in VMs and containers)")
+
+// maxEvents bounds the number of counters read per invocation, which keeps
+// the map values fixed-size.
+const maxEvents = 4
+
+// ProfileCounters are the cumulative counters of the profiling probes. Values
+// holds one delta sum per counted event, in the order of Profiler.Events.
+type ProfileCounters struct {
+	Runs   uint64
+	Values []uint64
+}
+
+// profileValue is the layout of the "start" and "stats" map values: a flag
+// (entry) or run count (exit) followed by one slot per event.
+type profileValue [1 + maxEvents]uint64
+
+// Profiler is a pair of fentry/fexit programs that read hardware counters
+// around every invocation of a target program.
+type Profiler struct {
+	coll        *ebpf.Collection
+	links       []link.Link
+	fds         []int
+	events      []Event
+	unavailable []string
+}
+
+// AttachProfile opens the given counters on every online CPU, loads the
+// probes and attaches them to the program with the given kernel ID. Counters
+// the PMU does not provide are skipped and listed by Unavailable; if none
+// remain, ErrNoPMU is returned. Close must be called to detach the probes.
+func AttachProfile(id uint32, events []Event) (*Profiler, error) {
+	if len(events) == 0 || len(events) > maxEvents {
+		return nil, fmt.Errorf("profile between 1 and %d counters, got %d", maxEvents, len(events))
+	}
+
+	target, err := ebpf.NewProgramFromID(ebpf.ProgramID(id))
+	if err != nil {
+		return nil, fmt.Errorf("NewProgramFromID: %w", err)
+	}
+	defer target.Close()
+
+	fn, err := entryFunction(target)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Profiler{}
+	cpus := environment.OnlineCPUList()
+	perCPU := make([][]int, 0, len(events))
+	for _, ev := range events {
+		fds, err := p.openEvent(ev, cpus)
+		if errors.Is(err, errUnsupportedEvent) {
+			p.unavailable = append(p.unavailable, ev.Name)
+			continue
+		}
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.events = append(p.events, ev)
+		perCPU = append(perCPU, fds)
+	}
+	if len(p.events) == 0 {
+		return nil, ErrNoPMU
+	}
+
+	possible, err := ebpf.PossibleCPU()
+	if err != nil {
+		p.Close()
+		return nil, fmt.Errorf("possible CPUs: %w", err)
+	}
+
+	valueSize := uint32(unsafe.Sizeof(profileValue{}))
+	spec := &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			startMap: {Type: ebpf.PerCPUArray, KeySize: 4, ValueSize: valueSize, MaxEntries: 1},
+			statsMap: {Type: ebpf.PerCPUArray, KeySize: 4, ValueSize: valueSize, MaxEntries: 1},
+		},
+		Programs: map[string]*ebpf.ProgramSpec{
+			entryProg: {
+				Type:         ebpf.Tracing,
+				AttachType:   ebpf.AttachTraceFEntry,
+				AttachTarget: target,
+				AttachTo:     fn.Name,
+				License:      "GPL",
+				Instructions: profileEntryInstructions(len(p.events)),
+			},
+			exitProg: {
+				Type:         ebpf.Tracing,
+				AttachType:   ebpf.AttachTraceFExit,
+				AttachTarget: target,
+				AttachTo:     fn.Name,
+				License:      "GPL",
+				Instructions: profileExitInstructions(len(p.events)),
+			},
+		},
+	}
+	for i := range p.events {
+		spec.Maps[pmuMap(i)] = &ebpf.MapSpec{Type: ebpf.PerfEventArray, KeySize: 4, ValueSize: 4, MaxEntries: uint32(possible)}
+	}
+
+	p.coll, err = ebpf.NewCollection(spec)
+	if err != nil {
+		p.Close()
+		return nil, fmt.Errorf("load profiling probes: %w", err)
+	}
+
+	for i, fds := range perCPU {
+		for j, fd := range fds {
+			if err := p.coll.Maps[pmuMap(i)].Put(uint32(cpus[j]), uint32(fd)); err != nil {
+				p.Close()
+				return nil, fmt.Errorf("install %s counter for CPU %d: %w", p.events[i].Name, cpus[j], err)
+			}
+		}
+	}
+
+	for _, name := range []string{entryProg, exitProg} {
+		l, err := link.AttachTracing(link.TracingOptions{Program: p.coll.Programs[name]})
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("attach %s: %w", name, err)
+		}
+		p.links = append(p.links, l)
+	}
+	return p, nil
+}
+
+// errUnsupportedEvent marks a counter the PMU (or its absence) cannot provide.
+var errUnsupportedEvent = errors.New("unsupported event")
+
+// openEvent opens a counting event on each CPU and returns the descriptors in
+// the same order as cpus.
+func (p *Profiler) openEvent(ev Event, cpus []int) ([]int, error) {
+	fds := make([]int, 0, len(cpus))
+	for _, cpu := range cpus {
+		attr := unix.PerfEventAttr{
+			Type:   ev.Type,
+			Config: ev.Config,
+			Size:   uint32(unsafe.Sizeof(unix.PerfEventAttr{})),
+		}
+		fd, err := unix.PerfEventOpen(&attr, -1, cpu, -1, unix.PERF_FLAG_FD_CLOEXEC)
+		switch {
+		case err == nil:
+			fds = append(fds, fd)
+			p.fds = append(p.fds, fd)
+			continue
+		case errors.Is(err, unix.ENOENT), errors.Is(err, unix.EOPNOTSUPP), errors.Is(err, unix.ENODEV), errors.Is(err, unix.EINVAL):
+			if len(fds) == 0 {
+				return nil, fmt.Errorf("%s: %w", ev.Name, errUnsupportedEvent)
+			}
+		case errors.Is(err, unix.EACCES), errors.Is(err, unix.EPERM):
+			return nil, fmt.Errorf("perf_event_open %s: %w (requires CAP_PERFMON or a lower kernel.perf_event_paranoid)", ev.Name, err)
+		}
+		return nil, fmt.Errorf("perf_event_open %s on CPU %d: %w", ev.Name, cpu, err)
+	}
+	return fds, nil
+}
+
+// Events returns the counters being read, in the order of
+// ProfileCounters.Values.
+func (p *Profiler) Events() []Event {
+	return p.events
+}
+
+// Unavailable returns the names of requested counters the PMU does not
+// provide.
+func (p *Profiler) Unavailable() []string {
+	return p.unavailable
+}
+
+// Totals returns the counters summed over all CPUs.
+func (p *Profiler) Totals() (ProfileCounters, error) {
+	var values []profileValue
+	if err := p.coll.Maps[statsMap].Lookup(uint32(0), &values); err != nil {
+		return ProfileCounters{}, fmt.Errorf("lookup per-CPU profile: %w", err)
+	}
+	total := ProfileCounters{Values: make([]uint64, len(p.events))}
+	for _, v := range values {
+		total.Runs += v[0]
+		for i := range total.Values {
+			total.Values[i] += v[1+i]
+		}
+	}
+	return total, nil
+}
+
+// Close detaches the probes and closes the counters.
+func (p *Profiler) Close() error {
+	var errs []error
+	for _, l := range p.links {
+		errs = append(errs, l.Close())
+	}
+	p.links = nil
+	if p.coll != nil {
+		p.coll.Close()
+		p.coll = nil
+	}
+	for _, fd := range p.fds {
+		errs = append(errs, unix.Close(fd))
+	}
+	p.fds = nil
+	return errors.Join(errs...)
+}
+
+func pmuMap(i int) string {
+	return fmt.Sprintf("pmu%d", i)
+}
+
+// Stack layout shared by the profiling probes.
+const (
+	keySlot   = -4  // u32 map key
+	readSlot  = -32 // struct bpf_perf_event_value (24 bytes)
+	valueSlot = -40 // first of the exit counter readings, one u64 per event
+)
+
+// readCounter emits bpf_perf_event_read_value(pmu<i>, BPF_F_CURRENT_CPU) and
+// jumps to "out" when the counter cannot be read on this CPU. On success the
+// counter value is left in R1.
+func readCounter(i int) asm.Instructions {
+	return asm.Instructions{
+		asm.LoadMapPtr(asm.R1, 0).WithReference(pmuMap(i)),
+		asm.Mov.Imm32(asm.R2, -1), // BPF_F_CURRENT_CPU
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, readSlot),
+		asm.Mov.Imm(asm.R4, 24),
+		asm.FnPerfEventReadValue.Call(),
+		asm.JNE.Imm(asm.R0, 0, "out"),
+		asm.LoadMem(asm.R1, asm.RFP, readSlot, asm.DWord),
+	}
+}
+
+// profileEntryInstructions stores the counters of n events in the per-CPU
+// start slot and marks it valid. The counters are read last to keep the
+// lookup out of the window.
+func profileEntryInstructions(n int) asm.Instructions {
+	insns := asm.Instructions{
+		asm.StoreImm(asm.RFP, keySlot, 0, asm.Word),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, keySlot),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(startMap),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "out"),
+		asm.Mov.Reg(asm.R6, asm.R0),
+
+		// start->valid = 0 until every counter was read
+		asm.Mov.Imm(asm.R1, 0),
+		asm.StoreMem(asm.R6, 0, asm.R1, asm.DWord),
+	}
+	for i := 0; i < n; i++ {
+		insns = append(insns, readCounter(i)...)
+		insns = append(insns, asm.StoreMem(asm.R6, int16(8*(1+i)), asm.R1, asm.DWord))
+	}
+	return append(insns,
+		asm.Mov.Imm(asm.R1, 1),
+		asm.StoreMem(asm.R6, 0, asm.R1, asm.DWord),
+
+		asm.Mov.Imm(asm.R0, 0).WithSymbol("out"),
+		asm.Return(),
+	)
+}
+
+// profileExitInstructions reads the counters first, in reverse order of the
+// entry probe so each event's window is as tight as possible, and accumulates
+// the deltas to this CPU's stats.
+func profileExitInstructions(n int) asm.Instructions {
+	var insns asm.Instructions
+	for i := n - 1; i >= 0; i-- {
+		insns = append(insns, readCounter(i)...)
+		insns = append(insns, asm.StoreMem(asm.RFP, int16(valueSlot-8*i), asm.R1, asm.DWord))
+	}
+
+	insns = append(insns,
+		// skip invocations whose entry was not (fully) recorded
+		asm.StoreImm(asm.RFP, keySlot, 0, asm.Word),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, keySlot),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(startMap),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "out"),
+		asm.Mov.Reg(asm.R6, asm.R0),
+		asm.LoadMem(asm.R1, asm.R6, 0, asm.DWord),
+		asm.JEq.Imm(asm.R1, 0, "out"),
+		asm.Mov.Imm(asm.R1, 0),
+		asm.StoreMem(asm.R6, 0, asm.R1, asm.DWord),
+
+		// stats->runs++ (per-CPU, no atomics needed)
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, keySlot),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(statsMap),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "out"),
+		asm.Mov.Reg(asm.R7, asm.R0),
+		asm.LoadMem(asm.R1, asm.R7, 0, asm.DWord),
+		asm.Add.Imm(asm.R1, 1),
+		asm.StoreMem(asm.R7, 0, asm.R1, asm.DWord),
+	)
+
+	// stats->values[i] += end[i] - start->values[i]
+	for i := 0; i < n; i++ {
+		off := int16(8 * (1 + i))
+		insns = append(insns,
+			asm.LoadMem(asm.R1, asm.RFP, int16(valueSlot-8*i), asm.DWord),
+			asm.LoadMem(asm.R2, asm.R6, off, asm.DWord),
+			asm.Sub.Reg(asm.R1, asm.R2),
+			asm.LoadMem(asm.R2, asm.R7, off, asm.DWord),
+			asm.Add.Reg(asm.R2, asm.R1),
+			asm.StoreMem(asm.R7, off, asm.R2, asm.DWord),
+		)
+	}
+
+	return append(insns,
+		asm.Mov.Imm(asm.R0, 0).WithSymbol("out"),
+		asm.Return(),
+	)
+}
@@ -0,0 +1,112 @@
+// Package program describes loaded eBPF programs for result metadata.
+package program
+
+import (
+	"fmt"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+)
+
+// linkTypes names link types the way bpftool does.
+var linkTypes = map[link.Type]string{
+	link.RawTracepointType: "raw_tracepoint",
+	link.TracingType:       "tracing",
+	link.CgroupType:        "cgroup",
+	link.IterType:          "iter",
+	link.NetNsType:         "netns",
+	link.XDPType:           "xdp",
+	link.PerfEventType:     "perf_event",
+	link.KprobeMultiType:   "kprobe_multi",
+	link.TCXType:           "tcx",
+	link.UprobeMultiType:   "uprobe_multi",
+	link.NetfilterType:     "netfilter",
+	link.NetkitType:        "netkit",
+}
+
+// Describe returns the metadata of the program with the given kernel ID.
+// Fields the running kernel does not report are left empty.
+func Describe(id uint32) (*bpfsv1.Program, error) {
+	prog, err := ebpf.NewProgramFromID(ebpf.ProgramID(id))
+	if err != nil {
+		return nil, fmt.Errorf("NewProgramFromID: %w", err)
+	}
+	defer prog.Close()
+
+	info, err := prog.Info()
+	if err != nil {
+		return nil, fmt.Errorf("Info: %w", err)
+	}
+
+	p := &bpfsv1.Program{
+		ID:   id,
+		Name: info.Name,
+		Type: info.Type.String(),
+		Tag:  info.Tag,
+	}
+	if btfID, ok := info.BTFID(); ok {
+		v := uint32(btfID)
+		p.BTFID = &v
+	}
+	if sinceBoot, ok := info.LoadTime(); ok {
+		if loaded, err := bootTimeToWall(sinceBoot); err == nil {
+			p.LoadedAt = &loaded
+		}
+	}
+	if size, err := info.TranslatedSize(); err == nil {
+		p.XlatedSize = &size
+	}
+	if size, err := info.JitedSize(); err == nil {
+		p.JitedSize = &size
+	}
+	if n, ok := info.VerifiedInstructions(); ok {
+		p.VerifiedInstructions = &n
+	}
+	if maps, ok := info.MapIDs(); ok {
+		for _, m := range maps {
+			p.MapIDs = append(p.MapIDs, uint32(m))
+		}
+	}
+
+	// Links are optional metadata: programs attached through legacy APIs
+	// (netlink, ioctl) have none, and older kernels cannot enumerate them.
+	p.Links, _ = links(id)
+
+	return p, nil
+}
+
+// links returns the BPF links that attach the program.
+func links(id uint32) ([]bpfsv1.ProgramLink, error) {
+	var out []bpfsv1.ProgramLink
+	it := new(link.Iterator)
+	defer it.Close()
+	for it.Next() {
+		info, err := it.Link.Info()
+		if err != nil {
+			continue
+		}
+		if uint32(info.Program) != id {
+			continue
+		}
+		name, ok := linkTypes[info.Type]
+		if !ok {
+			name = fmt.Sprintf("type_%d", info.Type)
+		}
+		out = append(out, bpfsv1.ProgramLink{ID: uint32(info.ID), Type: name})
+	}
+	return out, it.Err()
+}
+
+// bootTimeToWall converts a duration since boot (CLOCK_BOOTTIME) into wall
+// clock time.
+func bootTimeToWall(sinceBoot time.Duration) (time.Time, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &ts); err != nil {
+		return time.Time{}, err
+	}
+	uptime := time.Duration(ts.Nano())
+	return time.Now().Add(sinceBoot - uptime), nil
+}
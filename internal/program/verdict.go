@@ -0,0 +1,23 @@
+package program
+
+import "github.com/cilium/ebpf"
+
+// Verdict names a program's return code for the program types whose return
+// codes are actions, e.g. 1 => "XDP_DROP". It returns "" for other types.
+func Verdict(typ ebpf.ProgramType, ret uint32) string {
+	var names []string
+	switch typ {
+	case ebpf.XDP:
+		names = []string{"XDP_ABORTED", "XDP_DROP", "XDP_PASS", "XDP_TX", "XDP_REDIRECT"}
+	case ebpf.SchedCLS, ebpf.SchedACT:
+		if int32(ret) == -1 {
+			return "TC_ACT_UNSPEC"
+		}
+		names = []string{"TC_ACT_OK", "TC_ACT_RECLASSIFY", "TC_ACT_SHOT", "TC_ACT_PIPE",
+			"TC_ACT_STOLEN", "TC_ACT_QUEUED", "TC_ACT_REPEAT", "TC_ACT_REDIRECT", "TC_ACT_TRAP"}
+	}
+	if int(ret) < len(names) {
+		return names[ret]
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package record
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/cilium/ebpf"
+)
+
+// Capture polls the statistics of program id every interval and appends them
+// to rw until ctx is done, then closes the recording. Failed polls are kept
+// as error records. onPoll, if set, is called after every successful poll.
+func Capture(ctx context.Context, rw *Writer, id uint32, interval time.Duration, onPoll func(*ebpf.ProgramStats)) error {
+	source := collector.NewKernelSource(id)
+	defer source.Close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return rw.Close(time.Now())
+
+		case <-ticker.C:
+			now := time.Now()
+			stats, err := source.Stats()
+			if err != nil {
+				if err := rw.Error(now, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := rw.Observe(now, stats); err != nil {
+				return err
+			}
+			if onPoll != nil {
+				onPoll(stats)
+			}
+		}
+	}
+}
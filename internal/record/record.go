@@ -0,0 +1,301 @@
+// Package record stores the raw kernel statistics of a program, as polled by
+// the collectors, in a compact binary file so that a measurement can be
+// analyzed again offline.
+//
+// A recording starts with the magic "BPFREC", a format version byte and a
+// length-prefixed JSON header. It is followed by tagged records:
+//
+//	tagDelta    uvarint dt, dRunCount, dRuntime, dRecursionMisses
+//	tagAbsolute uvarint dt, RunCount, Runtime, RecursionMisses
+//	tagError    uvarint dt, uvarint length, message
+//	tagEnd      uvarint dt
+//
+// dt is the time in nanoseconds since the previous record, or since the
+// header's start for the first one. Counters are stored as deltas against the
+// previous observation; an absolute record is written for the first one and
+// whenever a counter went backwards, e.g. after the program was reloaded.
+// Readers reject unknown tags, so new record types require a version bump.
+package record
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/cilium/ebpf"
+)
+
+const (
+	magic   = "BPFREC"
+	version = 1
+
+	// maxHeaderSize bounds the header allocation when reading corrupt files
+	maxHeaderSize = 16 << 20
+)
+
+const (
+	tagDelta byte = iota + 1
+	tagAbsolute
+	tagError
+	tagEnd
+)
+
+// Header describes a recording.
+type Header struct {
+	ID       uint32             `json:"id"`                // program the statistics were read from
+	Interval time.Duration      `json:"interval_ns"`       // polling interval
+	Started  time.Time          `json:"started"`           // when polling started
+	Metadata bpfsv1.RunMetadata `json:"metadata,omitzero"` // host, environment and program of the run
+}
+
+// PollError is a failed poll kept in a recording.
+type PollError struct {
+	At      time.Time
+	Message string
+}
+
+// Recording is the decoded content of a recording file.
+type Recording struct {
+	Header       Header
+	Observations []collector.Observation
+	Errors       []PollError
+	Ended        time.Time // end of the recording, or the last record if truncated
+	Truncated    bool      // the file ended without an end record
+}
+
+// Writer encodes a recording. Records must be written in time order.
+type Writer struct {
+	w    *bufio.Writer
+	last time.Time
+	prev *ebpf.ProgramStats
+	buf  []byte
+}
+
+// NewWriter writes the file header to w and returns a Writer for the records.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	header, err := json.Marshal(h)
+	if err != nil {
+		return nil, fmt.Errorf("encode header: %w", err)
+	}
+
+	rw := &Writer{w: bufio.NewWriter(w), last: h.Started}
+	rw.buf = append(rw.buf, magic...)
+	rw.buf = append(rw.buf, version)
+	rw.buf = binary.AppendUvarint(rw.buf, uint64(len(header)))
+	rw.buf = append(rw.buf, header...)
+	if err := rw.flushBuf(); err != nil {
+		return nil, err
+	}
+	return rw, nil
+}
+
+// Observe appends a reading of the program's statistics taken at at.
+func (rw *Writer) Observe(at time.Time, stats *ebpf.ProgramStats) error {
+	cur := *stats
+	prev := rw.prev
+	if prev == nil || cur.RunCount < prev.RunCount || cur.Runtime < prev.Runtime ||
+		cur.RecursionMisses < prev.RecursionMisses {
+		rw.begin(tagAbsolute, at)
+		rw.buf = binary.AppendUvarint(rw.buf, cur.RunCount)
+		rw.buf = binary.AppendUvarint(rw.buf, uint64(cur.Runtime))
+		rw.buf = binary.AppendUvarint(rw.buf, cur.RecursionMisses)
+	} else {
+		rw.begin(tagDelta, at)
+		rw.buf = binary.AppendUvarint(rw.buf, cur.RunCount-prev.RunCount)
+		rw.buf = binary.AppendUvarint(rw.buf, uint64(cur.Runtime-prev.Runtime))
+		rw.buf = binary.AppendUvarint(rw.buf, cur.RecursionMisses-prev.RecursionMisses)
+	}
+	rw.prev = &cur
+	return rw.flushBuf()
+}
+
+// Error appends a failed poll.
+func (rw *Writer) Error(at time.Time, err error) error {
+	msg := err.Error()
+	rw.begin(tagError, at)
+	rw.buf = binary.AppendUvarint(rw.buf, uint64(len(msg)))
+	rw.buf = append(rw.buf, msg...)
+	return rw.flushBuf()
+}
+
+// Close writes the end record and flushes buffered records. It does not
+// close the underlying writer.
+func (rw *Writer) Close(at time.Time) error {
+	rw.begin(tagEnd, at)
+	if err := rw.flushBuf(); err != nil {
+		return err
+	}
+	return rw.w.Flush()
+}
+
+// begin starts a record with its tag and time offset. Times before the
+// previous record are clamped to keep offsets unsigned.
+func (rw *Writer) begin(tag byte, at time.Time) {
+	dt := at.Sub(rw.last)
+	if dt < 0 {
+		dt = 0
+	} else {
+		rw.last = at
+	}
+	rw.buf = append(rw.buf, tag)
+	rw.buf = binary.AppendUvarint(rw.buf, uint64(dt))
+}
+
+func (rw *Writer) flushBuf() error {
+	_, err := rw.w.Write(rw.buf)
+	rw.buf = rw.buf[:0]
+	return err
+}
+
+// Read decodes a whole recording. A file cut short, e.g. because the
+// recording process was killed, is returned with Truncated set.
+func Read(r io.Reader) (*Recording, error) {
+	br := bufio.NewReader(r)
+
+	prefix := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(br, prefix); err != nil || string(prefix[:len(magic)]) != magic {
+		return nil, fmt.Errorf("not a bpfstats recording")
+	}
+	if prefix[len(magic)] != version {
+		return nil, fmt.Errorf("unsupported recording version %d", prefix[len(magic)])
+	}
+
+	size, err := binary.ReadUvarint(br)
+	if err != nil || size > maxHeaderSize {
+		return nil, fmt.Errorf("read header: invalid length")
+	}
+	header := make([]byte, size)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	rec := &Recording{}
+	if err := json.Unmarshal(header, &rec.Header); err != nil {
+		return nil, fmt.Errorf("decode header: %w", err)
+	}
+
+	at := rec.Header.Started
+	var prev ebpf.ProgramStats
+	for {
+		tag, err := br.ReadByte()
+		if err == io.EOF {
+			rec.Truncated = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		dt, err := binary.ReadUvarint(br)
+		if err != nil {
+			return truncated(rec, at, err)
+		}
+		at = at.Add(time.Duration(dt))
+
+		switch tag {
+		case tagDelta, tagAbsolute:
+			var v [3]uint64
+			for i := range v {
+				if v[i], err = binary.ReadUvarint(br); err != nil {
+					return truncated(rec, at, err)
+				}
+			}
+			stats := ebpf.ProgramStats{RunCount: v[0], Runtime: time.Duration(v[1]), RecursionMisses: v[2]}
+			if tag == tagDelta {
+				if len(rec.Observations) == 0 {
+					return nil, fmt.Errorf("delta record without a previous observation")
+				}
+				stats.RunCount += prev.RunCount
+				stats.Runtime += prev.Runtime
+				stats.RecursionMisses += prev.RecursionMisses
+			}
+			rec.Observations = append(rec.Observations, collector.Observation{At: at, Stats: stats})
+			prev = stats
+
+		case tagError:
+			n, err := binary.ReadUvarint(br)
+			if err != nil {
+				return truncated(rec, at, err)
+			}
+			if n > maxHeaderSize {
+				return nil, fmt.Errorf("corrupt recording: error message of %d bytes", n)
+			}
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(br, msg); err != nil {
+				return truncated(rec, at, err)
+			}
+			rec.Errors = append(rec.Errors, PollError{At: at, Message: string(msg)})
+
+		case tagEnd:
+			rec.Ended = at
+			return rec, nil
+
+		default:
+			return nil, fmt.Errorf("unknown record tag %d", tag)
+		}
+	}
+
+	rec.Ended = at
+	return rec, nil
+}
+
+// truncated ends decoding of a file cut in the middle of a record.
+func truncated(rec *Recording, at time.Time, err error) (*Recording, error) {
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	rec.Truncated = true
+	rec.Ended = at
+	return rec, nil
+}
+
+// ReadFile decodes the recording at path, see Read.
+func ReadFile(path string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rec, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rec, nil
+}
+
+// Source returns a collector source replaying the observations of the
+// recording at the collectors' own polling pace. Use collector.Replay to
+// analyze it with the recorded timestamps instead.
+func (rec *Recording) Source() collector.StatsSource {
+	return collector.NewObservationSource(rec.Observations)
+}
+
+// Integrity accounts for the failed polls and idle intervals of the
+// recording, in the order they were recorded. Intervals ending within warmup
+// of the start are not checked for activity. Collectors analyzing the
+// recording can add to it, see collector.Snapshots.
+func (rec *Recording) Integrity(warmup *time.Duration) *collector.Integrity {
+	warmupEnd := rec.Header.Started
+	if warmup != nil {
+		warmupEnd = warmupEnd.Add(*warmup)
+	}
+
+	in := collector.NewIntegrity()
+	obs, errs := rec.Observations, rec.Errors
+	for len(obs) > 0 || len(errs) > 0 {
+		if len(errs) > 0 && (len(obs) == 0 || errs[0].At.Before(obs[0].At)) {
+			in.Failed(errs[0].At, errors.New(errs[0].Message))
+			errs = errs[1:]
+			continue
+		}
+		in.Observed(obs[0].At, &obs[0].Stats, obs[0].At.Before(warmupEnd))
+		obs = obs[1:]
+	}
+	return in
+}
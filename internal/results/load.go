@@ -0,0 +1,185 @@
+// Package results reads measurement results written by the json, ndjson and
+// yaml printers back into typed bpfsv1 values.
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"go.yaml.in/yaml/v3"
+)
+
+// LoadFile reads all parameters stored in the file at path.
+func LoadFile(path string) ([]bpfsv1.Parameter, error) {
+	reports, err := LoadReportsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return flatten(reports), nil
+}
+
+// LoadReportsFile reads all reports stored in the file at path.
+func LoadReportsFile(path string) ([]bpfsv1.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reports, err := LoadReports(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return reports, nil
+}
+
+// Load decodes the parameters of every report in r, see LoadReports.
+func Load(r io.Reader) ([]bpfsv1.Parameter, error) {
+	reports, err := LoadReports(r)
+	if err != nil {
+		return nil, err
+	}
+	return flatten(reports), nil
+}
+
+// LoadReports decodes reports from JSON or YAML. It accepts a single document,
+// a stream of documents (concatenated or newline-delimited JSON, "---"
+// separated YAML) and documents holding a list of parameters. Bare
+// parameters, which carry no run metadata, are grouped into one report.
+func LoadReports(r io.Reader) ([]bpfsv1.Report, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("no results found")
+	}
+
+	var docs []json.RawMessage
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		docs, err = splitJSON(trimmed)
+	} else {
+		docs, err = splitYAML(trimmed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		reports []bpfsv1.Report
+		bare    []bpfsv1.Parameter
+	)
+	for _, doc := range docs {
+		report, params, err := decodeDocument(doc)
+		if err != nil {
+			return nil, err
+		}
+		if report != nil {
+			reports = append(reports, *report)
+		}
+		bare = append(bare, params...)
+	}
+	if len(bare) > 0 {
+		reports = append(reports, *bpfsv1.NewReport(bpfsv1.RunMetadata{}, bare...))
+	}
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("no results found")
+	}
+	return reports, nil
+}
+
+func flatten(reports []bpfsv1.Report) []bpfsv1.Parameter {
+	var params []bpfsv1.Parameter
+	for i := range reports {
+		params = append(params, reports[i].Params()...)
+	}
+	return params
+}
+
+// decodeDocument decodes a report, a single parameter object or a list of
+// parameters.
+func decodeDocument(doc json.RawMessage) (*bpfsv1.Report, []bpfsv1.Parameter, error) {
+	doc = bytes.TrimSpace(doc)
+	if len(doc) > 0 && doc[0] == '[' {
+		var list []bpfsv1.TypedParameter
+		if err := json.Unmarshal(doc, &list); err != nil {
+			return nil, nil, err
+		}
+		params := make([]bpfsv1.Parameter, len(list))
+		for i, t := range list {
+			params[i] = t.Parameter
+		}
+		return nil, params, nil
+	}
+
+	var head struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+	}
+	if err := json.Unmarshal(doc, &head); err != nil {
+		return nil, nil, err
+	}
+	if head.Kind == bpfsv1.ReportKind {
+		if head.APIVersion != bpfsv1.APIVersion {
+			return nil, nil, fmt.Errorf("unsupported report apiVersion %q (want %q)", head.APIVersion, bpfsv1.APIVersion)
+		}
+		var report bpfsv1.Report
+		if err := json.Unmarshal(doc, &report); err != nil {
+			return nil, nil, err
+		}
+		return &report, nil, nil
+	}
+
+	var t bpfsv1.TypedParameter
+	if err := json.Unmarshal(doc, &t); err != nil {
+		return nil, nil, err
+	}
+	return nil, []bpfsv1.Parameter{t.Parameter}, nil
+}
+
+func splitJSON(data []byte) ([]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var docs []json.RawMessage
+	for {
+		var doc json.RawMessage
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode json: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// splitYAML converts every YAML document into its JSON equivalent, so that
+// both formats share the JSON decoding (and its field names and units).
+func splitYAML(data []byte) ([]json.RawMessage, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var docs []json.RawMessage
+	for {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode yaml: %w", err)
+		}
+		if doc == nil {
+			continue
+		}
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("convert yaml: %w", err)
+		}
+		docs = append(docs, raw)
+	}
+}
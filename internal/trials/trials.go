@@ -0,0 +1,172 @@
+// Package trials summarizes a measurement repeated several times: each
+// metric per trial, pooled over all trials, and its spread between trials.
+package trials
+
+import (
+	"fmt"
+	"math"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/Tjaarda1/bpfstats/internal/compare"
+)
+
+// observation is the value of a metric in one trial. When samples are set
+// they are the per-interval values the metric was computed with stat ("mean"
+// or a percentile key), so the pooled value is recomputed over the samples of
+// all trials; otherwise it is the weighted mean of the per-trial values.
+type observation struct {
+	value   float64
+	weight  float64
+	samples []float64
+	stat    string
+}
+
+// Summarize builds the Trials summary of the parameters of every trial.
+// Parameters are matched by kind; the first of each kind in a trial is used.
+// The caller fills in the identity and timing fields.
+func Summarize(perTrial [][]bpfsv1.Parameter, confidence float64, percentiles []string) (bpfsv1.Trials, error) {
+	if len(perTrial) == 0 {
+		return bpfsv1.Trials{}, fmt.Errorf("no trials to summarize")
+	}
+
+	obs := make(map[string][]observation)
+	for _, params := range perTrial {
+		seen := make(map[string]bool)
+		for _, p := range params {
+			if seen[p.Kind()] {
+				continue
+			}
+			seen[p.Kind()] = true
+			for name, o := range observe(p, percentiles) {
+				obs[name] = append(obs[name], o)
+			}
+		}
+	}
+
+	t := bpfsv1.Trials{
+		Trials:     len(perTrial),
+		Confidence: confidence,
+		Metrics:    make(map[string]bpfsv1.TrialMetric, len(obs)),
+	}
+	for name, series := range obs {
+		// Metrics missing from a trial cannot be compared across trials
+		if len(series) != len(perTrial) {
+			continue
+		}
+		m, err := summarizeMetric(series, confidence)
+		if err != nil {
+			return bpfsv1.Trials{}, fmt.Errorf("%s: %w", name, err)
+		}
+		t.Metrics[name] = m
+	}
+	return t, nil
+}
+
+func summarizeMetric(series []observation, confidence float64) (bpfsv1.TrialMetric, error) {
+	values := make([]float64, len(series))
+	for i, o := range series {
+		values[i] = o.value
+	}
+
+	s := &collector.Stats{}
+	for _, v := range values {
+		s.Add(v)
+	}
+	mean := s.Mean()
+	stddev := math.Sqrt(s.Variance())
+
+	m := bpfsv1.TrialMetric{
+		PerTrial: values,
+		Mean:     mean,
+		StdDev:   stddev,
+	}
+	if mean != 0 {
+		cv := stddev / math.Abs(mean)
+		m.CV = &cv
+	}
+	m.CILow, m.CIHigh = compare.MeanInterval(values, confidence)
+
+	// Pooled over the raw samples when every trial kept them
+	groups := make([][]float64, 0, len(series))
+	for _, o := range series {
+		if o.samples == nil {
+			groups = nil
+			break
+		}
+		groups = append(groups, o.samples)
+	}
+	stat := series[0].stat
+	if groups != nil && stat != "" {
+		pooled := &collector.Stats{}
+		for _, g := range groups {
+			for _, v := range g {
+				pooled.Add(v)
+			}
+		}
+		if stat == "mean" {
+			m.Pooled = pooled.Mean()
+		} else {
+			values, err := pooled.PercentileMap([]string{stat})
+			if err != nil {
+				return bpfsv1.TrialMetric{}, err
+			}
+			m.Pooled = values[stat]
+		}
+	} else {
+		var sum, weights float64
+		for _, o := range series {
+			sum += o.value * o.weight
+			weights += o.weight
+		}
+		if weights > 0 {
+			m.Pooled = sum / weights
+		}
+	}
+
+	// Variance decomposition only makes sense for per-interval means
+	if groups != nil && stat == "mean" {
+		if share, ok := compare.BetweenShare(groups); ok {
+			m.BetweenShare = &share
+		}
+	}
+	return m, nil
+}
+
+// observe extracts the metrics of one parameter, keyed by parameter,
+// statistic and unit.
+func observe(p bpfsv1.Parameter, percentiles []string) map[string]observation {
+	out := make(map[string]observation)
+	switch t := p.(type) {
+	case bpfsv1.Latency:
+		samples := floats(t.Timeline)
+		weight := float64(t.Samples)
+		out["latency_mean_ns"] = observation{float64(t.Mean), weight, samples, "mean"}
+		if t.Percentiles != nil {
+			for _, k := range percentiles {
+				if v, ok := (*t.Percentiles)[k]; ok {
+					out["latency_"+k+"_ns"] = observation{float64(v), weight, samples, k}
+				}
+			}
+		}
+	case bpfsv1.Throughput:
+		out["throughput_mean_per_sec"] = observation{t.Mean, float64(t.Samples), t.Timeline, "mean"}
+	case bpfsv1.Cpu:
+		out["cpu_cores_mean"] = observation{value: t.Mean, weight: float64(t.Samples)}
+		out["cpu_machine_fraction_mean"] = observation{value: t.MachineMean, weight: float64(t.Samples)}
+	case bpfsv1.Health:
+		out["health_missed_fraction"] = observation{value: t.MissedFraction, weight: float64(t.Runs + t.RecursionMisses)}
+	}
+	return out
+}
+
+func floats(v []uint64) []float64 {
+	if v == nil {
+		return nil
+	}
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = float64(x)
+	}
+	return out
+}
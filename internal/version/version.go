@@ -0,0 +1,21 @@
+// Package version reports the bpfstats build version.
+package version
+
+import "runtime/debug"
+
+// Version is set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/Tjaarda1/bpfstats/internal/version.Version=v0.2.0"
+var Version = "dev"
+
+// Get returns the build version, falling back to the module version recorded
+// by `go install` when no version was stamped at link time.
+func Get() string {
+	if Version != "dev" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return Version
+}
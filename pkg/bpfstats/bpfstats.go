@@ -0,0 +1,334 @@
+package bpfstats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/Tjaarda1/bpfstats/internal/environment"
+	"github.com/Tjaarda1/bpfstats/internal/program"
+	"github.com/cilium/ebpf"
+)
+
+// DefaultInterval is the sampling interval of measurements that set none.
+const DefaultInterval = 100 * time.Millisecond
+
+// defaultSampleBuffer is the capacity of Measurement.Samples.
+const defaultSampleBuffer = 64
+
+// ErrTooManyFailedPolls is returned (wrapped) by Wait when more polls failed
+// than Options.MaxErrorRate allows.
+var ErrTooManyFailedPolls = errors.New("too many failed polls")
+
+var (
+	defaultMetrics     = []string{"latency", "cpu", "health", "throughput"}
+	defaultPercentiles = []float64{50, 90, 99, 99.9}
+)
+
+// Window selects statistics of a recent window of a measurement, reported
+// besides the statistics of the whole measurement.
+type Window struct {
+	Span time.Duration
+
+	// Tumbling reports the last complete span of consecutive,
+	// non-overlapping spans instead of the trailing span
+	Tumbling bool
+}
+
+// Options configure a measurement. The zero value measures every metric of
+// the kernel's counters until the context is done.
+type Options struct {
+	// Duration of the measurement, 0 => until the context is done or the
+	// source is exhausted
+	Duration time.Duration
+	// Warmup at the start whose samples are discarded
+	Warmup time.Duration
+	// Interval between two polls, 0 => DefaultInterval
+	Interval time.Duration
+
+	// Metrics to report, e.g. "latency" or "cpu", nil => all of latency,
+	// cpu, health and throughput. See Metrics for the available ones.
+	Metrics []string
+	// Percentiles to compute, e.g. 50 or 99.9, nil => 50, 90, 99 and 99.9
+	Percentiles []float64
+	// Recent windows reported by the latency and cpu metrics
+	Windows []Window
+
+	// MaxErrorRate is the largest tolerated fraction of failed polls, e.g.
+	// 0.1 for 10%, 0 => unchecked. See ErrTooManyFailedPolls.
+	MaxErrorRate float64
+
+	// Source of the counters, nil => KernelSource of the measured program.
+	// With another source the report describes neither the program nor
+	// the host.
+	Source Source
+
+	// OnSample, if set, is called from the polling goroutine with every
+	// sample. It must not block, or it delays the next poll.
+	OnSample func(Sample)
+	// SampleBuffer is the capacity of Measurement.Samples, 0 => 64
+	SampleBuffer int
+}
+
+// Metrics returns the sorted names of the metrics a measurement can report.
+func Metrics() []string {
+	return collector.Names()
+}
+
+// Measure measures program id as configured by opts and returns the report
+// of the measurement. If ctx is done before Duration has passed, Measure
+// returns the report so far together with the context's error.
+func Measure(ctx context.Context, id uint32, opts Options) (*bpfsv1.Report, error) {
+	m, err := Start(ctx, id, opts)
+	if err != nil {
+		return nil, err
+	}
+	return m.Wait()
+}
+
+// Measurement is a measurement running in the background.
+type Measurement struct {
+	runner   *collector.Runner
+	meta     bpfsv1.RunMetadata
+	cancel   context.CancelFunc
+	parent   context.Context
+	onSample func(Sample)
+	maxRate  float64
+
+	sampler sampler
+	samples chan Sample
+	dropped atomic.Uint64
+
+	done   chan struct{}
+	mu     sync.Mutex
+	ended  time.Time
+	runErr error
+}
+
+// Start starts measuring program id as configured by opts. The measurement
+// ends after opts.Duration, when ctx is done or when Stop is called.
+func Start(ctx context.Context, id uint32, opts Options) (*Measurement, error) {
+	copts, err := collectorOptions(id, opts)
+	if err != nil {
+		return nil, err
+	}
+	metrics := opts.Metrics
+	if metrics == nil {
+		metrics = defaultMetrics
+	}
+	for i, name := range metrics {
+		if slices.Contains(metrics[:i], name) {
+			return nil, fmt.Errorf("metric %q given twice", name)
+		}
+	}
+	collectors, err := collector.New(metrics, copts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a source of its own the program is measured in the kernel,
+	// and the report identifies it and the host
+	var env *bpfsv1.Environment
+	var programs []bpfsv1.Program
+	source := opts.Source
+	if source == nil {
+		prog, err := program.Describe(id)
+		if err != nil {
+			return nil, fmt.Errorf("describe program %d: %w", id, err)
+		}
+		programs = []bpfsv1.Program{*prog}
+		env = environment.Capture()
+		source = KernelSource(id)
+	}
+
+	runner, err := collector.NewRunner(source, copts.Interval, copts.Warmup, collectors...)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := opts.SampleBuffer
+	if buffer <= 0 {
+		buffer = defaultSampleBuffer
+	}
+	m := &Measurement{
+		runner:   runner,
+		parent:   ctx,
+		onSample: opts.OnSample,
+		maxRate:  opts.MaxErrorRate,
+		samples:  make(chan Sample, buffer),
+		done:     make(chan struct{}),
+	}
+	runner.OnPoll(m.poll)
+
+	var runCtx context.Context
+	if opts.Duration > 0 {
+		runCtx, m.cancel = context.WithTimeout(ctx, opts.Duration)
+	} else {
+		runCtx, m.cancel = context.WithCancel(ctx)
+	}
+
+	started := time.Now()
+	m.meta = environment.RunMetadata(started, started, env)
+	m.meta.Programs = programs
+
+	go m.run(runCtx)
+	return m, nil
+}
+
+// collectorOptions converts opts to the options of the collectors.
+func collectorOptions(id uint32, opts Options) (collector.Options, error) {
+	copts := collector.Options{ID: id, Interval: opts.Interval}
+	if copts.Interval <= 0 {
+		copts.Interval = DefaultInterval
+	}
+	if opts.MaxErrorRate < 0 || opts.MaxErrorRate > 1 {
+		return copts, fmt.Errorf("invalid max error rate %v: must be within [0, 1]", opts.MaxErrorRate)
+	}
+	if opts.Warmup < 0 {
+		return copts, fmt.Errorf("invalid warmup %v: must not be negative", opts.Warmup)
+	}
+	if opts.Warmup > 0 {
+		copts.Warmup = &opts.Warmup
+	}
+
+	percentiles := opts.Percentiles
+	if percentiles == nil {
+		percentiles = defaultPercentiles
+	}
+	for _, p := range percentiles {
+		if p < 0 || p > 100 {
+			return copts, fmt.Errorf("invalid percentile %v: must be within [0, 100]", p)
+		}
+		key := strconv.FormatFloat(p, 'f', -1, 64)
+		copts.Percentiles = append(copts.Percentiles, "p"+strings.ReplaceAll(key, ".", "_"))
+	}
+
+	for _, w := range opts.Windows {
+		if w.Span <= 0 {
+			return copts, fmt.Errorf("invalid window %v: must be positive", w.Span)
+		}
+		spec := collector.WindowSpec{Span: w.Span, Mode: collector.WindowSliding}
+		if w.Tumbling {
+			spec.Mode = collector.WindowTumbling
+		}
+		copts.Windows = append(copts.Windows, spec)
+	}
+	return copts, nil
+}
+
+func (m *Measurement) run(ctx context.Context) {
+	err := m.runner.Start(ctx)
+
+	m.mu.Lock()
+	m.ended = time.Now()
+	// Ending after Duration or Stop is a complete measurement, only the
+	// caller's context ends it prematurely
+	if m.parent.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		err = nil
+	}
+	m.runErr = err
+	m.mu.Unlock()
+
+	m.cancel()
+	close(m.samples)
+	close(m.done)
+}
+
+// poll turns the runner's readings into samples.
+func (m *Measurement) poll(at time.Time, stats *ebpf.ProgramStats, warmup bool) {
+	s, ok := m.sampler.next(at, stats, warmup)
+	if !ok {
+		return
+	}
+	if m.onSample != nil {
+		m.onSample(s)
+	}
+	select {
+	case m.samples <- s:
+	default:
+		m.dropped.Add(1)
+	}
+}
+
+// Samples returns the samples of the measurement in order. The channel is
+// closed when the measurement ends. Samples that do not fit its buffer are
+// dropped rather than delaying the polls, see Dropped.
+func (m *Measurement) Samples() <-chan Sample {
+	return m.samples
+}
+
+// Dropped returns the number of samples dropped because Samples was full.
+func (m *Measurement) Dropped() uint64 {
+	return m.dropped.Load()
+}
+
+// Err returns the most recent error polling the source, if any. Failed
+// polls do not end the measurement; the report accounts for all of them in
+// its integrity metadata.
+func (m *Measurement) Err() error {
+	return m.runner.Err()
+}
+
+// Stop ends the measurement before its duration has passed. The report
+// then covers the time until Stop.
+func (m *Measurement) Stop() {
+	m.cancel()
+}
+
+// Done is closed when the measurement has ended.
+func (m *Measurement) Done() <-chan struct{} {
+	return m.done
+}
+
+// Report returns the report of the measurement so far, or of the complete
+// measurement once it has ended.
+func (m *Measurement) Report() (*bpfsv1.Report, error) {
+	params, err := m.runner.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	ended := m.ended
+	m.mu.Unlock()
+	if ended.IsZero() {
+		ended = time.Now()
+	}
+
+	meta := m.meta
+	meta.Ended = &ended
+	meta.Integrity = m.runner.Integrity()
+	return bpfsv1.NewReport(meta, params...), nil
+}
+
+// Wait waits for the measurement to end and returns its report. If the
+// context of Start was done first, the report so far is returned together
+// with the context's error; if too many polls failed, together with an
+// error wrapping ErrTooManyFailedPolls.
+func (m *Measurement) Wait() (*bpfsv1.Report, error) {
+	<-m.done
+	report, err := m.Report()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	runErr := m.runErr
+	m.mu.Unlock()
+	if runErr != nil {
+		return report, runErr
+	}
+
+	if in := report.Metadata.Integrity; m.maxRate > 0 && in.ErrorRate > m.maxRate {
+		return report, fmt.Errorf("%w: %d of %d polls failed", ErrTooManyFailedPolls, in.FailedPolls, in.Polls)
+	}
+	return report, nil
+}
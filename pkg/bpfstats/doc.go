@@ -0,0 +1,49 @@
+// Package bpfstats measures the runtime statistics of loaded eBPF programs
+// from Go programs, e.g. test harnesses, with the collectors and printers of
+// the bpfstats command.
+//
+// Measure runs one measurement and returns its report:
+//
+//	report, err := bpfstats.Measure(ctx, id, bpfstats.Options{
+//		Duration:    10 * time.Second,
+//		Warmup:      2 * time.Second,
+//		Percentiles: []float64{50, 99, 99.9},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	for _, p := range report.Params() {
+//		if lat, ok := p.(bpfsv1.Latency); ok {
+//			fmt.Println(lat.Mean) // nanoseconds
+//		}
+//	}
+//
+// Start runs a measurement in the background and streams the counters of
+// every interval over Measurement.Samples, or to Options.OnSample:
+//
+//	m, err := bpfstats.Start(ctx, id, bpfstats.Options{Duration: time.Minute})
+//	if err != nil {
+//		return err
+//	}
+//	for s := range m.Samples() {
+//		fmt.Println(s.At, s.Latency())
+//	}
+//	report, err := m.Wait()
+//
+// Measuring the kernel's counters requires kernel.bpf_stats_enabled=1 and
+// the privileges to open programs by ID (CAP_SYS_ADMIN). SyntheticSource
+// simulates a program without either, for tests of the embedding tool.
+//
+// Results are the typed parameters of the bpfsv1 API, the same documents the
+// command prints, so reports of both can be compared and gated alike.
+//
+// # Compatibility
+//
+// The package follows semantic versioning, reported by Version. Within a
+// major version, exported identifiers are neither removed nor changed
+// incompatibly; fields and functions may be added in minor versions. The
+// schema of the results is versioned separately by bpfsv1.APIVersion.
+package bpfstats
+
+// Version is the semantic version of the package API.
+const Version = "1.1.0"
@@ -0,0 +1,63 @@
+package bpfstats_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/pkg/bpfstats"
+)
+
+func ExampleMeasure() {
+	// A simulated program running 1000 times per poll for 500ns each; the
+	// measurement ends when the source is exhausted after 20 polls
+	source := bpfstats.SyntheticSource(bpfstats.SyntheticConfig{
+		RunsPerPoll: 1000,
+		Latency:     500 * time.Nanosecond,
+		Polls:       20,
+	})
+
+	report, err := bpfstats.Measure(context.Background(), 0, bpfstats.Options{
+		Interval: time.Millisecond,
+		Metrics:  []string{"latency"},
+		Source:   source,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range report.Params() {
+		if lat, ok := p.(bpfsv1.Latency); ok {
+			fmt.Printf("%d intervals, mean %v\n", lat.Samples, time.Duration(lat.Mean))
+		}
+	}
+	// Output:
+	// 19 intervals, mean 500ns
+}
+
+func ExampleStart() {
+	source := bpfstats.SyntheticSource(bpfstats.SyntheticConfig{
+		RunsPerPoll: 200,
+		Latency:     2 * time.Microsecond,
+		Polls:       4,
+	})
+
+	m, err := bpfstats.Start(context.Background(), 0, bpfstats.Options{
+		Interval: time.Millisecond,
+		Source:   source,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for s := range m.Samples() {
+		fmt.Println(s.Runs, s.Latency())
+	}
+	if _, err := m.Wait(); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// 200 2µs
+	// 200 2µs
+	// 200 2µs
+}
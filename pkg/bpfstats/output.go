@@ -0,0 +1,25 @@
+package bpfstats
+
+import (
+	"io"
+
+	bpfsv1 "github.com/Tjaarda1/bpfstats/api/v1"
+	"github.com/Tjaarda1/bpfstats/internal/output"
+)
+
+// Formats returns the sorted names of the output formats Write accepts.
+func Formats() []string {
+	return output.Formats()
+}
+
+// Write prints report to w in format, as the bpfstats command does with
+// --output. format is a name such as "text", "json" or "csv", or a name with
+// an argument such as "jsonpath={.parameters[*].mean_ns}". An empty format
+// selects text.
+func Write(w io.Writer, report *bpfsv1.Report, format string) error {
+	p, err := output.NewPrinter(format, output.OutputOptions{})
+	if err != nil {
+		return err
+	}
+	return p.OutputReport(report, w)
+}
@@ -0,0 +1,68 @@
+package bpfstats
+
+import (
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+// Sample holds the activity of a program during one sampling interval, the
+// difference between two consecutive polls.
+type Sample struct {
+	At       time.Time     // time of the poll ending the interval
+	Interval time.Duration // time since the previous poll
+	Warmup   bool          // within the warmup, not counted in the results
+
+	Runs            uint64        // invocations during the interval
+	Runtime         time.Duration // time spent in the program during the interval
+	RecursionMisses uint64        // invocations skipped by the kernel during the interval
+
+	Stats ebpf.ProgramStats // cumulative counters read at At
+}
+
+// Latency returns the mean runtime per invocation, zero without invocations.
+func (s Sample) Latency() time.Duration {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.Runtime / time.Duration(s.Runs)
+}
+
+// Cores returns the CPU time spent in the program per wall-clock time, e.g.
+// 0.5 for half a core.
+func (s Sample) Cores() float64 {
+	if s.Interval <= 0 {
+		return 0
+	}
+	return float64(s.Runtime) / float64(s.Interval)
+}
+
+// sampler turns the cumulative readings of consecutive polls into samples.
+type sampler struct {
+	prev   ebpf.ProgramStats
+	prevAt time.Time
+	primed bool
+}
+
+// next returns the sample ending at at. The first reading, and a reading
+// after the counters were reset, only become the baseline of the next one.
+func (sp *sampler) next(at time.Time, stats *ebpf.ProgramStats, warmup bool) (Sample, bool) {
+	prev, prevAt, primed := sp.prev, sp.prevAt, sp.primed
+	sp.prev, sp.prevAt, sp.primed = *stats, at, true
+
+	if !primed || stats.RunCount < prev.RunCount || stats.Runtime < prev.Runtime {
+		return Sample{}, false
+	}
+	s := Sample{
+		At:       at,
+		Interval: at.Sub(prevAt),
+		Warmup:   warmup,
+		Runs:     stats.RunCount - prev.RunCount,
+		Runtime:  stats.Runtime - prev.Runtime,
+		Stats:    *stats,
+	}
+	if stats.RecursionMisses >= prev.RecursionMisses {
+		s.RecursionMisses = stats.RecursionMisses - prev.RecursionMisses
+	}
+	return s, true
+}
@@ -0,0 +1,47 @@
+package bpfstats
+
+import (
+	"time"
+
+	"github.com/Tjaarda1/bpfstats/internal/collector"
+	"github.com/cilium/ebpf"
+)
+
+// Source provides the cumulative statistics of a program, the way
+// (*ebpf.Program).Stats does. A measurement polls it once per interval. A
+// source that has nothing more to provide returns an error wrapping io.EOF,
+// which ends the measurement early. A source implementing io.Closer is closed
+// when the measurement ends.
+type Source interface {
+	Stats() (*ebpf.ProgramStats, error)
+}
+
+// SyntheticConfig describes the workload simulated by a SyntheticSource.
+type SyntheticConfig struct {
+	RunsPerPoll   uint64        // invocations between two polls
+	Latency       time.Duration // mean runtime per invocation
+	Jitter        time.Duration // runtime per invocation varies uniformly by ±Jitter between polls
+	MissesPerPoll uint64        // recursion misses between two polls
+	Polls         int           // polls before the source is exhausted, 0 => unlimited
+	Seed          uint64        // seed of the jitter, the same seed yields the same counters
+}
+
+// KernelSource returns a source reading the statistics of the loaded program
+// with the given ID. It is the source measurements use by default.
+func KernelSource(id uint32) Source {
+	return collector.NewKernelSource(id)
+}
+
+// SyntheticSource returns a source simulating a program with the workload
+// in cfg. Its counters depend only on cfg, so measurements using it are
+// reproducible and need neither privileges nor a loaded program.
+func SyntheticSource(cfg SyntheticConfig) Source {
+	return collector.NewSyntheticSource(collector.SyntheticConfig{
+		RunsPerPoll:   cfg.RunsPerPoll,
+		Latency:       cfg.Latency,
+		Jitter:        cfg.Jitter,
+		MissesPerPoll: cfg.MissesPerPoll,
+		Polls:         cfg.Polls,
+		Seed:          cfg.Seed,
+	})
+}